@@ -2,6 +2,8 @@ package api
 
 import (
 	"testing"
+
+	"github.com/docker/docker/api/types"
 )
 
 func TestJsonContentType(t *testing.T) {
@@ -17,3 +19,14 @@ func TestJsonContentType(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestDisplayablePortsIPv6(t *testing.T) {
+	ports := []types.Port{
+		{IP: "::", PrivatePort: 80, PublicPort: 8080, Type: "tcp"},
+		{IP: "2001:db8::1", PrivatePort: 443, PublicPort: 443, Type: "tcp"},
+	}
+	expected := "[2001:db8::1]:443->443/tcp, [::]:8080->80/tcp"
+	if output := DisplayablePorts(ports); output != expected {
+		t.Fatalf("Expected %q, got %q", expected, output)
+	}
+}
@@ -0,0 +1,30 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/docker/docker/runconfig"
+)
+
+// CmdCheckpoint checkpoints the processes of one or more running containers.
+//
+// Usage: docker checkpoint [OPTIONS] CONTAINER [CONTAINER...]
+func (cli *DockerCli) CmdCheckpoint(args ...string) error {
+	cmd := cli.Subcmd("checkpoint", "CONTAINER [CONTAINER...]", "Checkpoint one or more running containers", true)
+
+	config, err := runconfig.ParseCheckpoint(cmd, args)
+	if err != nil {
+		cmd.ReportError(err.Error(), true)
+	}
+
+	var encounteredError error
+	for _, name := range cmd.Args() {
+		if _, _, err := readBody(cli.call("POST", "/containers/"+name+"/checkpoint", config, nil)); err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			encounteredError = fmt.Errorf("Error: failed to checkpoint one or more containers")
+		} else {
+			fmt.Fprintf(cli.out, "%s\n", name)
+		}
+	}
+	return encounteredError
+}
@@ -0,0 +1,30 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/docker/docker/runconfig"
+)
+
+// CmdRestore restores one or more containers from their checkpoint.
+//
+// Usage: docker restore [OPTIONS] CONTAINER [CONTAINER...]
+func (cli *DockerCli) CmdRestore(args ...string) error {
+	cmd := cli.Subcmd("restore", "CONTAINER [CONTAINER...]", "Restore one or more checkpointed containers", true)
+
+	config, err := runconfig.ParseRestore(cmd, args)
+	if err != nil {
+		cmd.ReportError(err.Error(), true)
+	}
+
+	var encounteredError error
+	for _, name := range cmd.Args() {
+		if _, _, err := readBody(cli.call("POST", "/containers/"+name+"/restore", config, nil)); err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			encounteredError = fmt.Errorf("Error: failed to restore one or more containers")
+		} else {
+			fmt.Fprintf(cli.out, "%s\n", name)
+		}
+	}
+	return encounteredError
+}
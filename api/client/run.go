@@ -1,12 +1,14 @@
 package client
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/url"
 	"os"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/opts"
 	"github.com/docker/docker/pkg/promise"
 	"github.com/docker/docker/pkg/resolvconf"
@@ -87,10 +89,6 @@ func (cli *DockerCli) CmdRun(args ...string) error {
 				return ErrConflictAttachDetach
 			}
 		}
-		if *flAutoRemove {
-			return ErrConflictDetachAutoRemove
-		}
-
 		config.AttachStdin = false
 		config.AttachStdout = false
 		config.AttachStderr = false
@@ -103,10 +101,53 @@ func (cli *DockerCli) CmdRun(args ...string) error {
 		sigProxy = false
 	}
 
+	if *flAutoRemove && (hostConfig.RestartPolicy.Name == "always" || hostConfig.RestartPolicy.Name == "unless-stopped" || hostConfig.RestartPolicy.Name == "on-failure") {
+		return ErrConflictRestartPolicyAndAutoRemove
+	}
+	hostConfig.AutoRemove = *flAutoRemove
+
 	createResponse, err := cli.createContainer(config, hostConfig, hostConfig.ContainerIDFile, *flName)
 	if err != nil {
 		return err
 	}
+
+	// The daemon removes the container when it exits. An attached client
+	// waits for the removal to get the exit code, unless the daemon doesn't
+	// support AutoRemove and leaves the removal to the client, which can't
+	// do it for a detached container.
+	var (
+		daemonAutoRemove bool
+		removed          chan error
+		removedStatus    int
+	)
+	if *flAutoRemove {
+		if daemonAutoRemove, err = getAutoRemove(cli, createResponse.ID); err != nil {
+			return err
+		}
+		if *flDetach && !daemonAutoRemove {
+			if _, _, err := readBody(cli.call("DELETE", "/containers/"+createResponse.ID+"?v=1", nil, nil)); err != nil {
+				logrus.Errorf("Error deleting container: %s", err)
+			}
+			return ErrConflictDetachAutoRemove
+		}
+		if daemonAutoRemove && !*flDetach {
+			// the daemon answers once it found the container, before it
+			// can be removed
+			stream, _, err := cli.call("POST", "/containers/"+createResponse.ID+"/wait?condition=removed", nil, nil)
+			if err != nil {
+				return err
+			}
+			removed = promise.Go(func() error {
+				defer stream.Close()
+				var res types.ContainerWaitResponse
+				if err := json.NewDecoder(stream).Decode(&res); err != nil {
+					return err
+				}
+				removedStatus = res.StatusCode
+				return nil
+			})
+		}
+	}
 	if sigProxy {
 		sigc := cli.forwardAllSignals(createResponse.ID)
 		defer signal.StopCatch(sigc)
@@ -123,9 +164,6 @@ func (cli *DockerCli) CmdRun(args ...string) error {
 			fmt.Fprintf(cli.out, "%s\n", createResponse.ID)
 		}()
 	}
-	if *flAutoRemove && (hostConfig.RestartPolicy.Name == "always" || hostConfig.RestartPolicy.Name == "on-failure") {
-		return ErrConflictRestartPolicyAndAutoRemove
-	}
 	// We need to instantiate the chan because the select needs it. It can
 	// be closed but can't be uninitialized.
 	hijacked := make(chan io.Closer)
@@ -180,11 +218,15 @@ func (cli *DockerCli) CmdRun(args ...string) error {
 		}
 	}
 
+	started := false
 	defer func() {
-		if *flAutoRemove {
-			if _, _, err = readBody(cli.call("DELETE", "/containers/"+createResponse.ID+"?v=1", nil, nil)); err != nil {
-				logrus.Errorf("Error deleting container: %s", err)
-			}
+		if !*flAutoRemove || *flDetach || (daemonAutoRemove && started) {
+			return
+		}
+		// a container that failed to start may be removed by the daemon
+		// already
+		if _, _, err = readBody(cli.call("DELETE", "/containers/"+createResponse.ID+"?v=1", nil, nil)); err != nil && !daemonAutoRemove {
+			logrus.Errorf("Error deleting container: %s", err)
 		}
 	}()
 
@@ -192,6 +234,7 @@ func (cli *DockerCli) CmdRun(args ...string) error {
 	if _, _, err = readBody(cli.call("POST", "/containers/"+createResponse.ID+"/start", nil, nil)); err != nil {
 		return err
 	}
+	started = true
 
 	if (config.AttachStdin || config.AttachStdout || config.AttachStderr) && config.Tty && cli.isTerminalOut {
 		if err := cli.monitorTtySize(createResponse.ID, false); err != nil {
@@ -216,9 +259,16 @@ func (cli *DockerCli) CmdRun(args ...string) error {
 	var status int
 
 	// Attached mode
-	if *flAutoRemove {
-		// Autoremove: wait for the container to finish, retrieve
-		// the exit code and remove the container
+	if daemonAutoRemove {
+		// Autoremove: the exit code is known once the daemon removed the
+		// container
+		if err := <-removed; err != nil {
+			return err
+		}
+		status = removedStatus
+	} else if *flAutoRemove {
+		// Autoremove with a daemon that doesn't support it: wait for the
+		// container to finish, retrieve the exit code and remove the container
 		if _, _, err := readBody(cli.call("POST", "/containers/"+createResponse.ID+"/wait", nil, nil)); err != nil {
 			return err
 		}
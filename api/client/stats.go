@@ -23,6 +23,7 @@ type containerStats struct {
 	MemoryPercentage float64
 	NetworkRx        float64
 	NetworkTx        float64
+	Networks         map[string]types.Network
 	mu               sync.RWMutex
 	err              error
 }
@@ -61,8 +62,8 @@ func (s *containerStats) Collect(cli *DockerCli) {
 			s.Memory = float64(v.MemoryStats.Usage)
 			s.MemoryLimit = float64(v.MemoryStats.Limit)
 			s.MemoryPercentage = memPercent
-			s.NetworkRx = float64(v.Network.RxBytes)
-			s.NetworkTx = float64(v.Network.TxBytes)
+			s.NetworkRx, s.NetworkTx = calculateNetwork(v)
+			s.Networks = v.Networks
 			s.mu.Unlock()
 			previousCPU = v.CpuStats.CpuUsage.TotalUsage
 			previousSystem = v.CpuStats.SystemUsage
@@ -102,6 +103,19 @@ func (s *containerStats) Display(w io.Writer) error {
 		units.HumanSize(s.Memory), units.HumanSize(s.MemoryLimit),
 		s.MemoryPercentage,
 		units.HumanSize(s.NetworkRx), units.HumanSize(s.NetworkTx))
+	// break the network I/O down when there are several interfaces
+	if len(s.Networks) > 1 {
+		var names []string
+		for name := range s.Networks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			n := s.Networks[name]
+			fmt.Fprintf(w, "  %s\t\t\t\t%s/%s\n", name,
+				units.HumanSize(float64(n.RxBytes)), units.HumanSize(float64(n.TxBytes)))
+		}
+	}
 	return nil
 }
 
@@ -179,3 +193,17 @@ func calculateCPUPercent(previousCPU, previousSystem uint64, v *types.Stats) flo
 	}
 	return cpuPercent
 }
+
+// calculateNetwork returns the bytes received and sent over all the
+// interfaces of the container.
+func calculateNetwork(v *types.Stats) (float64, float64) {
+	var rx, tx float64
+	if v.Network != nil {
+		rx, tx = float64(v.Network.RxBytes), float64(v.Network.TxBytes)
+	}
+	for _, n := range v.Networks {
+		rx += float64(n.RxBytes)
+		tx += float64(n.TxBytes)
+	}
+	return rx, tx
+}
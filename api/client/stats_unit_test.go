@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"sync"
 	"testing"
+
+	"github.com/docker/docker/api/types"
 )
 
 func TestDisplay(t *testing.T) {
@@ -27,3 +29,41 @@ func TestDisplay(t *testing.T) {
 		t.Fatalf("c.Display() = %q, want %q", got, want)
 	}
 }
+
+func TestDisplayNetworks(t *testing.T) {
+	c := &containerStats{
+		Name:      "app",
+		NetworkRx: 3000,
+		NetworkTx: 30000,
+		Networks: map[string]types.Network{
+			"eth1": {RxBytes: 2000, TxBytes: 20000},
+			"eth0": {RxBytes: 1000, TxBytes: 10000},
+		},
+		mu: sync.RWMutex{},
+	}
+	var b bytes.Buffer
+	if err := c.Display(&b); err != nil {
+		t.Fatalf("c.Display() gave error: %s", err)
+	}
+	got := b.String()
+	want := "app\t0.00%\t0 B/0 B\t0.00%\t3 kB/30 kB\n" +
+		"  eth0\t\t\t\t1 kB/10 kB\n" +
+		"  eth1\t\t\t\t2 kB/20 kB\n"
+	if got != want {
+		t.Fatalf("c.Display() = %q, want %q", got, want)
+	}
+}
+
+func TestCalculateNetwork(t *testing.T) {
+	rx, tx := calculateNetwork(&types.Stats{Network: &types.Network{RxBytes: 10, TxBytes: 20}})
+	if rx != 10 || tx != 20 {
+		t.Fatalf("Unexpected aggregate network %v/%v", rx, tx)
+	}
+	rx, tx = calculateNetwork(&types.Stats{Networks: map[string]types.Network{
+		"eth0": {RxBytes: 10, TxBytes: 20},
+		"eth1": {RxBytes: 1, TxBytes: 2},
+	}})
+	if rx != 11 || tx != 22 {
+		t.Fatalf("Unexpected network %v/%v", rx, tx)
+	}
+}
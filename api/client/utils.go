@@ -235,17 +235,31 @@ func (cli *DockerCli) resizeTty(id string, isExec bool) {
 }
 
 func waitForExit(cli *DockerCli, containerID string) (int, error) {
-	stream, _, err := cli.call("POST", "/containers/"+containerID+"/wait", nil, nil)
+	res, err := waitForCondition(cli, containerID, "")
 	if err != nil {
 		return -1, err
 	}
+	return res.StatusCode, nil
+}
+
+// waitForCondition blocks until the container meets the condition, the
+// daemon defaults to "not-running" if it is empty.
+func waitForCondition(cli *DockerCli, containerID, condition string) (*types.ContainerWaitResponse, error) {
+	v := url.Values{}
+	if condition != "" {
+		v.Set("condition", condition)
+	}
+	stream, _, err := cli.call("POST", "/containers/"+containerID+"/wait?"+v.Encode(), nil, nil)
+	if err != nil {
+		return nil, err
+	}
 
 	var res types.ContainerWaitResponse
 	if err := json.NewDecoder(stream).Decode(&res); err != nil {
-		return -1, err
+		return nil, err
 	}
 
-	return res.StatusCode, nil
+	return &res, nil
 }
 
 // getExitCode perform an inspect on the container. It returns
@@ -268,6 +282,24 @@ func getExitCode(cli *DockerCli, containerID string) (bool, int, error) {
 	return c.State.Running, c.State.ExitCode, nil
 }
 
+// getAutoRemove performs an inspect on the container. It returns whether
+// the daemon removes the container when it exits, which is always false for
+// daemons that don't support AutoRemove.
+func getAutoRemove(cli *DockerCli, containerID string) (bool, error) {
+	stream, _, err := cli.call("GET", "/containers/"+containerID+"/json", nil, nil)
+	if err != nil {
+		return false, err
+	}
+	defer stream.Close()
+
+	var c types.ContainerJSON
+	if err := json.NewDecoder(stream).Decode(&c); err != nil {
+		return false, err
+	}
+
+	return c.HostConfig != nil && c.HostConfig.AutoRemove, nil
+}
+
 // getExecExitCode perform an inspect on the exec command. It returns
 // the running state and the exit code.
 func getExecExitCode(cli *DockerCli, execID string) (bool, int, error) {
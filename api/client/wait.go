@@ -10,22 +10,26 @@ import (
 //
 // If more than one container is specified, this will wait synchronously on each container.
 //
-// Usage: docker wait CONTAINER [CONTAINER...]
+// Usage: docker wait [OPTIONS] CONTAINER [CONTAINER...]
 func (cli *DockerCli) CmdWait(args ...string) error {
 	cmd := cli.Subcmd("wait", "CONTAINER [CONTAINER...]", "Block until a container stops, then print its exit code.", true)
+	condition := cmd.String([]string{"-condition"}, "not-running", "Condition to wait for (not-running, next-exit or removed)")
 	cmd.Require(flag.Min, 1)
 
 	cmd.ParseFlags(args, true)
 
 	var encounteredError error
 	for _, name := range cmd.Args() {
-		status, err := waitForExit(cli, name)
+		res, err := waitForCondition(cli, name, *condition)
 		if err != nil {
 			fmt.Fprintf(cli.err, "%s\n", err)
 			encounteredError = fmt.Errorf("Error: failed to wait one or more containers")
-		} else {
-			fmt.Fprintf(cli.out, "%d\n", status)
+			continue
 		}
+		if res.Error != "" {
+			fmt.Fprintf(cli.err, "%s: %s\n", name, res.Error)
+		}
+		fmt.Fprintf(cli.out, "%d\n", res.StatusCode)
 	}
 	return encounteredError
 }
@@ -287,18 +287,12 @@ func (s *Server) postContainersKill(eng *engine.Engine, version version.Version,
 
 	// If we have a signal, look at it. Otherwise, do nothing
 	if sigStr := vars["signal"]; sigStr != "" {
-		// Check if we passed the signal as a number:
-		// The largest legal signal is 31, so let's parse on 5 bits
-		sig, err = strconv.ParseUint(sigStr, 10, 5)
+		// the signal is validated like the stop signal of the container
+		parsed, err := signal.ParseSignal(sigStr)
 		if err != nil {
-			// The signal is not a number, treat it as a string (either like
-			// "KILL" or like "SIGKILL")
-			sig = uint64(signal.SignalMap[strings.TrimPrefix(sigStr, "SIG")])
-		}
-
-		if sig == 0 {
-			return fmt.Errorf("Invalid signal: %s", sigStr)
+			return err
 		}
+		sig = uint64(parsed)
 	}
 
 	if err = s.daemon.ContainerKill(name, sig); err != nil {
@@ -621,7 +615,12 @@ func (s *Server) getContainersStats(eng *engine.Engine, version version.Version,
 		return fmt.Errorf("Missing parameter")
 	}
 
-	return s.daemon.ContainerStats(vars["name"], utils.NewWriteFlusher(w))
+	statsConfig := &daemon.ContainerStatsConfig{
+		AggregateNetwork: version.LessThan("1.19"),
+		OutStream:        utils.NewWriteFlusher(w),
+	}
+
+	return s.daemon.ContainerStats(vars["name"], statsConfig)
 }
 
 func (s *Server) getContainersLogs(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
@@ -957,7 +956,7 @@ func (s *Server) postContainersRestart(eng *engine.Engine, version version.Versi
 		return fmt.Errorf("Missing parameter")
 	}
 
-	timeout, err := strconv.Atoi(r.Form.Get("t"))
+	timeout, err := stopTimeout(version, r.Form.Get("t"))
 	if err != nil {
 		return err
 	}
@@ -988,6 +987,80 @@ func (s *Server) postContainerRename(eng *engine.Engine, version version.Version
 	return nil
 }
 
+func (s *Server) postContainersUpdate(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	if err := checkForJson(r); err != nil {
+		return err
+	}
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+
+	updateConfig := &runconfig.UpdateConfig{}
+	if err := json.NewDecoder(r.Body).Decode(updateConfig); err != nil {
+		return err
+	}
+
+	warnings, err := s.daemon.ContainerUpdate(vars["name"], updateConfig)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, &types.ContainerUpdateResponse{
+		Warnings: warnings,
+	})
+}
+
+func (s *Server) postContainersCheckpoint(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	if err := checkForJson(r); err != nil {
+		return err
+	}
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+
+	config := &runconfig.CheckpointConfig{}
+	if err := json.NewDecoder(r.Body).Decode(config); err != nil {
+		return err
+	}
+
+	if err := s.daemon.ContainerCheckpoint(vars["name"], config); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *Server) postContainersRestore(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	if err := checkForJson(r); err != nil {
+		return err
+	}
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+
+	config := &runconfig.CheckpointConfig{}
+	if err := json.NewDecoder(r.Body).Decode(config); err != nil {
+		return err
+	}
+
+	if err := s.daemon.ContainerRestore(vars["name"], config); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
 func (s *Server) deleteContainers(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	if err := parseForm(r); err != nil {
 		return err
@@ -1080,7 +1153,7 @@ func (s *Server) postContainersStop(eng *engine.Engine, version version.Version,
 		return fmt.Errorf("Missing parameter")
 	}
 
-	seconds, err := strconv.Atoi(r.Form.Get("t"))
+	seconds, err := stopTimeout(version, r.Form.Get("t"))
 	if err != nil {
 		return err
 	}
@@ -1097,7 +1170,20 @@ func (s *Server) postContainersStop(eng *engine.Engine, version version.Version,
 	return nil
 }
 
+// stopTimeout parses the t parameter of stop and restart requests. Without
+// it, the container's own stop timeout applies, a negative timeout. Clients
+// older than 1.19 still have to give it.
+func stopTimeout(version version.Version, t string) (int, error) {
+	if t == "" && !version.LessThan("1.19") {
+		return -1, nil
+	}
+	return strconv.Atoi(t)
+}
+
 func (s *Server) postContainersWait(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := parseForm(r); err != nil {
+		return err
+	}
 	if vars == nil {
 		return fmt.Errorf("Missing parameter")
 	}
@@ -1108,10 +1194,35 @@ func (s *Server) postContainersWait(eng *engine.Engine, version version.Version,
 		return err
 	}
 
-	status, _ := cont.WaitStop(-1 * time.Second)
+	condition := daemon.WaitConditionNotRunning
+	if c := r.Form.Get("condition"); c != "" {
+		condition = daemon.WaitCondition(c)
+	}
+
+	if condition == daemon.WaitConditionRemoved {
+		// The headers are sent as soon as the container is found, so that a
+		// client knows it can start a container it wants to see removed
+		// without missing its removal.
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		output := utils.NewWriteFlusher(w)
+		output.Flush()
+
+		res := &types.ContainerWaitResponse{}
+		if res.StatusCode, res.Error, err = cont.Wait(condition, -1*time.Second); err != nil {
+			res.StatusCode, res.Error = -1, err.Error()
+		}
+		return json.NewEncoder(output).Encode(res)
+	}
+
+	status, errMsg, err := cont.Wait(condition, -1*time.Second)
+	if err != nil {
+		return err
+	}
 
 	return writeJSON(w, http.StatusOK, &types.ContainerWaitResponse{
 		StatusCode: status,
+		Error:      errMsg,
 	})
 }
 
@@ -1525,6 +1636,35 @@ func (s *Server) postContainerExecResize(eng *engine.Engine, version version.Ver
 	return s.daemon.ContainerExecResize(vars["name"], height, width)
 }
 
+func (s *Server) postContainerExecKill(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	err := parseForm(r)
+	if err != nil {
+		return err
+	}
+
+	var sig uint64
+	name := vars["name"]
+
+	// If we have a signal, look at it. Otherwise, kill the process
+	if sigStr := r.Form.Get("signal"); sigStr != "" {
+		parsed, err := signal.ParseSignal(sigStr)
+		if err != nil {
+			return err
+		}
+		sig = uint64(parsed)
+	}
+
+	if err = s.daemon.ContainerExecKill(name, sig); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
 func (s *Server) optionsHandler(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	w.WriteHeader(http.StatusOK)
 	return nil
@@ -1607,28 +1747,32 @@ func createRouter(s *Server, eng *engine.Engine) *mux.Router {
 			"/exec/{id:.*}/json":              s.getExecByID,
 		},
 		"POST": {
-			"/auth":                         s.postAuth,
-			"/commit":                       s.postCommit,
-			"/build":                        s.postBuild,
-			"/images/create":                s.postImagesCreate,
-			"/images/load":                  s.postImagesLoad,
-			"/images/{name:.*}/push":        s.postImagesPush,
-			"/images/{name:.*}/tag":         s.postImagesTag,
-			"/containers/create":            s.postContainersCreate,
-			"/containers/{name:.*}/kill":    s.postContainersKill,
-			"/containers/{name:.*}/pause":   s.postContainersPause,
-			"/containers/{name:.*}/unpause": s.postContainersUnpause,
-			"/containers/{name:.*}/restart": s.postContainersRestart,
-			"/containers/{name:.*}/start":   s.postContainersStart,
-			"/containers/{name:.*}/stop":    s.postContainersStop,
-			"/containers/{name:.*}/wait":    s.postContainersWait,
-			"/containers/{name:.*}/resize":  s.postContainersResize,
-			"/containers/{name:.*}/attach":  s.postContainersAttach,
-			"/containers/{name:.*}/copy":    s.postContainersCopy,
-			"/containers/{name:.*}/exec":    s.postContainerExecCreate,
-			"/exec/{name:.*}/start":         s.postContainerExecStart,
-			"/exec/{name:.*}/resize":        s.postContainerExecResize,
-			"/containers/{name:.*}/rename":  s.postContainerRename,
+			"/auth":                            s.postAuth,
+			"/commit":                          s.postCommit,
+			"/build":                           s.postBuild,
+			"/images/create":                   s.postImagesCreate,
+			"/images/load":                     s.postImagesLoad,
+			"/images/{name:.*}/push":           s.postImagesPush,
+			"/images/{name:.*}/tag":            s.postImagesTag,
+			"/containers/create":               s.postContainersCreate,
+			"/containers/{name:.*}/kill":       s.postContainersKill,
+			"/containers/{name:.*}/pause":      s.postContainersPause,
+			"/containers/{name:.*}/unpause":    s.postContainersUnpause,
+			"/containers/{name:.*}/restart":    s.postContainersRestart,
+			"/containers/{name:.*}/start":      s.postContainersStart,
+			"/containers/{name:.*}/stop":       s.postContainersStop,
+			"/containers/{name:.*}/wait":       s.postContainersWait,
+			"/containers/{name:.*}/resize":     s.postContainersResize,
+			"/containers/{name:.*}/attach":     s.postContainersAttach,
+			"/containers/{name:.*}/copy":       s.postContainersCopy,
+			"/containers/{name:.*}/exec":       s.postContainerExecCreate,
+			"/exec/{name:.*}/start":            s.postContainerExecStart,
+			"/exec/{name:.*}/resize":           s.postContainerExecResize,
+			"/exec/{name:.*}/kill":             s.postContainerExecKill,
+			"/containers/{name:.*}/rename":     s.postContainerRename,
+			"/containers/{name:.*}/update":     s.postContainersUpdate,
+			"/containers/{name:.*}/checkpoint": s.postContainersCheckpoint,
+			"/containers/{name:.*}/restore":    s.postContainersRestore,
 		},
 		"DELETE": {
 			"/containers/{name:.*}": s.deleteContainers,
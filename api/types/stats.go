@@ -48,6 +48,13 @@ type MemoryStats struct {
 	Limit   uint64 `json:"limit"`
 }
 
+type PidsStats struct {
+	// number of pids in the cgroup
+	Current uint64 `json:"current,omitempty"`
+	// active pids hard limit
+	Limit uint64 `json:"limit,omitempty"`
+}
+
 type BlkioStatEntry struct {
 	Major uint64 `json:"major"`
 	Minor uint64 `json:"minor"`
@@ -79,9 +86,15 @@ type Network struct {
 }
 
 type Stats struct {
-	Read        time.Time   `json:"read"`
-	Network     Network     `json:"network,omitempty"`
-	CpuStats    CpuStats    `json:"cpu_stats,omitempty"`
-	MemoryStats MemoryStats `json:"memory_stats,omitempty"`
-	BlkioStats  BlkioStats  `json:"blkio_stats,omitempty"`
+	Read time.Time `json:"read"`
+	// Network is the sum of all the interfaces, only sent to API
+	// versions older than 1.19.
+	Network *Network `json:"network,omitempty"`
+	// Networks holds the statistics of each interface, indexed by
+	// interface name.
+	Networks    map[string]Network `json:"networks,omitempty"`
+	CpuStats    CpuStats           `json:"cpu_stats,omitempty"`
+	MemoryStats MemoryStats        `json:"memory_stats,omitempty"`
+	PidsStats   PidsStats          `json:"pids_stats,omitempty"`
+	BlkioStats  BlkioStats         `json:"blkio_stats,omitempty"`
 }
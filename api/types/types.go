@@ -18,6 +18,12 @@ type ContainerCreateResponse struct {
 	Warnings []string `json:"Warnings"`
 }
 
+// POST /containers/{name:.*}/update
+type ContainerUpdateResponse struct {
+	// Warnings are any warnings encountered while updating the container.
+	Warnings []string `json:"Warnings"`
+}
+
 // POST /containers/{name:.*}/exec
 type ContainerExecCreateResponse struct {
 	// ID is the exec ID.
@@ -34,6 +40,8 @@ type AuthResponse struct {
 type ContainerWaitResponse struct {
 	// StatusCode is the status code of the wait job
 	StatusCode int `json:"StatusCode"`
+	// Error is the error message of the container, if any
+	Error string `json:"Error,omitempty"`
 }
 
 // POST "/commit?container="+containerID
@@ -187,16 +195,35 @@ type ExecStartCheck struct {
 }
 
 type ContainerState struct {
-	Running    bool
-	Paused     bool
-	Restarting bool
-	OOMKilled  bool
-	Dead       bool
-	Pid        int
-	ExitCode   int
-	Error      string
-	StartedAt  time.Time
-	FinishedAt time.Time
+	Running        bool
+	Paused         bool
+	Restarting     bool
+	OOMKilled      bool
+	Dead           bool
+	Checkpointed   bool
+	Pid            int
+	ExitCode       int
+	Error          string
+	StartedAt      time.Time
+	FinishedAt     time.Time
+	CheckpointedAt time.Time
+	NextRestartAt  time.Time
+	Health         *Health `json:",omitempty"`
+}
+
+// Health stores the results of the health check of a container
+type Health struct {
+	Status        string               // starting, healthy or unhealthy
+	FailingStreak int                  // Number of consecutive failed probes
+	Log           []*HealthcheckResult // The last few probes, oldest first
+}
+
+// HealthcheckResult stores the outcome of a single probe
+type HealthcheckResult struct {
+	Start    time.Time
+	End      time.Time
+	ExitCode int    // 0 is healthy, anything else unhealthy
+	Output   string // Combined stdout and stderr of the probe, truncated
 }
 
 // GET "/containers/{name:.*}/json"
@@ -223,5 +250,14 @@ type ContainerJSON struct {
 	VolumesRW       map[string]bool
 	AppArmorProfile string
 	ExecIDs         []string
+	ExecSessions    []ExecSession
 	HostConfig      *runconfig.HostConfig
 }
+
+// ExecSession is an exec command of a container that didn't exit yet
+type ExecSession struct {
+	ID      string
+	Cmd     []string
+	Running bool
+	Pid     int // 0 until the command is started
+}
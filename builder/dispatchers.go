@@ -13,11 +13,14 @@ import (
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/nat"
 	flag "github.com/docker/docker/pkg/mflag"
+	"github.com/docker/docker/pkg/signal"
 	"github.com/docker/docker/runconfig"
 )
 
@@ -441,6 +444,87 @@ func volume(b *Builder, args []string, attributes map[string]bool, original stri
 	return nil
 }
 
+// HEALTHCHECK [--interval=30s] [--timeout=30s] [--retries=3] CMD command
+// HEALTHCHECK NONE
+//
+// Set the probe run inside the container to check that it's still working,
+// or disable the probe inherited from the base image. Accepts the JSON
+// array form for the command.
+//
+func healthcheck(b *Builder, args []string, attributes map[string]bool, original string) error {
+	health := &runconfig.HealthConfig{}
+
+	for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+		parts := strings.SplitN(strings.TrimPrefix(args[0], "--"), "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return fmt.Errorf("HEALTHCHECK option %s requires a value", args[0])
+		}
+		switch parts[0] {
+		case "interval", "timeout":
+			d, err := time.ParseDuration(parts[1])
+			if err != nil || d <= 0 {
+				return fmt.Errorf("Invalid HEALTHCHECK %s: %s", parts[0], parts[1])
+			}
+			if parts[0] == "interval" {
+				health.Interval = d
+			} else {
+				health.Timeout = d
+			}
+		case "retries":
+			retries, err := strconv.Atoi(parts[1])
+			if err != nil || retries <= 0 {
+				return fmt.Errorf("Invalid HEALTHCHECK retries: %s", parts[1])
+			}
+			health.Retries = retries
+		default:
+			return fmt.Errorf("Unknown HEALTHCHECK option: %s", args[0])
+		}
+		args = args[1:]
+	}
+
+	if len(args) == 0 {
+		return fmt.Errorf("HEALTHCHECK requires CMD or NONE")
+	}
+	switch args[0] {
+	case "NONE":
+		health.Test = []string{"NONE"}
+	case "CMD":
+		cmdSlice := handleJsonArgs(args[1:], attributes)
+		if len(cmdSlice) == 0 {
+			return fmt.Errorf("Missing command after HEALTHCHECK CMD")
+		}
+		if attributes["json"] {
+			health.Test = append([]string{"CMD"}, cmdSlice...)
+		} else {
+			health.Test = []string{"CMD-SHELL", cmdSlice[0]}
+		}
+	default:
+		return fmt.Errorf("Unknown type %q in HEALTHCHECK (try CMD)", args[0])
+	}
+
+	b.Config.Healthcheck = health
+	return b.commit("", b.Config.Cmd, fmt.Sprintf("HEALTHCHECK %q", health.Test))
+}
+
+// STOPSIGNAL signal
+//
+// Set the signal that will be used to stop the container, either as a
+// number or as a name like SIGQUIT.
+//
+func stopSignal(b *Builder, args []string, attributes map[string]bool, original string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("STOPSIGNAL requires exactly one argument")
+	}
+
+	sig := args[0]
+	if _, err := signal.ParseSignal(sig); err != nil {
+		return err
+	}
+
+	b.Config.StopSignal = sig
+	return b.commit("", b.Config.Cmd, fmt.Sprintf("STOPSIGNAL %v", sig))
+}
+
 // INSERT is no longer accepted, but we still parse it.
 func insert(b *Builder, args []string, attributes map[string]bool, original string) error {
 	return fmt.Errorf("INSERT has been deprecated. Please use ADD instead")
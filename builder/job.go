@@ -26,14 +26,16 @@ import (
 
 // whitelist of commands allowed for a commit/import
 var validCommitCommands = map[string]bool{
-	"entrypoint": true,
-	"cmd":        true,
-	"user":       true,
-	"workdir":    true,
-	"env":        true,
-	"volume":     true,
-	"expose":     true,
-	"onbuild":    true,
+	"entrypoint":  true,
+	"cmd":         true,
+	"user":        true,
+	"workdir":     true,
+	"env":         true,
+	"volume":      true,
+	"expose":      true,
+	"onbuild":     true,
+	"healthcheck": true,
+	"stopsignal":  true,
 }
 
 type Config struct {
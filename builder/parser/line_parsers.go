@@ -293,3 +293,59 @@ func parseMaybeJSONToList(rest string) (*Node, map[string]bool, error) {
 
 	return parseStringsWhitespaceDelimited(rest)
 }
+
+// parseHealthcheck parses the options of HEALTHCHECK into --name=value
+// nodes, followed by the CMD keyword and the command as parsed by
+// parseMaybeJSON, or by the lone NONE keyword.
+//
+// HEALTHCHECK --retries=5 CMD curl -f localhost -> (healthcheck "--retries=5" "CMD" "curl -f localhost")
+//
+func parseHealthcheck(rest string) (*Node, map[string]bool, error) {
+	var options []string
+	for {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if !strings.HasPrefix(rest, "--") {
+			break
+		}
+		end := strings.IndexFunc(rest, unicode.IsSpace)
+		if end == -1 {
+			end = len(rest)
+		}
+		options = append(options, rest[:end])
+		rest = rest[end:]
+	}
+
+	keyword, args := rest, ""
+	if end := strings.IndexFunc(rest, unicode.IsSpace); end != -1 {
+		keyword, args = rest[:end], strings.TrimSpace(rest[end:])
+	}
+
+	var (
+		node  *Node
+		attrs map[string]bool
+	)
+	switch strings.ToUpper(keyword) {
+	case "NONE":
+		if len(options) != 0 || args != "" {
+			return nil, nil, fmt.Errorf("HEALTHCHECK NONE takes no arguments")
+		}
+		node = &Node{Value: "NONE"}
+	case "CMD":
+		cmd, cmdAttrs, err := parseMaybeJSON(args)
+		if err != nil {
+			return nil, nil, err
+		}
+		if cmd == nil {
+			return nil, nil, fmt.Errorf("Missing command after HEALTHCHECK CMD")
+		}
+		node = &Node{Value: "CMD", Next: cmd}
+		attrs = cmdAttrs
+	default:
+		return nil, nil, fmt.Errorf("Unknown type %q in HEALTHCHECK (try CMD)", keyword)
+	}
+
+	for i := len(options) - 1; i >= 0; i-- {
+		node = &Node{Value: options[i], Next: node}
+	}
+	return node, attrs, nil
+}
@@ -0,0 +1,51 @@
+package daemon
+
+import (
+	"fmt"
+
+	"github.com/docker/docker/runconfig"
+)
+
+// ContainerCheckpoint dumps the processes of a running container to disk
+// with CRIU.
+func (daemon *Daemon) ContainerCheckpoint(name string, config *runconfig.CheckpointConfig) error {
+	container, err := daemon.Get(name)
+	if err != nil {
+		return err
+	}
+
+	if !container.IsRunning() {
+		return fmt.Errorf("Container %s not running", name)
+	}
+
+	if err := container.Checkpoint(config); err != nil {
+		return fmt.Errorf("Cannot checkpoint container %s: %s", name, err)
+	}
+
+	container.LogEvent("checkpoint")
+	return nil
+}
+
+// ContainerRestore starts the processes of a container again from its
+// checkpoint.
+func (daemon *Daemon) ContainerRestore(name string, config *runconfig.CheckpointConfig) error {
+	container, err := daemon.Get(name)
+	if err != nil {
+		return err
+	}
+
+	if container.IsRunning() {
+		return fmt.Errorf("Container %s already running", name)
+	}
+
+	if !container.HasCheckpoint() {
+		return fmt.Errorf("Container %s has no checkpoint to restore", name)
+	}
+
+	if err := container.Restore(config); err != nil {
+		container.LogEvent("die")
+		return fmt.Errorf("Cannot restore container %s: %s", name, err)
+	}
+
+	return nil
+}
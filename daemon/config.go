@@ -3,6 +3,8 @@ package daemon
 import (
 	"github.com/docker/docker/daemon/networkdriver"
 	"github.com/docker/docker/daemon/networkdriver/bridge"
+	"github.com/docker/docker/daemon/networkdriver/macvlan"
+	"github.com/docker/docker/daemon/networkdriver/overlay"
 	"github.com/docker/docker/opts"
 	flag "github.com/docker/docker/pkg/mflag"
 	"github.com/docker/docker/pkg/ulimit"
@@ -19,7 +21,9 @@ const (
 // to the docker daemon when you launch it with say: `docker -d -e lxc`
 // FIXME: separate runtime configuration from http api configuration
 type Config struct {
-	Bridge bridge.Config
+	Bridge  bridge.Config
+	Macvlan macvlan.Config
+	Overlay overlay.Config
 
 	Pidfile              string
 	Root                 string
@@ -40,6 +44,11 @@ type Config struct {
 	Labels               []string
 	Ulimits              map[string]*ulimit.Ulimit
 	LogConfig            runconfig.LogConfig
+	RemappedRoot         string
+	AppArmorProfile      string
+	Init                 bool
+	LiveRestore          bool
+	ShutdownTimeout      int
 }
 
 // InstallFlags adds command-line options to the top-level flag parser for
@@ -61,9 +70,21 @@ func (config *Config) InstallFlags() {
 	flag.StringVar(&config.Bridge.DefaultGatewayIPv4, []string{"-default-gateway"}, "", "Container default gateway IPv4 address")
 	flag.StringVar(&config.Bridge.DefaultGatewayIPv6, []string{"-default-gateway-v6"}, "", "Container default gateway IPv6 address")
 	flag.BoolVar(&config.Bridge.InterContainerCommunication, []string{"#icc", "-icc"}, true, "Enable inter-container communication")
+	flag.BoolVar(&config.Bridge.EnableUserlandProxy, []string{"-userland-proxy"}, true, "Use userland proxy for loopback traffic")
+	flag.StringVar(&config.Macvlan.Parent, []string{"-macvlan-parent"}, "", "Parent interface for --net=macvlan containers")
+	flag.StringVar(&config.Macvlan.Subnet, []string{"-macvlan-subnet"}, "", "IPv4 subnet for --net=macvlan containers")
+	flag.StringVar(&config.Macvlan.Gateway, []string{"-macvlan-gateway"}, "", "IPv4 gateway for --net=macvlan containers")
+	flag.StringVar(&config.Overlay.Store, []string{"-overlay-store"}, "", "Key-value store shared by the overlay network hosts")
+	flag.StringVar(&config.Overlay.BindAddress, []string{"-overlay-bind"}, "", "Local IPv4 address for overlay network traffic")
+	opts.ListVar(&config.Overlay.Networks, []string{"-overlay-network"}, "Overlay network as name:vni:subnet")
 	flag.StringVar(&config.GraphDriver, []string{"s", "-storage-driver"}, "", "Storage driver to use")
 	flag.StringVar(&config.ExecDriver, []string{"e", "-exec-driver"}, "native", "Exec driver to use")
 	flag.BoolVar(&config.EnableSelinuxSupport, []string{"-selinux-enabled"}, false, "Enable selinux support")
+	flag.StringVar(&config.RemappedRoot, []string{"-userns-remap"}, "", "User/Group setting for user namespaces")
+	flag.StringVar(&config.AppArmorProfile, []string{"-default-apparmor-profile"}, "", "Default AppArmor profile for containers")
+	flag.BoolVar(&config.Init, []string{"-init"}, false, "Run an init in the containers to forward signals and reap processes")
+	flag.BoolVar(&config.LiveRestore, []string{"-live-restore"}, false, "Keep containers running while the daemon is down")
+	flag.IntVar(&config.ShutdownTimeout, []string{"-shutdown-timeout"}, 15, "Seconds to wait for containers to stop when the daemon shuts down")
 	flag.IntVar(&config.Mtu, []string{"#mtu", "-mtu"}, 0, "Set the containers network MTU")
 	flag.StringVar(&config.SocketGroup, []string{"G", "-group"}, "docker", "Group for the unix socket")
 	flag.BoolVar(&config.EnableCors, []string{"#api-enable-cors", "#-api-enable-cors"}, false, "Enable CORS headers in the remote API, this is deprecated by --api-cors-header")
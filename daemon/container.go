@@ -26,6 +26,7 @@ import (
 	"github.com/docker/docker/daemon/logger/syslog"
 	"github.com/docker/docker/daemon/network"
 	"github.com/docker/docker/daemon/networkdriver/bridge"
+	"github.com/docker/docker/daemon/networkdriver/macvlan"
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/links"
@@ -37,6 +38,7 @@ import (
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/docker/docker/pkg/promise"
 	"github.com/docker/docker/pkg/resolvconf"
+	"github.com/docker/docker/pkg/signal"
 	"github.com/docker/docker/pkg/stringid"
 	"github.com/docker/docker/pkg/symlink"
 	"github.com/docker/docker/pkg/ulimit"
@@ -91,6 +93,7 @@ type Container struct {
 	daemon                   *Daemon
 	MountLabel, ProcessLabel string
 	AppArmorProfile          string
+	SeccompProfile           string
 	RestartCount             int
 	UpdateDns                bool
 
@@ -260,6 +263,47 @@ func getDevicesFromPath(deviceMapping runconfig.DeviceMapping) (devs []*configs.
 	return devs, fmt.Errorf("error gathering device information while adding custom device %q: %s", deviceMapping.PathOnHost, err)
 }
 
+// getBlkioDevice returns the block device at path on the host, resolving
+// symlinks like the ones in /dev/disk.
+func getBlkioDevice(path string) (*configs.Device, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid block IO device %s: %v", path, err)
+	}
+	device, err := devices.DeviceFromPath(resolved, "")
+	if err != nil {
+		return nil, fmt.Errorf("Invalid block IO device %s: %v", path, err)
+	}
+	if device.Type != 'b' {
+		return nil, fmt.Errorf("Invalid block IO device %s: not a block device", path)
+	}
+	return device, nil
+}
+
+func getBlkioWeightDevices(hostConfig *runconfig.HostConfig) ([]*execdriver.WeightDevice, error) {
+	var weightDevices []*execdriver.WeightDevice
+	for _, wd := range hostConfig.BlkioWeightDevice {
+		device, err := getBlkioDevice(wd.Path)
+		if err != nil {
+			return nil, err
+		}
+		weightDevices = append(weightDevices, &execdriver.WeightDevice{Major: device.Major, Minor: device.Minor, Weight: wd.Weight})
+	}
+	return weightDevices, nil
+}
+
+func getBlkioThrottleDevices(throttles []*runconfig.ThrottleDevice) ([]*execdriver.ThrottleDevice, error) {
+	var throttleDevices []*execdriver.ThrottleDevice
+	for _, td := range throttles {
+		device, err := getBlkioDevice(td.Path)
+		if err != nil {
+			return nil, err
+		}
+		throttleDevices = append(throttleDevices, &execdriver.ThrottleDevice{Major: device.Major, Minor: device.Minor, Rate: td.Rate})
+	}
+	return throttleDevices, nil
+}
+
 func populateCommand(c *Container, env []string) error {
 	en := &execdriver.Network{
 		Mtu:       c.daemon.config.Mtu,
@@ -284,6 +328,27 @@ func populateCommand(c *Container, env []string) error {
 				GlobalIPv6Address:    network.GlobalIPv6Address,
 				GlobalIPv6PrefixLen:  network.GlobalIPv6PrefixLen,
 				IPv6Gateway:          network.IPv6Gateway,
+				HairpinMode:          !c.daemon.config.Bridge.EnableUserlandProxy,
+				RateIngress:          c.hostConfig.NetworkRate.Ingress,
+				RateEgress:           c.hostConfig.NetworkRate.Egress,
+			}
+		}
+	case "macvlan":
+		if !c.Config.NetworkDisabled {
+			if c.daemon.macvlan != nil {
+				en.NamespacePath = c.daemon.macvlan.NamespacePath(c.ID)
+			}
+			if en.NamespacePath == "" {
+				return fmt.Errorf("no macvlan network allocated for %s", c.ID)
+			}
+		}
+	case "overlay":
+		if !c.Config.NetworkDisabled {
+			if c.daemon.overlay != nil {
+				en.NamespacePath = c.daemon.overlay.NamespacePath(c.ID)
+			}
+			if en.NamespacePath == "" {
+				return fmt.Errorf("no overlay network allocated for %s", c.ID)
 			}
 		}
 	case "container":
@@ -353,14 +418,46 @@ func populateCommand(c *Container, env []string) error {
 		rlimits = append(rlimits, rl)
 	}
 
+	weightDevices, err := getBlkioWeightDevices(c.hostConfig)
+	if err != nil {
+		return err
+	}
+	readBpsDevices, err := getBlkioThrottleDevices(c.hostConfig.BlkioDeviceReadBps)
+	if err != nil {
+		return err
+	}
+	writeBpsDevices, err := getBlkioThrottleDevices(c.hostConfig.BlkioDeviceWriteBps)
+	if err != nil {
+		return err
+	}
+	readIOpsDevices, err := getBlkioThrottleDevices(c.hostConfig.BlkioDeviceReadIOps)
+	if err != nil {
+		return err
+	}
+	writeIOpsDevices, err := getBlkioThrottleDevices(c.hostConfig.BlkioDeviceWriteIOps)
+	if err != nil {
+		return err
+	}
+
 	resources := &execdriver.Resources{
-		Memory:     c.hostConfig.Memory,
-		MemorySwap: c.hostConfig.MemorySwap,
-		CpuShares:  c.hostConfig.CpuShares,
-		CpusetCpus: c.hostConfig.CpusetCpus,
-		CpusetMems: c.hostConfig.CpusetMems,
-		CpuQuota:   c.hostConfig.CpuQuota,
-		Rlimits:    rlimits,
+		Memory:                       c.hostConfig.Memory,
+		MemorySwap:                   c.hostConfig.MemorySwap,
+		MemoryReservation:            c.hostConfig.MemoryReservation,
+		KernelMemory:                 c.hostConfig.KernelMemory,
+		MemorySwappiness:             c.hostConfig.MemorySwappiness,
+		OomKillDisable:               c.hostConfig.OomKillDisable,
+		CpuShares:                    c.hostConfig.CpuShares,
+		CpusetCpus:                   c.hostConfig.CpusetCpus,
+		CpusetMems:                   c.hostConfig.CpusetMems,
+		CpuQuota:                     c.hostConfig.CpuQuota,
+		PidsLimit:                    c.hostConfig.PidsLimit,
+		BlkioWeight:                  int64(c.hostConfig.BlkioWeight),
+		BlkioWeightDevice:            weightDevices,
+		BlkioThrottleReadBpsDevice:   readBpsDevices,
+		BlkioThrottleWriteBpsDevice:  writeBpsDevices,
+		BlkioThrottleReadIOPSDevice:  readIOpsDevices,
+		BlkioThrottleWriteIOPSDevice: writeIOpsDevices,
+		Rlimits:                      rlimits,
 	}
 
 	processConfig := execdriver.ProcessConfig{
@@ -374,6 +471,11 @@ func populateCommand(c *Container, env []string) error {
 	processConfig.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
 	processConfig.Env = env
 
+	runInit := c.daemon.config.Init
+	if c.hostConfig.Init != nil {
+		runInit = *c.hostConfig.Init
+	}
+
 	c.command = &execdriver.Command{
 		ID:                 c.ID,
 		Rootfs:             c.RootfsPath(),
@@ -393,7 +495,15 @@ func populateCommand(c *Container, env []string) error {
 		MountLabel:         c.GetMountLabel(),
 		LxcConfig:          lxcConfig,
 		AppArmorProfile:    c.AppArmorProfile,
+		SeccompProfile:     c.SeccompProfile,
 		CgroupParent:       c.hostConfig.CgroupParent,
+		OomScoreAdj:        c.hostConfig.OomScoreAdj,
+		Init:               runInit,
+		Sysctls:            c.hostConfig.Sysctls,
+		ShmSize:            c.hostConfig.ShmSize,
+		GroupAdd:           c.hostConfig.GroupAdd,
+		UIDMapping:         c.daemon.uidMaps,
+		GIDMapping:         c.daemon.gidMaps,
 	}
 
 	return nil
@@ -410,6 +520,16 @@ func (container *Container) Start() (err error) {
 	if container.removalInProgress || container.Dead {
 		return fmt.Errorf("Container is marked for removal and cannot be started.")
 	}
+	container.ManuallyStopped = false
+
+	// a checkpoint can't be restored once the container ran again
+	if !container.CheckpointedAt.IsZero() {
+		container.Checkpointed = false
+		container.CheckpointedAt = time.Time{}
+		if err := os.RemoveAll(container.checkpointPath()); err != nil {
+			logrus.Warnf("Failed to remove the checkpoint of container %s: %v", container.ID, err)
+		}
+	}
 
 	// if we encounter an error during start we need to ensure that any other
 	// setup has been cleaned up properly
@@ -459,6 +579,142 @@ func (container *Container) Start() (err error) {
 	return container.waitForStart()
 }
 
+// Checkpoint dumps the processes of the running container to disk, from
+// where Restore can start them again. The container stops unless
+// config.LeaveRunning is set.
+func (container *Container) Checkpoint(config *runconfig.CheckpointConfig) error {
+	container.Lock()
+	if !container.Running || container.Restarting {
+		container.Unlock()
+		return fmt.Errorf("Container %s is not running", container.ID)
+	}
+	if container.Paused {
+		container.Unlock()
+		return fmt.Errorf("Container %s is paused. Unpause the container before checkpointing", container.ID)
+	}
+	if container.Config.Tty {
+		container.Unlock()
+		return fmt.Errorf("Checkpointing containers with a tty is not supported")
+	}
+	if !config.LeaveRunning {
+		// the processes exit once dumped, this is not a reason to restart them
+		container.Checkpointed = true
+	}
+	container.Unlock()
+
+	if err := os.RemoveAll(container.checkpointPath()); err != nil {
+		container.SetCheckpointed(false)
+		return err
+	}
+	if err := container.daemon.Checkpoint(container, container.checkpointOpts(config)); err != nil {
+		container.SetCheckpointed(false)
+		return err
+	}
+
+	container.Lock()
+	container.CheckpointedAt = time.Now().UTC()
+	err := container.toDisk()
+	container.Unlock()
+	return err
+}
+
+// Restore starts the processes of the container again from its checkpoint,
+// with the network settings they had.
+func (container *Container) Restore(config *runconfig.CheckpointConfig) (err error) {
+	container.Lock()
+	defer container.Unlock()
+
+	if container.Running {
+		return fmt.Errorf("Container %s is already running", container.ID)
+	}
+	if container.CheckpointedAt.IsZero() {
+		return fmt.Errorf("Container %s has no checkpoint to restore", container.ID)
+	}
+	if container.removalInProgress || container.Dead {
+		return fmt.Errorf("Container is marked for removal and cannot be restored.")
+	}
+
+	defer func() {
+		if err != nil {
+			container.setError(err)
+			container.toDisk()
+			container.cleanup()
+		}
+	}()
+
+	if err := container.restoreCommand(false); err != nil {
+		return err
+	}
+
+	monitor := newContainerMonitor(container, container.hostConfig.RestartPolicy)
+	monitor.restoreOpts = container.checkpointOpts(config)
+	return container.startMonitor(monitor)
+}
+
+// Reattach monitors again the processes of the container, which kept
+// running while the daemon was down.
+func (container *Container) Reattach() (err error) {
+	container.Lock()
+	defer container.Unlock()
+
+	defer func() {
+		if err != nil {
+			container.setError(err)
+			container.toDisk()
+			container.cleanup()
+		}
+	}()
+
+	if err := container.restoreCommand(true); err != nil {
+		return err
+	}
+
+	monitor := newContainerMonitor(container, container.hostConfig.RestartPolicy)
+	monitor.reattach = true
+	return container.startMonitor(monitor)
+}
+
+// restoreCommand sets up the resources of the container and its command
+// for processes that ran before with the same network settings, and that
+// are still running if running is set.
+func (container *Container) restoreCommand(running bool) error {
+	if err := container.Mount(); err != nil {
+		return err
+	}
+	if err := container.RestoreNetwork(running); err != nil {
+		return err
+	}
+	if err := container.prepareVolumes(); err != nil {
+		return err
+	}
+	linkedEnv, err := container.setupLinkedContainers()
+	if err != nil {
+		return err
+	}
+	env := container.createDaemonEnvironment(linkedEnv)
+	if err := populateCommand(container, env); err != nil {
+		return err
+	}
+	return container.setupMounts()
+}
+
+// checkpointPath returns the directory of the images of the container's
+// checkpoint.
+func (container *Container) checkpointPath() string {
+	return filepath.Join(container.root, "checkpoint")
+}
+
+func (container *Container) checkpointOpts(config *runconfig.CheckpointConfig) *execdriver.CheckpointOpts {
+	return &execdriver.CheckpointOpts{
+		ImagesDirectory:         container.checkpointPath(),
+		WorkDirectory:           filepath.Join(container.root, "criu.work"),
+		LeaveRunning:            config.LeaveRunning,
+		TcpEstablished:          config.TcpEstablished,
+		ExternalUnixConnections: config.ExternalUnixConnections,
+		ShellJob:                config.ShellJob,
+	}
+}
+
 func (container *Container) Run() error {
 	if err := container.Start(); err != nil {
 		return err
@@ -576,6 +832,32 @@ func (container *Container) AllocateNetwork() error {
 		return nil
 	}
 
+	if mode.IsMacvlan() {
+		// containers are reached directly on the parent's network, there
+		// are no ports to publish
+		if container.daemon.macvlan == nil {
+			return macvlan.ErrNotConfigured
+		}
+		networkSettings, err := container.daemon.macvlan.Allocate(container.ID, container.Config.MacAddress, "")
+		if err != nil {
+			return err
+		}
+		container.NetworkSettings = networkSettings
+		return nil
+	}
+
+	if mode.IsOverlay() {
+		if container.daemon.overlay == nil {
+			return fmt.Errorf("No overlay networks are configured on this daemon")
+		}
+		networkSettings, err := container.daemon.overlay.Allocate(container.ID, mode.OverlayNetwork(), container.Config.MacAddress, "")
+		if err != nil {
+			return err
+		}
+		container.NetworkSettings = networkSettings
+		return nil
+	}
+
 	var (
 		err error
 		eng = container.daemon.eng
@@ -644,7 +926,18 @@ func (container *Container) ReleaseNetwork() {
 		return
 	}
 
-	bridge.Release(container.ID)
+	switch mode := container.hostConfig.NetworkMode; {
+	case mode.IsMacvlan():
+		if container.daemon.macvlan != nil {
+			container.daemon.macvlan.Release(container.ID)
+		}
+	case mode.IsOverlay():
+		if container.daemon.overlay != nil {
+			container.daemon.overlay.Release(container.ID)
+		}
+	default:
+		bridge.Release(container.ID)
+	}
 
 	container.NetworkSettings = &network.Settings{}
 }
@@ -653,7 +946,10 @@ func (container *Container) isNetworkAllocated() bool {
 	return container.NetworkSettings.IPAddress != ""
 }
 
-func (container *Container) RestoreNetwork() error {
+// RestoreNetwork allocates the network of the container again with the same
+// settings. The network namespace of a container whose processes kept running
+// is taken over instead of created.
+func (container *Container) RestoreNetwork(running bool) error {
 	mode := container.hostConfig.NetworkMode
 	// Don't attempt a restore if we previously didn't allocate networking.
 	// This might be a legacy container with no network allocated, in which case the
@@ -662,6 +958,28 @@ func (container *Container) RestoreNetwork() error {
 		return nil
 	}
 
+	if mode.IsMacvlan() {
+		if container.daemon.macvlan == nil {
+			return macvlan.ErrNotConfigured
+		}
+		if running {
+			return container.daemon.macvlan.Reattach(container.ID, container.NetworkSettings.IPAddress)
+		}
+		_, err := container.daemon.macvlan.Allocate(container.ID, container.NetworkSettings.MacAddress, container.NetworkSettings.IPAddress)
+		return err
+	}
+
+	if mode.IsOverlay() {
+		if container.daemon.overlay == nil {
+			return fmt.Errorf("No overlay networks are configured on this daemon")
+		}
+		if running {
+			return container.daemon.overlay.Reattach(container.ID, mode.OverlayNetwork(), container.NetworkSettings.MacAddress, container.NetworkSettings.IPAddress)
+		}
+		_, err := container.daemon.overlay.Allocate(container.ID, mode.OverlayNetwork(), container.NetworkSettings.MacAddress, container.NetworkSettings.IPAddress)
+		return err
+	}
+
 	eng := container.daemon.eng
 
 	// Re-allocate the interface with the same IP and MAC address.
@@ -681,7 +999,17 @@ func (container *Container) RestoreNetwork() error {
 // cleanup releases any network resources allocated to the container along with any rules
 // around how containers are linked together.  It also unmounts the container's root filesystem.
 func (container *Container) cleanup() {
-	container.ReleaseNetwork()
+	// Checkpointed is set before the processes are dumped, while
+	// CheckpointedAt is only recorded once the dump succeeded, after they
+	// have exited and been cleaned up.
+	if !container.Checkpointed {
+		container.ReleaseNetwork()
+	} else {
+		// keep the network settings to restore the checkpoint with them
+		settings := container.NetworkSettings
+		container.ReleaseNetwork()
+		container.NetworkSettings = settings
+	}
 
 	// Disable all active links
 	if container.activeLinks != nil {
@@ -694,7 +1022,7 @@ func (container *Container) cleanup() {
 		logrus.Errorf("%v: Failed to umount filesystem: %v", container.ID, err)
 	}
 
-	for _, eConfig := range container.execCommands.s {
+	for _, eConfig := range container.execCommands.configs() {
 		container.daemon.unregisterExecCommand(eConfig)
 	}
 }
@@ -790,9 +1118,10 @@ func (container *Container) Stop(seconds int) error {
 		return nil
 	}
 
-	// 1. Send a SIGTERM
-	if err := container.killPossiblyDeadProcess(15); err != nil {
-		logrus.Infof("Failed to send SIGTERM to the process, force killing")
+	// 1. Send the stop signal, SIGTERM unless configured otherwise
+	stopSignal := container.stopSignal()
+	if err := container.killPossiblyDeadProcess(int(stopSignal)); err != nil {
+		logrus.Infof("Failed to send signal %d to the process, force killing", stopSignal)
 		if err := container.killPossiblyDeadProcess(9); err != nil {
 			return err
 		}
@@ -800,7 +1129,7 @@ func (container *Container) Stop(seconds int) error {
 
 	// 2. Wait for the process to exit on its own
 	if _, err := container.WaitStop(time.Duration(seconds) * time.Second); err != nil {
-		logrus.Infof("Container %v failed to exit within %d seconds of SIGTERM - using the force", container.ID, seconds)
+		logrus.Infof("Container %v failed to exit within %d seconds of signal %d - using the force", container.ID, seconds, stopSignal)
 		// 3. If it doesn't, then send SIGKILL
 		if err := container.Kill(); err != nil {
 			container.WaitStop(-1 * time.Second)
@@ -810,6 +1139,28 @@ func (container *Container) Stop(seconds int) error {
 	return nil
 }
 
+// stopSignal returns the signal sent to stop the container, SIGTERM unless
+// the container or its image sets another one.
+func (container *Container) stopSignal() syscall.Signal {
+	if container.Config.StopSignal != "" {
+		sig, err := signal.ParseSignal(container.Config.StopSignal)
+		if err == nil {
+			return sig
+		}
+		logrus.Warnf("Ignoring invalid stop signal of container %s: %v", container.ID, err)
+	}
+	return syscall.SIGTERM
+}
+
+// stopTimeout returns the seconds to wait for the container to stop before
+// killing it, defaultTimeout unless the container sets its own.
+func (container *Container) stopTimeout(defaultTimeout int) int {
+	if container.Config.StopTimeout != nil {
+		return *container.Config.StopTimeout
+	}
+	return defaultTimeout
+}
+
 func (container *Container) Restart(seconds int) error {
 	// Avoid unnecessarily unmounting and then directly mounting
 	// the container when the container stops and then starts
@@ -856,7 +1207,11 @@ func (container *Container) Export() (archive.Archive, error) {
 		return nil, err
 	}
 
-	archive, err := archive.Tar(container.basefs, archive.Uncompressed)
+	archive, err := archive.TarWithOptions(container.basefs, &archive.TarOptions{
+		Compression: archive.Uncompressed,
+		UIDMaps:     container.daemon.uidMaps,
+		GIDMaps:     container.daemon.gidMaps,
+	})
 	if err != nil {
 		container.Unmount()
 		return nil, err
@@ -1003,6 +1358,8 @@ func (container *Container) Copy(resource string) (io.ReadCloser, error) {
 	archive, err := archive.TarWithOptions(basePath, &archive.TarOptions{
 		Compression:  archive.Uncompressed,
 		IncludeFiles: filter,
+		UIDMaps:      container.daemon.uidMaps,
+		GIDMaps:      container.daemon.gidMaps,
 	})
 	if err != nil {
 		return nil, err
@@ -1446,7 +1803,12 @@ func (container *Container) startLogging() error {
 }
 
 func (container *Container) waitForStart() error {
-	container.monitor = newContainerMonitor(container, container.hostConfig.RestartPolicy)
+	return container.startMonitor(newContainerMonitor(container, container.hostConfig.RestartPolicy))
+}
+
+// startMonitor runs the container with monitor.
+func (container *Container) startMonitor(monitor *containerMonitor) error {
+	container.monitor = monitor
 
 	// block until we either receive an error from the initial start of the container's
 	// process or until the process is running in the container
@@ -1,8 +1,11 @@
 package daemon
 
 import (
-	"github.com/docker/docker/nat"
+	"syscall"
 	"testing"
+
+	"github.com/docker/docker/nat"
+	"github.com/docker/docker/runconfig"
 )
 
 func TestParseNetworkOptsPrivateOnly(t *testing.T) {
@@ -195,3 +198,33 @@ func TestValidContainerNames(t *testing.T) {
 		}
 	}
 }
+
+func TestContainerStopSignal(t *testing.T) {
+	c := &Container{Config: &runconfig.Config{}}
+	if s := c.stopSignal(); s != syscall.SIGTERM {
+		t.Fatalf("Expected the default stop signal SIGTERM, got %v", s)
+	}
+
+	c.Config.StopSignal = "SIGKILL"
+	if s := c.stopSignal(); s != syscall.SIGKILL {
+		t.Fatalf("Expected the stop signal SIGKILL, got %v", s)
+	}
+
+	c.Config.StopSignal = "NOPE"
+	if s := c.stopSignal(); s != syscall.SIGTERM {
+		t.Fatalf("Expected an invalid stop signal to fall back to SIGTERM, got %v", s)
+	}
+}
+
+func TestContainerStopTimeout(t *testing.T) {
+	c := &Container{Config: &runconfig.Config{}}
+	if s := c.stopTimeout(15); s != 15 {
+		t.Fatalf("Expected the default stop timeout 15, got %d", s)
+	}
+
+	timeout := 0
+	c.Config.StopTimeout = &timeout
+	if s := c.stopTimeout(15); s != 0 {
+		t.Fatalf("Expected the stop timeout 0 of the container, got %d", s)
+	}
+}
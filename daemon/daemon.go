@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
 	"io"
@@ -10,10 +11,13 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/docker/libcontainer/apparmor"
+	"github.com/docker/libcontainer/cgroups/systemd"
 	"github.com/docker/libcontainer/label"
 
 	"github.com/Sirupsen/logrus"
@@ -23,17 +27,22 @@ import (
 	"github.com/docker/docker/daemon/execdriver"
 	"github.com/docker/docker/daemon/execdriver/execdrivers"
 	"github.com/docker/docker/daemon/execdriver/lxc"
+	"github.com/docker/docker/daemon/execdriver/native/seccomp"
 	"github.com/docker/docker/daemon/graphdriver"
 	_ "github.com/docker/docker/daemon/graphdriver/vfs"
 	"github.com/docker/docker/daemon/network"
 	"github.com/docker/docker/daemon/networkdriver/bridge"
+	"github.com/docker/docker/daemon/networkdriver/macvlan"
+	"github.com/docker/docker/daemon/networkdriver/overlay"
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/graph"
 	"github.com/docker/docker/image"
+	"github.com/docker/docker/opts"
 	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/broadcastwriter"
 	"github.com/docker/docker/pkg/fileutils"
 	"github.com/docker/docker/pkg/graphdb"
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/docker/docker/pkg/namesgenerator"
 	"github.com/docker/docker/pkg/parsers"
@@ -55,6 +64,22 @@ import (
 var (
 	validContainerNameChars   = `[a-zA-Z0-9][a-zA-Z0-9_.-]`
 	validContainerNamePattern = regexp.MustCompile(`^/?` + validContainerNameChars + `+$`)
+
+	// shutdownParallelism is the number of containers stopped at once when
+	// the daemon shuts down
+	shutdownParallelism = 32 * runtime.NumCPU()
+)
+
+// shutdownGracePeriod is the time left to the daemon to clean up once its
+// containers are stopped when it shuts down
+const shutdownGracePeriod = 10 * time.Second
+
+const (
+	// defaultAppArmorProfile is the profile the native driver installs and
+	// runs containers with
+	defaultAppArmorProfile = "docker-default"
+	// appArmorProfilesPath lists the profiles loaded into the kernel
+	appArmorProfilesPath = "/sys/kernel/security/apparmor/profiles"
 )
 
 type contStore struct {
@@ -108,10 +133,14 @@ type Daemon struct {
 	containerGraph   *graphdb.Database
 	driver           graphdriver.Driver
 	execDriver       execdriver.Driver
+	macvlan          *macvlan.Driver
+	overlay          *overlay.Driver
 	statsCollector   *statsCollector
 	defaultLogConfig runconfig.LogConfig
 	RegistryService  *registry.Service
 	EventsService    *events.Events
+	uidMaps          []idtools.IDMap
+	gidMaps          []idtools.IDMap
 }
 
 // Install installs daemon capabilities to eng.
@@ -221,39 +250,46 @@ func (daemon *Daemon) register(container *Container, updateSuffixarray bool) err
 
 	// FIXME: if the container is supposed to be running but is not, auto restart it?
 	//        if so, then we need to restart monitor and init a new lock
-	// If the container is supposed to be running, make sure of it
-	if container.IsRunning() {
-		logrus.Debugf("killing old running container %s", container.ID)
+	// If the container is supposed to be running, make sure of it, unless
+	// it is reattached to once all the containers are registered
+	if container.IsRunning() && !daemon.config.LiveRestore {
+		daemon.killStaleContainer(container)
+	}
+
+	return nil
+}
 
-		existingPid := container.Pid
-		container.SetStopped(&execdriver.ExitStatus{ExitCode: 0})
+// killStaleContainer kills the processes left by a container which was
+// running when the daemon stopped, and marks it stopped.
+func (daemon *Daemon) killStaleContainer(container *Container) {
+	logrus.Debugf("killing old running container %s", container.ID)
 
-		// We only have to handle this for lxc because the other drivers will ensure that
-		// no processes are left when docker dies
-		if container.ExecDriver == "" || strings.Contains(container.ExecDriver, "lxc") {
-			lxc.KillLxc(container.ID, 9)
-		} else {
-			// use the current driver and ensure that the container is dead x.x
-			cmd := &execdriver.Command{
-				ID: container.ID,
-			}
-			var err error
-			cmd.ProcessConfig.Process, err = os.FindProcess(existingPid)
-			if err != nil {
-				logrus.Debugf("cannot find existing process for %d", existingPid)
-			}
-			daemon.execDriver.Terminate(cmd)
-		}
+	existingPid := container.Pid
+	container.SetStopped(&execdriver.ExitStatus{ExitCode: 0})
 
-		if err := container.Unmount(); err != nil {
-			logrus.Debugf("unmount error %s", err)
+	// We only have to handle this for lxc because the other drivers will ensure that
+	// no processes are left when docker dies
+	if container.ExecDriver == "" || strings.Contains(container.ExecDriver, "lxc") {
+		lxc.KillLxc(container.ID, 9)
+	} else {
+		// use the current driver and ensure that the container is dead x.x
+		cmd := &execdriver.Command{
+			ID: container.ID,
 		}
-		if err := container.ToDisk(); err != nil {
-			logrus.Debugf("saving stopped state to disk %s", err)
+		var err error
+		cmd.ProcessConfig.Process, err = os.FindProcess(existingPid)
+		if err != nil {
+			logrus.Debugf("cannot find existing process for %d", existingPid)
 		}
+		daemon.execDriver.Terminate(cmd)
 	}
 
-	return nil
+	if err := container.Unmount(); err != nil {
+		logrus.Debugf("unmount error %s", err)
+	}
+	if err := container.ToDisk(); err != nil {
+		logrus.Debugf("saving stopped state to disk %s", err)
+	}
 }
 
 func (daemon *Daemon) ensureName(container *Container) error {
@@ -345,14 +381,48 @@ func (daemon *Daemon) restore() error {
 		registeredContainers = append(registeredContainers, container)
 	}
 
+	// reattach to the containers which kept running while the daemon was down
+	if daemon.config.LiveRestore {
+		for _, container := range registeredContainers {
+			if !container.IsRunning() {
+				continue
+			}
+			logrus.Debugf("Reattaching to container %s", container.ID)
+			if err := container.Reattach(); err != nil {
+				logrus.Errorf("Failed to reattach to container %s: %s", container.ID, err)
+				daemon.killStaleContainer(container)
+			}
+		}
+	}
+
+	// the endpoints of the containers which didn't keep running are left
+	// in the store of the overlay networks
+	if daemon.overlay != nil {
+		daemon.overlay.Purge()
+	}
+
+	// remove the containers which exited while the daemon was down and were
+	// meant to be removed
+	for _, container := range registeredContainers {
+		if container.hostConfig.AutoRemove && !container.IsRunning() && !container.StartedAt.IsZero() {
+			daemon.autoRemove(container)
+		}
+	}
+
 	// check the restart policy on the containers and restart any container with
-	// the restart policy of "always"
+	// the restart policy of "always", or "unless-stopped" unless a user stopped it
 	if daemon.config.AutoRestart {
 		logrus.Debug("Restarting containers...")
 
 		for _, container := range registeredContainers {
-			if container.hostConfig.RestartPolicy.Name == "always" ||
-				(container.hostConfig.RestartPolicy.Name == "on-failure" && container.ExitCode != 0) {
+			// starting a checkpointed container would discard its checkpoint
+			if container.Checkpointed {
+				continue
+			}
+			policy := container.hostConfig.RestartPolicy
+			if policy.Name == "always" ||
+				(policy.Name == "unless-stopped" && !container.ManuallyStopped) ||
+				(policy.Name == "on-failure" && container.ExitCode != 0) {
 				logrus.Debugf("Starting container %s", container.ID)
 
 				if err := container.Start(); err != nil {
@@ -453,6 +523,9 @@ func (daemon *Daemon) mergeAndVerifyConfig(config *runconfig.Config, img *image.
 	if config.Entrypoint.Len() == 0 && config.Cmd.Len() == 0 {
 		return nil, fmt.Errorf("No command specified")
 	}
+	if config.StopTimeout != nil && *config.StopTimeout < 0 {
+		return nil, fmt.Errorf("Invalid stop timeout: %d, it can't be negative", *config.StopTimeout)
+	}
 	return warnings, nil
 }
 
@@ -566,16 +639,27 @@ func parseSecurityOpt(container *Container, config *runconfig.HostConfig) error
 		err       error
 	)
 
+	container.AppArmorProfile = ""
 	for _, opt := range config.SecurityOpt {
-		con := strings.SplitN(opt, ":", 2)
-		if len(con) == 1 {
+		// options are either key:value or key=value, seccomp profiles are
+		// JSON documents which contain colons themselves
+		i := strings.IndexAny(opt, ":=")
+		if i == -1 {
 			return fmt.Errorf("Invalid --security-opt: %q", opt)
 		}
+		con := []string{opt[:i], opt[i+1:]}
 		switch con[0] {
 		case "label":
 			labelOpts = append(labelOpts, con[1])
 		case "apparmor":
 			container.AppArmorProfile = con[1]
+		case "seccomp":
+			if con[1] != "unconfined" {
+				if _, err := seccomp.LoadProfile(con[1]); err != nil {
+					return fmt.Errorf("Invalid --security-opt: %v", err)
+				}
+			}
+			container.SeccompProfile = con[1]
 		default:
 			return fmt.Errorf("Invalid --security-opt: %q", opt)
 		}
@@ -585,6 +669,66 @@ func parseSecurityOpt(container *Container, config *runconfig.HostConfig) error
 	return err
 }
 
+// setAppArmorProfile records the AppArmor profile the container runs with,
+// the profile given with --security-opt, the daemon's default profile or the
+// driver's default, and verifies that it is loaded on the host.
+func (daemon *Daemon) setAppArmorProfile(container *Container, hostConfig *runconfig.HostConfig) error {
+	if container.AppArmorProfile == "" && apparmor.IsEnabled() {
+		switch {
+		case hostConfig.Privileged:
+			container.AppArmorProfile = "unconfined"
+		case daemon.config.AppArmorProfile != "":
+			container.AppArmorProfile = daemon.config.AppArmorProfile
+		case strings.HasPrefix(daemon.execDriver.Name(), "native"):
+			container.AppArmorProfile = defaultAppArmorProfile
+		}
+	}
+	return checkAppArmorProfile(container.AppArmorProfile)
+}
+
+// checkAppArmorProfile returns an error if the AppArmor profile name is not
+// loaded on the host.
+func checkAppArmorProfile(name string) error {
+	if name == "" || name == "unconfined" {
+		return nil
+	}
+	if !apparmor.IsEnabled() {
+		return fmt.Errorf("AppArmor is not enabled on the host, cannot apply the AppArmor profile %q", name)
+	}
+	loaded, err := isAppArmorProfileLoaded(name)
+	if err != nil {
+		return err
+	}
+	if !loaded {
+		return fmt.Errorf("AppArmor profile %q is not loaded", name)
+	}
+	return nil
+}
+
+// isAppArmorProfileLoaded returns true if the profile name is loaded into
+// the kernel.
+func isAppArmorProfileLoaded(name string) (bool, error) {
+	f, err := os.Open(appArmorProfilesPath)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	// every line is a profile name followed by its mode, e.g.
+	// "docker-default (enforce)"
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.LastIndex(line, " ("); i != -1 {
+			line = line[:i]
+		}
+		if line == name {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
 func (daemon *Daemon) newContainer(name string, config *runconfig.Config, imgID string) (*Container, error) {
 	var (
 		id  string
@@ -619,9 +763,13 @@ func (daemon *Daemon) newContainer(name string, config *runconfig.Config, imgID
 }
 
 func (daemon *Daemon) createRootfs(container *Container) error {
+	rootUID, rootGID, err := daemon.GetRemappedUIDGID()
+	if err != nil {
+		return err
+	}
 	// Step 1: create the container directory.
 	// This doubles as a barrier to avoid race conditions.
-	if err := os.Mkdir(container.root, 0700); err != nil {
+	if err := idtools.MkdirAs(container.root, 0700, rootUID, rootGID); err != nil {
 		return err
 	}
 	initID := fmt.Sprintf("%s-init", container.ID)
@@ -634,7 +782,7 @@ func (daemon *Daemon) createRootfs(container *Container) error {
 	}
 	defer daemon.driver.Put(initID)
 
-	if err := graph.SetupInitLayer(initPath); err != nil {
+	if err := graph.SetupInitLayer(initPath, rootUID, rootGID); err != nil {
 		return err
 	}
 
@@ -770,6 +918,9 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine, registryService
 	if !config.Bridge.EnableIptables && config.Bridge.EnableIpMasq {
 		config.Bridge.EnableIpMasq = false
 	}
+	if config.ShutdownTimeout < 0 {
+		return nil, fmt.Errorf("Invalid --shutdown-timeout: %d, it can't be negative", config.ShutdownTimeout)
+	}
 	config.DisableNetwork = config.Bridge.Iface == disableNetworkBridge
 
 	// Claim the pidfile first, to avoid any and all unexpected race conditions.
@@ -823,11 +974,23 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine, registryService
 		return nil, err
 	}
 
+	uidMaps, gidMaps, err := setupRemappedRoot(config)
+	if err != nil {
+		return nil, err
+	}
+	rootUID, rootGID, err := idtools.GetRootUIDGID(uidMaps, gidMaps)
+	if err != nil {
+		return nil, err
+	}
+	if err := setupDaemonRoot(config, rootUID, rootGID); err != nil {
+		return nil, err
+	}
+
 	// Set the default driver
 	graphdriver.DefaultDriver = config.GraphDriver
 
 	// Load storage driver
-	driver, err := graphdriver.New(config.Root, config.GraphOptions)
+	driver, err := graphdriver.New(config.Root, config.GraphOptions, uidMaps, gidMaps)
 	if err != nil {
 		return nil, fmt.Errorf("error intializing graphdriver: %v", err)
 	}
@@ -855,12 +1018,12 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine, registryService
 
 	daemonRepo := path.Join(config.Root, "containers")
 
-	if err := os.MkdirAll(daemonRepo, 0700); err != nil && !os.IsExist(err) {
+	if err := idtools.MkdirAllAs(daemonRepo, 0700, rootUID, rootGID); err != nil {
 		return nil, err
 	}
 
 	// Migrate the container if it is aufs and aufs is enabled
-	if err = migrateIfAufs(driver, config.Root); err != nil {
+	if err = migrateIfAufs(driver, config.Root, rootUID, rootGID); err != nil {
 		return nil, err
 	}
 
@@ -870,12 +1033,12 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine, registryService
 		return nil, err
 	}
 
-	volumesDriver, err := graphdriver.GetDriver("vfs", config.Root, config.GraphOptions)
+	volumesDriver, err := graphdriver.GetDriver("vfs", config.Root, config.GraphOptions, uidMaps, gidMaps)
 	if err != nil {
 		return nil, err
 	}
 
-	volumes, err := volumes.NewRepository(filepath.Join(config.Root, "volumes"), volumesDriver)
+	volumes, err := volumes.NewRepository(filepath.Join(config.Root, "volumes"), volumesDriver, uidMaps, gidMaps)
 	if err != nil {
 		return nil, err
 	}
@@ -908,10 +1071,25 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine, registryService
 		return nil, fmt.Errorf("Couldn't create Tag store: %s", err)
 	}
 
+	var (
+		macvlanDriver *macvlan.Driver
+		overlayDriver *overlay.Driver
+	)
 	if !config.DisableNetwork {
 		if err := bridge.InitDriver(&config.Bridge); err != nil {
 			return nil, fmt.Errorf("Error initializing Bridge: %v", err)
 		}
+		if config.Macvlan.Parent != "" {
+			if macvlanDriver, err = macvlan.New(&config.Macvlan); err != nil {
+				return nil, fmt.Errorf("Error initializing macvlan network: %v", err)
+			}
+		}
+		if len(config.Overlay.Networks) > 0 {
+			if overlayDriver, err = overlay.New(&config.Overlay); err != nil {
+				return nil, fmt.Errorf("Error initializing overlay networks: %v", err)
+			}
+			overlayDriver.Start()
+		}
 	}
 
 	graphdbPath := path.Join(config.Root, "linkgraph.db")
@@ -934,7 +1112,7 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine, registryService
 
 	if sysInitPath != localCopy {
 		// When we find a suitable dockerinit binary (even if it's our local binary), we copy it into config.Root at localCopy for future use (so that the original can go away without that being a problem, for example during a package upgrade).
-		if err := os.Mkdir(path.Dir(localCopy), 0700); err != nil && !os.IsExist(err) {
+		if err := idtools.MkdirAllAs(path.Dir(localCopy), 0700, rootUID, rootGID); err != nil {
 			return nil, err
 		}
 		if _, err := fileutils.CopyFile(sysInitPath, localCopy); err != nil {
@@ -943,15 +1121,27 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine, registryService
 		if err := os.Chmod(localCopy, 0700); err != nil {
 			return nil, err
 		}
+		if err := os.Chown(localCopy, rootUID, rootGID); err != nil {
+			return nil, err
+		}
 		sysInitPath = localCopy
 	}
 
 	sysInfo := sysinfo.New(false)
 	const runDir = "/var/run/docker"
-	ed, err := execdrivers.NewDriver(config.ExecDriver, runDir, config.Root, sysInitPath, sysInfo)
+	ed, err := execdrivers.NewDriver(config.ExecDriver, runDir, config.Root, sysInitPath, sysInfo, config.LiveRestore)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkAppArmorProfile(config.AppArmorProfile); err != nil {
+		return nil, fmt.Errorf("Invalid --default-apparmor-profile: %v", err)
+	}
+	if config.Init && !strings.Contains(ed.Name(), "native") {
+		return nil, fmt.Errorf("--init is not supported with execdriver: %s", ed.Name())
+	}
+	if config.LiveRestore && !strings.Contains(ed.Name(), "native") {
+		return nil, fmt.Errorf("--live-restore is not supported with execdriver: %s", ed.Name())
+	}
 
 	daemon := &Daemon{
 		ID:               trustKey.PublicKey().KeyID(),
@@ -968,11 +1158,15 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine, registryService
 		driver:           driver,
 		sysInitPath:      sysInitPath,
 		execDriver:       ed,
+		macvlan:          macvlanDriver,
+		overlay:          overlayDriver,
 		eng:              eng,
 		statsCollector:   newStatsCollector(1 * time.Second),
 		defaultLogConfig: config.LogConfig,
 		RegistryService:  registryService,
 		EventsService:    eventsService,
+		uidMaps:          uidMaps,
+		gidMaps:          gidMaps,
 	}
 
 	eng.OnShutdown(func() {
@@ -980,11 +1174,14 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine, registryService
 			logrus.Errorf("Error during daemon.shutdown(): %v", err)
 		}
 	})
+	eng.SetShutdownTimeout(daemon.shutdownTimeout)
 
 	if err := daemon.restore(); err != nil {
 		return nil, err
 	}
 
+	go daemon.execCommandGC()
+
 	// set up filesystem watch on resolv.conf for network changes
 	if err := daemon.setupResolvconfWatcher(); err != nil {
 		return nil, err
@@ -994,29 +1191,164 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine, registryService
 }
 
 func (daemon *Daemon) shutdown() error {
-	group := sync.WaitGroup{}
+	if daemon.config.LiveRestore {
+		logrus.Debug("leaving the containers running for live restore")
+		return nil
+	}
+
 	logrus.Debug("starting clean shutdown of all containers...")
-	for _, container := range daemon.List() {
-		c := container
-		if c.IsRunning() {
-			logrus.Debugf("stopping %s", c.ID)
-			group.Add(1)
+	running := daemon.runningContainers()
+	parents := daemon.linkParents(running)
 
-			go func() {
-				defer group.Done()
-				if err := c.KillSig(15); err != nil {
-					logrus.Debugf("kill 15 error for %s - %s", c.ID, err)
-				}
-				c.WaitStop(-1 * time.Second)
-				logrus.Debugf("container stopped %s", c.ID)
-			}()
-		}
+	// a container is stopped once the containers linking to it are, so that
+	// they keep their dependencies while they shut down
+	stopped := make(map[string]chan struct{}, len(running))
+	for id := range running {
+		stopped[id] = make(chan struct{})
+	}
+
+	workers := make(chan struct{}, shutdownParallelism)
+	group := sync.WaitGroup{}
+	for _, container := range running {
+		group.Add(1)
+		go func(c *Container) {
+			defer group.Done()
+			defer close(stopped[c.ID])
+
+			for _, id := range parents[c.ID] {
+				<-stopped[id]
+			}
+
+			workers <- struct{}{}
+			defer func() { <-workers }()
+
+			logrus.Debugf("stopping %s", c.ID)
+			if err := c.Stop(c.stopTimeout(daemon.config.ShutdownTimeout)); err != nil {
+				logrus.Errorf("Error stopping container %s: %v", c.ID, err)
+				return
+			}
+			logrus.Debugf("container stopped %s", c.ID)
+		}(container)
 	}
 	group.Wait()
 
+	// withdraw this host from the overlay networks once its containers are gone
+	if daemon.overlay != nil {
+		daemon.overlay.Stop()
+	}
+
 	return nil
 }
 
+// shutdownTimeout returns how long the daemon may take to stop its running
+// containers when it shuts down: the longest chain of stop timeouts through
+// their links, once for each batch of workers, and a grace period for the
+// containers to be cleaned up.
+func (daemon *Daemon) shutdownTimeout() time.Duration {
+	if daemon.config.LiveRestore {
+		return shutdownGracePeriod
+	}
+
+	running := daemon.runningContainers()
+	parents := daemon.linkParents(running)
+
+	chains := make(map[string]int, len(running))
+	var chain func(id string) int
+	chain = func(id string) int {
+		if seconds, exists := chains[id]; exists {
+			return seconds
+		}
+		longest := 0
+		for _, parent := range parents[id] {
+			if seconds := chain(parent); seconds > longest {
+				longest = seconds
+			}
+		}
+		chains[id] = longest + running[id].stopTimeout(daemon.config.ShutdownTimeout)
+		return chains[id]
+	}
+
+	longest := 0
+	for id := range running {
+		if seconds := chain(id); seconds > longest {
+			longest = seconds
+		}
+	}
+	batches := (len(running) + shutdownParallelism - 1) / shutdownParallelism
+	return time.Duration(longest*batches)*time.Second + shutdownGracePeriod
+}
+
+// runningContainers returns the running containers by ID.
+func (daemon *Daemon) runningContainers() map[string]*Container {
+	running := make(map[string]*Container)
+	for _, container := range daemon.List() {
+		if container.IsRunning() {
+			running[container.ID] = container
+		}
+	}
+	return running
+}
+
+// linkParents returns the IDs of the containers that link to each of the
+// containers, among these containers. Links that would close a cycle are
+// left out, so that the containers can be stopped in link order.
+func (daemon *Daemon) linkParents(containers map[string]*Container) map[string][]string {
+	parents := make(map[string][]string)
+	for id, container := range containers {
+		children, err := daemon.Children(container.Name)
+		if err != nil {
+			logrus.Debugf("Unable to get the links of %s: %v", container.ID, err)
+			continue
+		}
+		for _, child := range children {
+			if _, exists := containers[child.ID]; exists && child.ID != id {
+				parents[child.ID] = append(parents[child.ID], id)
+			}
+		}
+	}
+	breakLinkCycles(parents)
+	return parents
+}
+
+// breakLinkCycles removes the parents that lead back to a container through
+// its other parents. Links are made to existing containers, so cycles should
+// not happen, but a container waiting for itself would never be stopped.
+func breakLinkCycles(parents map[string][]string) {
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := make(map[string]int, len(parents))
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = visiting
+		kept := parents[id][:0]
+		for _, parent := range parents[id] {
+			if state[parent] == visiting {
+				logrus.Debugf("ignoring the circular link between %s and %s", parent, id)
+				continue
+			}
+			if state[parent] == 0 {
+				visit(parent)
+			}
+			kept = append(kept, parent)
+		}
+		parents[id] = kept
+		state[id] = visited
+	}
+
+	ids := make([]string, 0, len(parents))
+	for id := range parents {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if state[id] == 0 {
+			visit(id)
+		}
+	}
+}
+
 func (daemon *Daemon) Mount(container *Container) error {
 	dir, err := daemon.driver.Get(container.ID, container.GetMountLabel())
 	if err != nil {
@@ -1071,6 +1403,18 @@ func (daemon *Daemon) Kill(c *Container, sig int) error {
 	return daemon.execDriver.Kill(c.command, sig)
 }
 
+func (daemon *Daemon) Checkpoint(c *Container, opts *execdriver.CheckpointOpts) error {
+	return daemon.execDriver.Checkpoint(c.command, opts)
+}
+
+func (daemon *Daemon) Restore(c *Container, pipes *execdriver.Pipes, restoreCallback execdriver.StartCallback, opts *execdriver.CheckpointOpts) (execdriver.ExitStatus, error) {
+	return daemon.execDriver.Restore(c.command, pipes, restoreCallback, opts)
+}
+
+func (daemon *Daemon) Reattach(c *Container, pipes *execdriver.Pipes, startCallback execdriver.StartCallback) (execdriver.ExitStatus, error) {
+	return daemon.execDriver.Reattach(c.command, pipes, startCallback)
+}
+
 func (daemon *Daemon) Stats(c *Container) (*execdriver.ResourceStats, error) {
 	return daemon.execDriver.Stats(c.ID)
 }
@@ -1181,6 +1525,57 @@ func (daemon *Daemon) ImageGetCached(imgID string, config *runconfig.Config) (*i
 	return match, nil
 }
 
+// GetUIDGIDMaps returns the uid and gid mappings of the daemon's user
+// namespace remapping, which are nil unless it is enabled.
+func (daemon *Daemon) GetUIDGIDMaps() ([]idtools.IDMap, []idtools.IDMap) {
+	return daemon.uidMaps, daemon.gidMaps
+}
+
+// GetRemappedUIDGID returns the host uid and gid that root in a container
+// is mapped to. This is 0 and 0 unless the daemon remaps container root.
+func (daemon *Daemon) GetRemappedUIDGID() (int, int, error) {
+	return idtools.GetRootUIDGID(daemon.uidMaps, daemon.gidMaps)
+}
+
+// setupRemappedRoot builds the uid and gid mappings for --userns-remap from
+// the subordinate id ranges of the given user and group.
+func setupRemappedRoot(config *Config) ([]idtools.IDMap, []idtools.IDMap, error) {
+	if config.RemappedRoot == "" {
+		return nil, nil, nil
+	}
+	if config.ExecDriver != "native" {
+		return nil, nil, fmt.Errorf("User namespaces are only supported with the native exec driver")
+	}
+	username, groupname := config.RemappedRoot, config.RemappedRoot
+	if i := strings.Index(config.RemappedRoot, ":"); i >= 0 {
+		username, groupname = config.RemappedRoot[:i], config.RemappedRoot[i+1:]
+	}
+	if username == "" || groupname == "" {
+		return nil, nil, fmt.Errorf("Invalid --userns-remap value %q, expected user[:group]", config.RemappedRoot)
+	}
+	uidMaps, gidMaps, err := idtools.CreateIDMappings(username, groupname)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Can't create ID mappings for --userns-remap %q: %v", config.RemappedRoot, err)
+	}
+	logrus.Infof("User namespaces: container root is mapped to %s:%s", username, groupname)
+	return uidMaps, gidMaps, nil
+}
+
+// setupDaemonRoot moves config.Root to a directory of its own for the
+// remapped root, so that images and containers of different mappings are
+// kept apart. That directory is owned by the remapped root, and the top
+// level root directory must be searchable for it to be reachable.
+func setupDaemonRoot(config *Config, rootUID, rootGID int) error {
+	if rootUID == 0 && rootGID == 0 {
+		return nil
+	}
+	if err := os.Chmod(config.Root, 0701); err != nil {
+		return err
+	}
+	config.Root = filepath.Join(config.Root, fmt.Sprintf("%d.%d", rootUID, rootGID))
+	return idtools.MkdirAllAs(config.Root, 0700, rootUID, rootGID)
+}
+
 // tempDir returns the default directory to use for temporary files.
 func tempDir(rootDir string) (string, error) {
 	var tmpDir string
@@ -1238,6 +1633,138 @@ func (daemon *Daemon) verifyHostConfig(hostConfig *runconfig.HostConfig) ([]stri
 	if hostConfig.Memory == 0 && hostConfig.MemorySwap > 0 {
 		return warnings, fmt.Errorf("You should always set the Memory limit when using Memoryswap limit, see usage.")
 	}
+	if hostConfig.MemoryReservation < 0 || hostConfig.KernelMemory < 0 {
+		return warnings, fmt.Errorf("Memory limits can't be negative")
+	}
+	if hostConfig.MemoryReservation > 0 && !daemon.SystemConfig().MemoryLimit {
+		return warnings, fmt.Errorf("Your kernel does not support memory soft limit capabilities")
+	}
+	if hostConfig.Memory > 0 && hostConfig.MemoryReservation > hostConfig.Memory {
+		return warnings, fmt.Errorf("Minimum memory limit should be larger than memory reservation limit, see usage.")
+	}
+	if hostConfig.KernelMemory > 0 && !daemon.SystemConfig().KernelMemory {
+		return warnings, fmt.Errorf("Your kernel does not support kernel memory limit capabilities")
+	}
+	if hostConfig.KernelMemory != 0 && hostConfig.KernelMemory < 4194304 {
+		return warnings, fmt.Errorf("Minimum kernel memory limit allowed is 4MB")
+	}
+	// kernel memory accounting must be enabled before the container joins its
+	// cgroup, which systemd creates
+	if hostConfig.KernelMemory != 0 && strings.Contains(daemon.ExecutionDriver().Name(), "native") && systemd.UseSystemd() {
+		return warnings, fmt.Errorf("Cannot set a kernel memory limit with the systemd cgroup manager")
+	}
+	if swappiness := hostConfig.MemorySwappiness; swappiness != nil {
+		if !daemon.SystemConfig().MemorySwappiness {
+			return warnings, fmt.Errorf("Your kernel does not support memory swappiness capabilities")
+		}
+		if *swappiness < 0 || *swappiness > 100 {
+			return warnings, fmt.Errorf("Invalid memory swappiness %d, the valid range is 0-100", *swappiness)
+		}
+	}
+	if hostConfig.OomKillDisable && !daemon.SystemConfig().OomKillDisable {
+		return warnings, fmt.Errorf("Your kernel does not support disabling the OOM killer")
+	}
+	if hostConfig.OomKillDisable && hostConfig.Memory == 0 {
+		warnings = append(warnings, "Disabling the OOM killer on containers without setting a '-m/--memory' limit may be dangerous.")
+	}
+	if hostConfig.OomScoreAdj < -1000 || hostConfig.OomScoreAdj > 1000 {
+		return warnings, fmt.Errorf("Invalid OOM score adjustment %d, the valid range is -1000-1000", hostConfig.OomScoreAdj)
+	}
+	if hostConfig.OomScoreAdj != 0 && !strings.Contains(daemon.ExecutionDriver().Name(), "native") {
+		return warnings, fmt.Errorf("Cannot adjust the OOM score with execdriver: %s", daemon.ExecutionDriver().Name())
+	}
+	if hostConfig.Init != nil && *hostConfig.Init && !strings.Contains(daemon.ExecutionDriver().Name(), "native") {
+		return warnings, fmt.Errorf("Cannot run an init in the container with execdriver: %s", daemon.ExecutionDriver().Name())
+	}
+	if hostConfig.ShmSize < 0 {
+		return warnings, fmt.Errorf("The size of /dev/shm can't be negative")
+	}
+	if hostConfig.ShmSize != 0 && !strings.Contains(daemon.ExecutionDriver().Name(), "native") {
+		return warnings, fmt.Errorf("Cannot set the size of /dev/shm with execdriver: %s", daemon.ExecutionDriver().Name())
+	}
+	if len(hostConfig.GroupAdd) > 0 && !strings.Contains(daemon.ExecutionDriver().Name(), "native") {
+		return warnings, fmt.Errorf("Cannot add groups with execdriver: %s", daemon.ExecutionDriver().Name())
+	}
+	if len(hostConfig.Sysctls) > 0 && !strings.Contains(daemon.ExecutionDriver().Name(), "native") {
+		return warnings, fmt.Errorf("Cannot set sysctls with execdriver: %s", daemon.ExecutionDriver().Name())
+	}
+	for key, value := range hostConfig.Sysctls {
+		if _, err := opts.ValidateSysctl(key + "=" + value); err != nil {
+			return warnings, err
+		}
+		if opts.IsNetSysctl(key) && (hostConfig.NetworkMode.IsHost() || hostConfig.NetworkMode.IsContainer()) {
+			return warnings, fmt.Errorf("Sysctl %s can't be set with --net=%s, the network namespace isn't the container's own", key, hostConfig.NetworkMode)
+		}
+		if opts.IsIpcSysctl(key) && (hostConfig.IpcMode.IsHost() || hostConfig.IpcMode.IsContainer()) {
+			return warnings, fmt.Errorf("Sysctl %s can't be set with --ipc=%s, the IPC namespace isn't the container's own", key, hostConfig.IpcMode)
+		}
+	}
+	if p := hostConfig.RestartPolicy; p.Delay < 0 || p.MaxDelay < 0 || p.ResetWindow < 0 {
+		return warnings, fmt.Errorf("Restart delays can't be negative")
+	}
+	if hostConfig.AutoRemove && hostConfig.RestartPolicy.Name != "" && hostConfig.RestartPolicy.Name != "no" {
+		return warnings, fmt.Errorf("Can't create an auto-removed container with the %q restart policy", hostConfig.RestartPolicy.Name)
+	}
+	if hostConfig.PidsLimit != 0 && !daemon.SystemConfig().PidsLimit {
+		return warnings, fmt.Errorf("Your kernel does not support pids limit capabilities or the cgroup is not mounted")
+	}
+	if hostConfig.BlkioWeight > 0 {
+		if !daemon.SystemConfig().BlkioWeight {
+			return warnings, fmt.Errorf("Your kernel does not support block IO weight")
+		}
+		if hostConfig.BlkioWeight < 10 || hostConfig.BlkioWeight > 1000 {
+			return warnings, fmt.Errorf("Invalid block IO weight %d, the valid range is 10-1000", hostConfig.BlkioWeight)
+		}
+	}
+	if len(hostConfig.BlkioWeightDevice) > 0 && !daemon.SystemConfig().BlkioWeightDevice {
+		return warnings, fmt.Errorf("Your kernel does not support block IO weight_device")
+	}
+	if len(hostConfig.BlkioDeviceReadBps) > 0 && !daemon.SystemConfig().BlkioReadBpsDevice {
+		return warnings, fmt.Errorf("Your kernel does not support block IO read limit in bytes per second")
+	}
+	if len(hostConfig.BlkioDeviceWriteBps) > 0 && !daemon.SystemConfig().BlkioWriteBpsDevice {
+		return warnings, fmt.Errorf("Your kernel does not support block IO write limit in bytes per second")
+	}
+	if len(hostConfig.BlkioDeviceReadIOps) > 0 && !daemon.SystemConfig().BlkioReadIOpsDevice {
+		return warnings, fmt.Errorf("Your kernel does not support block IO read limit in IO per second")
+	}
+	if len(hostConfig.BlkioDeviceWriteIOps) > 0 && !daemon.SystemConfig().BlkioWriteIOpsDevice {
+		return warnings, fmt.Errorf("Your kernel does not support block IO write limit in IO per second")
+	}
+	for _, wd := range hostConfig.BlkioWeightDevice {
+		if wd.Weight != 0 && (wd.Weight < 10 || wd.Weight > 1000) {
+			return warnings, fmt.Errorf("Invalid block IO weight %d for device %s, the valid range is 10-1000", wd.Weight, wd.Path)
+		}
+		if _, err := getBlkioDevice(wd.Path); err != nil {
+			return warnings, err
+		}
+	}
+	for _, throttles := range [][]*runconfig.ThrottleDevice{hostConfig.BlkioDeviceReadBps, hostConfig.BlkioDeviceWriteBps, hostConfig.BlkioDeviceReadIOps, hostConfig.BlkioDeviceWriteIOps} {
+		for _, td := range throttles {
+			if _, err := getBlkioDevice(td.Path); err != nil {
+				return warnings, err
+			}
+		}
+	}
+	if hostConfig.NetworkRate.Ingress < 0 || hostConfig.NetworkRate.Egress < 0 {
+		return warnings, fmt.Errorf("Network rate limits can't be negative")
+	}
+	if hostConfig.NetworkRate.Ingress != 0 || hostConfig.NetworkRate.Egress != 0 {
+		if mode := hostConfig.NetworkMode; mode != "" && mode != "bridge" {
+			return warnings, fmt.Errorf("Network rate limits are only supported with the bridge network mode")
+		}
+		if !strings.Contains(daemon.ExecutionDriver().Name(), "native") {
+			return warnings, fmt.Errorf("Cannot limit the network rate with execdriver: %s", daemon.ExecutionDriver().Name())
+		}
+	}
+	if daemon.uidMaps != nil {
+		if hostConfig.Privileged {
+			return warnings, fmt.Errorf("Privileged containers are not supported with user namespaces enabled")
+		}
+		if hostConfig.NetworkMode.IsHost() || hostConfig.PidMode.IsHost() || hostConfig.IpcMode.IsHost() {
+			return warnings, fmt.Errorf("Sharing the host's namespaces is not supported with user namespaces enabled")
+		}
+	}
 	if hostConfig.CpuQuota > 0 && !daemon.SystemConfig().CpuCfsQuota {
 		warnings = append(warnings, "Your kernel does not support CPU cfs quota. Quota discarded.")
 		hostConfig.CpuQuota = 0
@@ -1252,6 +1779,9 @@ func (daemon *Daemon) setHostConfig(container *Container, hostConfig *runconfig.
 	if err := parseSecurityOpt(container, hostConfig); err != nil {
 		return err
 	}
+	if err := daemon.setAppArmorProfile(container, hostConfig); err != nil {
+		return err
+	}
 
 	// Register any links from the host config before starting the container
 	if err := daemon.RegisterLinks(container, hostConfig); err != nil {
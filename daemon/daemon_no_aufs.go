@@ -6,6 +6,6 @@ import (
 	"github.com/docker/docker/daemon/graphdriver"
 )
 
-func migrateIfAufs(driver graphdriver.Driver, root string) error {
+func migrateIfAufs(driver graphdriver.Driver, root string, rootUID, rootGID int) error {
 	return nil
 }
@@ -19,6 +19,14 @@ func TestParseSecurityOpt(t *testing.T) {
 		t.Fatalf("Unexpected AppArmorProfile, expected: \"test_profile\", got %q", container.AppArmorProfile)
 	}
 
+	config.SecurityOpt = []string{"apparmor=test_profile"}
+	if err := parseSecurityOpt(container, config); err != nil {
+		t.Fatalf("Unexpected parseSecurityOpt error: %v", err)
+	}
+	if container.AppArmorProfile != "test_profile" {
+		t.Fatalf("Unexpected AppArmorProfile, expected: \"test_profile\", got %q", container.AppArmorProfile)
+	}
+
 	// test valid label
 	config.SecurityOpt = []string{"label:user:USER"}
 	if err := parseSecurityOpt(container, config); err != nil {
@@ -31,9 +39,107 @@ func TestParseSecurityOpt(t *testing.T) {
 		t.Fatal("Expected parseSecurityOpt error, got nil")
 	}
 
+	// test seccomp
+	config.SecurityOpt = []string{"seccomp=unconfined"}
+	if err := parseSecurityOpt(container, config); err != nil {
+		t.Fatalf("Unexpected parseSecurityOpt error: %v", err)
+	}
+	if container.SeccompProfile != "unconfined" {
+		t.Fatalf("Unexpected SeccompProfile, expected: \"unconfined\", got %q", container.SeccompProfile)
+	}
+
+	profile := `{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"name": "keyctl", "action": "SCMP_ACT_ERRNO"}]}`
+	config.SecurityOpt = []string{"seccomp=" + profile}
+	if err := parseSecurityOpt(container, config); err != nil {
+		t.Fatalf("Unexpected parseSecurityOpt error: %v", err)
+	}
+	if container.SeccompProfile != profile {
+		t.Fatalf("Unexpected SeccompProfile, expected: %q, got %q", profile, container.SeccompProfile)
+	}
+
+	// test invalid seccomp profile
+	config.SecurityOpt = []string{`seccomp={"defaultAction": "SCMP_ACT_NONE"}`}
+	if err := parseSecurityOpt(container, config); err == nil {
+		t.Fatal("Expected parseSecurityOpt error, got nil")
+	}
+
 	// test invalid opt
 	config.SecurityOpt = []string{"test"}
 	if err := parseSecurityOpt(container, config); err == nil {
 		t.Fatal("Expected parseSecurityOpt error, got nil")
 	}
 }
+
+func TestCheckAppArmorProfile(t *testing.T) {
+	for _, name := range []string{"", "unconfined"} {
+		if err := checkAppArmorProfile(name); err != nil {
+			t.Fatalf("Unexpected checkAppArmorProfile error for %q: %v", name, err)
+		}
+	}
+	if err := checkAppArmorProfile("docker-test-not-loaded"); err == nil {
+		t.Fatal("Expected checkAppArmorProfile error for a profile that isn't loaded, got nil")
+	}
+}
+
+func TestSetupRemappedRoot(t *testing.T) {
+	config := &Config{ExecDriver: "native"}
+	uidMaps, gidMaps, err := setupRemappedRoot(config)
+	if err != nil {
+		t.Fatalf("Unexpected setupRemappedRoot error: %v", err)
+	}
+	if uidMaps != nil || gidMaps != nil {
+		t.Fatalf("Expected no mappings without --userns-remap, got %v %v", uidMaps, gidMaps)
+	}
+
+	// test unsupported exec driver
+	config = &Config{ExecDriver: "lxc", RemappedRoot: "dockremap"}
+	if _, _, err := setupRemappedRoot(config); err == nil {
+		t.Fatal("Expected setupRemappedRoot error with the lxc exec driver, got nil")
+	}
+
+	// test invalid values
+	for _, value := range []string{":dockremap", "dockremap:"} {
+		config = &Config{ExecDriver: "native", RemappedRoot: value}
+		if _, _, err := setupRemappedRoot(config); err == nil {
+			t.Fatalf("Expected setupRemappedRoot error for %q, got nil", value)
+		}
+	}
+}
+
+func TestBreakLinkCycles(t *testing.T) {
+	// a <- b <- c <- a, and d <- c
+	parents := map[string][]string{
+		"a": {"c"},
+		"b": {"a"},
+		"c": {"b", "d"},
+	}
+	breakLinkCycles(parents)
+
+	links := 0
+	for _, p := range parents {
+		links += len(p)
+	}
+	if links != 3 {
+		t.Fatalf("Expected one link of the cycle to be removed, got %v", parents)
+	}
+	if len(parents["c"]) == 0 || parents["c"][len(parents["c"])-1] != "d" {
+		t.Fatalf("Expected the link outside the cycle to be kept, got %v", parents)
+	}
+
+	// every container can be stopped once its parents are
+	stopped := map[string]bool{"d": true}
+	for i := 0; i < len(parents); i++ {
+		for id, p := range parents {
+			ready := true
+			for _, parent := range p {
+				ready = ready && stopped[parent]
+			}
+			if ready {
+				stopped[id] = true
+			}
+		}
+	}
+	if len(stopped) != 4 {
+		t.Fatalf("Expected all containers to be stopped in link order, got %v with links %v", stopped, parents)
+	}
+}
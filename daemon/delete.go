@@ -76,6 +76,18 @@ func (daemon *Daemon) ContainerRm(name string, config *ContainerRmConfig) error
 	return nil
 }
 
+// autoRemove removes a container created with AutoRemove once it exited,
+// along with its volumes.
+func (daemon *Daemon) autoRemove(container *Container) {
+	// a checkpointed container is kept to be restored later
+	if container.IsCheckpointed() {
+		return
+	}
+	if err := daemon.ContainerRm(container.ID, &ContainerRmConfig{RemoveVolume: true}); err != nil {
+		logrus.Errorf("Error removing container %s: %v", container.ID, err)
+	}
+}
+
 func (daemon *Daemon) DeleteVolumes(volumeIDs map[string]struct{}) {
 	for id := range volumeIDs {
 		if err := daemon.volumes.Delete(id); err != nil {
@@ -129,6 +141,7 @@ func (daemon *Daemon) commonRm(container *Container, forceRemove bool) (err erro
 		if err != nil && forceRemove {
 			daemon.idIndex.Delete(container.ID)
 			daemon.containers.Delete(container.ID)
+			container.SetRemoved()
 		}
 	}()
 
@@ -157,6 +170,7 @@ func (daemon *Daemon) commonRm(container *Container, forceRemove bool) (err erro
 	selinuxFreeLxcContexts(container.ProcessLabel)
 	daemon.idIndex.Delete(container.ID)
 	daemon.containers.Delete(container.ID)
+	container.SetRemoved()
 
 	return nil
 }
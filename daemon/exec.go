@@ -4,10 +4,14 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/daemon/execdriver"
 	"github.com/docker/docker/daemon/execdriver/lxc"
 	"github.com/docker/docker/pkg/broadcastwriter"
@@ -15,19 +19,29 @@ import (
 	"github.com/docker/docker/pkg/promise"
 	"github.com/docker/docker/pkg/stringid"
 	"github.com/docker/docker/runconfig"
+	"github.com/docker/docker/utils"
 )
 
+// execCommandGCInterval is how often exec commands that exited are removed
+// from the daemon.
+const execCommandGCInterval = 5 * time.Minute
+
 type execConfig struct {
 	sync.Mutex
 	ID            string
 	Running       bool
 	ExitCode      int
+	Pid           int
 	ProcessConfig execdriver.ProcessConfig
 	StreamConfig
 	OpenStdin  bool
 	OpenStderr bool
 	OpenStdout bool
 	Container  *Container
+
+	// canRemove is set by the garbage collection of exec commands once the
+	// command exited, and the command is removed by the next collection
+	canRemove bool
 }
 
 type execStore struct {
@@ -58,6 +72,17 @@ func (e *execStore) Delete(id string) {
 	e.Unlock()
 }
 
+// configs returns the exec commands in the store.
+func (e *execStore) configs() []*execConfig {
+	var configs []*execConfig
+	e.RLock()
+	for _, config := range e.s {
+		configs = append(configs, config)
+	}
+	e.RUnlock()
+	return configs
+}
+
 func (e *execStore) List() []string {
 	var IDs []string
 	e.RLock()
@@ -72,6 +97,11 @@ func (execConfig *execConfig) Resize(h, w int) error {
 	return execConfig.ProcessConfig.Terminal.Resize(h, w)
 }
 
+// commandLine returns the command run by the exec command, for events.
+func (execConfig *execConfig) commandLine() string {
+	return execConfig.ProcessConfig.Entrypoint + " " + strings.Join(execConfig.ProcessConfig.Arguments, " ")
+}
+
 func (d *Daemon) registerExecCommand(execConfig *execConfig) {
 	// Storing execs in container in order to kill them gracefully whenever the container is stopped or removed.
 	execConfig.Container.execCommands.Add(execConfig.ID, execConfig)
@@ -131,6 +161,12 @@ func (d *Daemon) ContainerExecCreate(config *runconfig.ExecConfig) (string, erro
 		User:       config.User,
 		Privileged: config.Privileged,
 	}
+	// ReplaceOrAppendEnvValues modifies the defaults it is given
+	processConfig.Env = utils.ReplaceOrAppendEnvValues(append([]string(nil), container.command.ProcessConfig.Env...), config.Env)
+	processConfig.Dir = config.WorkingDir
+	if processConfig.Dir == "" {
+		processConfig.Dir = container.command.WorkingDir
+	}
 
 	execConfig := &execConfig{
 		ID:            stringid.GenerateRandomID(),
@@ -143,7 +179,7 @@ func (d *Daemon) ContainerExecCreate(config *runconfig.ExecConfig) (string, erro
 		Running:       false,
 	}
 
-	container.LogEvent("exec_create: " + execConfig.ProcessConfig.Entrypoint + " " + strings.Join(execConfig.ProcessConfig.Arguments, " "))
+	container.LogEvent("exec_create: " + execConfig.commandLine())
 
 	d.registerExecCommand(execConfig)
 
@@ -178,7 +214,7 @@ func (d *Daemon) ContainerExecStart(execName string, stdin io.ReadCloser, stdout
 	logrus.Debugf("starting exec command %s in container %s", execConfig.ID, execConfig.Container.ID)
 	container := execConfig.Container
 
-	container.LogEvent("exec_start: " + execConfig.ProcessConfig.Entrypoint + " " + strings.Join(execConfig.ProcessConfig.Arguments, " "))
+	container.LogEvent("exec_start: " + execConfig.commandLine())
 
 	if execConfig.OpenStdin {
 		r, w := io.Pipe()
@@ -209,9 +245,9 @@ func (d *Daemon) ContainerExecStart(execName string, stdin io.ReadCloser, stdout
 
 	execErr := make(chan error)
 
-	// Note, the execConfig data will be removed when the container
-	// itself is deleted.  This allows us to query it (for things like
-	// the exitStatus) even after the cmd is done running.
+	// Note, the execConfig data is kept in the daemon until the garbage
+	// collection of exec commands removes it.  This allows us to query it
+	// (for things like the exitStatus) even after the cmd is done running.
 
 	go func() {
 		err := container.Exec(execConfig)
@@ -241,16 +277,84 @@ func (d *Daemon) Exec(c *Container, execConfig *execConfig, pipes *execdriver.Pi
 		exitStatus = 128
 	}
 
+	execConfig.Lock()
 	execConfig.ExitCode = exitStatus
 	execConfig.Running = false
+	execConfig.Unlock()
 
 	return exitStatus, err
 }
 
+// ContainerExecKill sends the signal sig to the process of a running exec
+// command, or SIGKILL if sig is 0.
+func (d *Daemon) ContainerExecKill(name string, sig uint64) error {
+	execConfig, err := d.getExecConfig(name)
+	if err != nil {
+		return err
+	}
+
+	// the pid is set once the driver started the process
+	execConfig.Lock()
+	running, pid, signal := execConfig.Running, execConfig.Pid, execConfig.ProcessConfig.Signal
+	execConfig.Unlock()
+	if !running || pid == 0 || signal == nil {
+		return fmt.Errorf("Exec command %s is not running", name)
+	}
+
+	if sig == 0 {
+		sig = uint64(syscall.SIGKILL)
+	}
+	// signal through the driver rather than the pid, which can be reused by
+	// another process once the command exited
+	if err := signal(syscall.Signal(sig)); err != nil {
+		return fmt.Errorf("Cannot kill exec command %s: %s", name, err)
+	}
+	return nil
+}
+
+// execCommandGC removes the exec commands that exited from the daemon. An
+// exec command is kept for one interval after it exited, so that its exit
+// code can still be inspected.
+func (d *Daemon) execCommandGC() {
+	for range time.Tick(execCommandGCInterval) {
+		var cleaned int
+		for _, execConfig := range d.execCommands.configs() {
+			execConfig.Lock()
+			if execConfig.canRemove {
+				cleaned++
+				d.execCommands.Delete(execConfig.ID)
+			} else if execConfig.Container.execCommands.Get(execConfig.ID) == nil {
+				execConfig.canRemove = true
+			}
+			execConfig.Unlock()
+		}
+		if cleaned > 0 {
+			logrus.Debugf("clean %d unused exec commands", cleaned)
+		}
+	}
+}
+
 func (container *Container) GetExecIDs() []string {
 	return container.execCommands.List()
 }
 
+// getExecSessions returns the exec commands of the container that didn't
+// exit yet.
+func (container *Container) getExecSessions() []types.ExecSession {
+	var sessions []types.ExecSession
+	for _, execConfig := range container.execCommands.configs() {
+		execConfig.Lock()
+		sessions = append(sessions, types.ExecSession{
+			ID:      execConfig.ID,
+			Cmd:     append([]string{execConfig.ProcessConfig.Entrypoint}, execConfig.ProcessConfig.Arguments...),
+			Running: execConfig.Running,
+			Pid:     execConfig.Pid,
+		})
+		execConfig.Unlock()
+	}
+	return sessions
+}
+
 func (container *Container) Exec(execConfig *execConfig) error {
 	container.Lock()
 	defer container.Unlock()
@@ -258,6 +362,9 @@ func (container *Container) Exec(execConfig *execConfig) error {
 	waitStart := make(chan struct{})
 
 	callback := func(processConfig *execdriver.ProcessConfig, pid int) {
+		execConfig.Lock()
+		execConfig.Pid = pid
+		execConfig.Unlock()
 		if processConfig.Tty {
 			// The callback is called after the process Start()
 			// so we are in the parent process. In TTY mode, stdin/out/err is the PtySlave
@@ -296,6 +403,10 @@ func (container *Container) monitorExec(execConfig *execConfig, callback execdri
 	}
 
 	logrus.Debugf("Exec task in container %s exited with code %d", container.ID, exitCode)
+	// the exec command is no longer part of the container, the daemon keeps
+	// it until the next garbage collection
+	container.execCommands.Delete(execConfig.ID)
+	container.LogEvent("exec_die: " + execConfig.commandLine() + " (exit code " + strconv.Itoa(exitCode) + ")")
 	if execConfig.OpenStdin {
 		if err := execConfig.StreamConfig.stdin.Close(); err != nil {
 			logrus.Errorf("Error closing stdin while running in %s: %s", container.ID, err)
@@ -3,6 +3,7 @@ package execdriver
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
@@ -12,7 +13,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/daemon/execdriver/native/template"
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/ulimit"
 	"github.com/docker/libcontainer"
 	"github.com/docker/libcontainer/cgroups/fs"
@@ -59,9 +62,20 @@ type ExitStatus struct {
 	OOMKilled bool
 }
 
+// CheckpointOpts holds the options to checkpoint and restore a container.
+type CheckpointOpts struct {
+	ImagesDirectory         string // Directory of the images of the checkpoint
+	WorkDirectory           string // Directory for the logs of the checkpoint and restore
+	LeaveRunning            bool   // Keep the container running after the checkpoint
+	TcpEstablished          bool   // Checkpoint and restore established TCP connections
+	ExternalUnixConnections bool   // Allow unix sockets connected outside of the container
+	ShellJob                bool   // Allow processes that are part of a shell job
+}
+
 type Driver interface {
 	Run(c *Command, pipes *Pipes, startCallback StartCallback) (ExitStatus, error) // Run executes the process and blocks until the process exits and returns the exit code
-	// Exec executes the process in an existing container, blocks until the process exits and returns the exit code
+	// Exec executes the process in an existing container, blocks until the process exits and returns the exit code.
+	// The process runs with the Env and in the Dir of processConfig.
 	Exec(c *Command, processConfig *ProcessConfig, pipes *Pipes, startCallback StartCallback) (int, error)
 	Kill(c *Command, sig int) error
 	Pause(c *Command) error
@@ -72,6 +86,14 @@ type Driver interface {
 	Terminate(c *Command) error                   // kill it with fire
 	Clean(id string) error                        // clean all traces of container exec
 	Stats(id string) (*ResourceStats, error)      // Get resource stats for a running container
+	Update(c *Command) error                      // Apply the resources of c to the running container
+	// Checkpoint dumps the processes of the running container to disk
+	Checkpoint(c *Command, opts *CheckpointOpts) error
+	// Restore restores the processes of a checkpointed container, blocks until the process exits and returns the exit code
+	Restore(c *Command, pipes *Pipes, restoreCallback StartCallback, opts *CheckpointOpts) (ExitStatus, error)
+	// Reattach attaches to the processes of a container that kept running while the daemon was down,
+	// blocks until the process exits and returns the exit code
+	Reattach(c *Command, pipes *Pipes, startCallback StartCallback) (ExitStatus, error)
 }
 
 // Network settings of the container
@@ -80,6 +102,7 @@ type Network struct {
 	Mtu            int               `json:"mtu"`
 	ContainerID    string            `json:"container_id"` // id of the container to join network.
 	HostNetworking bool              `json:"host_networking"`
+	NamespacePath  string            `json:"namespace_path"` // path of an already configured network namespace to join.
 }
 
 // IPC settings of the container
@@ -103,25 +126,71 @@ type NetworkInterface struct {
 	LinkLocalIPv6Address string `json:"link_local_ipv6"`
 	GlobalIPv6PrefixLen  int    `json:"global_ipv6_prefix_len"`
 	IPv6Gateway          string `json:"ipv6_gateway"`
+	HairpinMode          bool   `json:"hairpin_mode"`
+	RateIngress          int64  `json:"rate_ingress"` // bytes per second into the container
+	RateEgress           int64  `json:"rate_egress"`  // bytes per second out of the container
 }
 
 type Resources struct {
-	Memory     int64            `json:"memory"`
-	MemorySwap int64            `json:"memory_swap"`
-	CpuShares  int64            `json:"cpu_shares"`
-	CpusetCpus string           `json:"cpuset_cpus"`
-	CpusetMems string           `json:"cpuset_mems"`
-	CpuQuota   int64            `json:"cpu_quota"`
-	Rlimits    []*ulimit.Rlimit `json:"rlimits"`
+	Memory                       int64             `json:"memory"`
+	MemorySwap                   int64             `json:"memory_swap"`
+	MemoryReservation            int64             `json:"memory_reservation"`
+	KernelMemory                 int64             `json:"kernel_memory"`
+	MemorySwappiness             *int64            `json:"memory_swappiness"`
+	OomKillDisable               bool              `json:"oom_kill_disable"`
+	CpuShares                    int64             `json:"cpu_shares"`
+	CpusetCpus                   string            `json:"cpuset_cpus"`
+	CpusetMems                   string            `json:"cpuset_mems"`
+	CpuQuota                     int64             `json:"cpu_quota"`
+	PidsLimit                    int64             `json:"pids_limit"`
+	BlkioWeight                  int64             `json:"blkio_weight"`
+	BlkioWeightDevice            []*WeightDevice   `json:"blkio_weight_device"`
+	BlkioThrottleReadBpsDevice   []*ThrottleDevice `json:"blkio_throttle_read_bps_device"`
+	BlkioThrottleWriteBpsDevice  []*ThrottleDevice `json:"blkio_throttle_write_bps_device"`
+	BlkioThrottleReadIOPSDevice  []*ThrottleDevice `json:"blkio_throttle_read_iops_device"`
+	BlkioThrottleWriteIOPSDevice []*ThrottleDevice `json:"blkio_throttle_write_iops_device"`
+	Rlimits                      []*ulimit.Rlimit  `json:"rlimits"`
+}
+
+// WeightDevice is the block IO weight of a block device.
+type WeightDevice struct {
+	Major  int64  `json:"major"`
+	Minor  int64  `json:"minor"`
+	Weight uint16 `json:"weight"` // from 10 to 1000
+}
+
+// String formats the device for the blkio.weight_device file.
+func (wd *WeightDevice) String() string {
+	return fmt.Sprintf("%d:%d %d", wd.Major, wd.Minor, wd.Weight)
+}
+
+// ThrottleDevice is a block IO rate limit of a block device.
+type ThrottleDevice struct {
+	Major int64  `json:"major"`
+	Minor int64  `json:"minor"`
+	Rate  uint64 `json:"rate"` // bytes or operations per second
+}
+
+// String formats the device for the blkio.throttle.* files.
+func (td *ThrottleDevice) String() string {
+	return fmt.Sprintf("%d:%d %d", td.Major, td.Minor, td.Rate)
 }
 
 type ResourceStats struct {
 	*libcontainer.Stats
+	PidsStats   PidsStats `json:"pids_stats"`
 	Read        time.Time `json:"read"`
 	MemoryLimit int64     `json:"memory_limit"`
 	SystemUsage uint64    `json:"system_usage"`
 }
 
+// PidsStats are the statistics of the pids cgroup of a container, which
+// libcontainer doesn't report.
+type PidsStats struct {
+	Current uint64 `json:"current,omitempty"` // number of processes
+	Limit   uint64 `json:"limit,omitempty"`   // 0 without limit
+}
+
 type Mount struct {
 	Source      string `json:"source"`
 	Destination string `json:"destination"`
@@ -141,6 +210,11 @@ type ProcessConfig struct {
 	Arguments  []string `json:"arguments"`
 	Terminal   Terminal `json:"-"` // standard or tty terminal
 	Console    string   `json:"-"` // dev/console path
+
+	// Signal sends a signal to the process. It is set by the driver once the
+	// process started, and fails once the process was waited for, rather than
+	// reaching another process that reused its pid.
+	Signal func(os.Signal) error `json:"-"`
 }
 
 // Process wrapps an os/exec.Cmd to add more metadata
@@ -166,7 +240,15 @@ type Command struct {
 	MountLabel         string            `json:"mount_label"`
 	LxcConfig          []string          `json:"lxc_config"`
 	AppArmorProfile    string            `json:"apparmor_profile"`
+	SeccompProfile     string            `json:"seccomp_profile"`
 	CgroupParent       string            `json:"cgroup_parent"` // The parent cgroup for this command.
+	UIDMapping         []idtools.IDMap   `json:"uidmapping"`    // Creates a user namespace with these mappings if set
+	GIDMapping         []idtools.IDMap   `json:"gidmapping"`
+	OomScoreAdj        int               `json:"oom_score_adj"`
+	Init               bool              `json:"init"` // run dockerinit as pid 1 to reap processes and forward signals
+	Sysctls            map[string]string `json:"sysctls"`
+	ShmSize            int64             `json:"shm_size"`  // size of /dev/shm in bytes, the default if 0
+	GroupAdd           []string          `json:"group_add"` // additional groups of the user, by name or gid
 }
 
 func InitContainer(c *Command) *configs.Config {
@@ -204,17 +286,113 @@ func SetupCgroups(container *configs.Config, c *Command) error {
 		container.Cgroups.CpuShares = c.Resources.CpuShares
 		container.Cgroups.Memory = c.Resources.Memory
 		container.Cgroups.MemoryReservation = c.Resources.Memory
+		if c.Resources.MemoryReservation != 0 {
+			container.Cgroups.MemoryReservation = c.Resources.MemoryReservation
+		}
 		container.Cgroups.MemorySwap = c.Resources.MemorySwap
+		container.Cgroups.OomKillDisable = c.Resources.OomKillDisable
 		container.Cgroups.CpusetCpus = c.Resources.CpusetCpus
 		container.Cgroups.CpusetMems = c.Resources.CpusetMems
 		container.Cgroups.CpuQuota = c.Resources.CpuQuota
+		container.Cgroups.BlkioWeight = c.Resources.BlkioWeight
 	}
 
 	return nil
 }
 
-// Returns the network statistics for the network interfaces represented by the NetworkRuntimeInfo.
-func getNetworkInterfaceStats(interfaceName string) (*libcontainer.NetworkInterface, error) {
+// UpdateCgroups applies the resources of c to a running container whose
+// current configuration is config, by passing the new configuration to set.
+// The kernel refuses a memory limit above the memory+swap limit, so when the
+// memory limit is raised past it the memory+swap limit is raised first.
+// Limits that are removed are lifted in the cgroups found in paths, as set
+// leaves the cgroup untouched for unset limits.
+func UpdateCgroups(config *configs.Config, c *Command, paths map[string]string, set func(*configs.Config) error) error {
+	oldCgroups := *config.Cgroups
+	newCgroups := oldCgroups
+	updated := *config
+	updated.Cgroups = &newCgroups
+	if err := SetupCgroups(&updated, c); err != nil {
+		return err
+	}
+	if err := removeCgroupLimits(&oldCgroups, &newCgroups, paths); err != nil {
+		return err
+	}
+
+	if limit := memswLimit(&oldCgroups); limit > 0 && newCgroups.Memory > limit {
+		swapFirst := newCgroups
+		swapFirst.Memory = oldCgroups.Memory
+		swapFirst.MemoryReservation = oldCgroups.MemoryReservation
+		swapFirst.MemorySwap = memswLimit(&newCgroups)
+		intermediate := updated
+		intermediate.Cgroups = &swapFirst
+		if err := set(&intermediate); err != nil {
+			return err
+		}
+	}
+	return set(&updated)
+}
+
+// memswLimit returns the memory+swap limit the cgroup ends up with, -1 when
+// it is unlimited. Without an explicit value it is twice the memory limit.
+func memswLimit(c *configs.Cgroup) int64 {
+	switch {
+	case c.MemorySwap > 0:
+		return c.MemorySwap
+	case c.MemorySwap == 0 && c.Memory > 0:
+		return c.Memory * 2
+	}
+	return -1
+}
+
+// removeCgroupLimits writes the kernel defaults for the limits set in
+// oldCgroups that newCgroups no longer sets. The memory+swap limit is lifted
+// before the memory limit, which can't exceed it.
+func removeCgroupLimits(oldCgroups, newCgroups *configs.Cgroup, paths map[string]string) error {
+	type cgroupDefault struct {
+		subsystem, file, value string
+	}
+	var defaults []cgroupDefault
+	if memswLimit(oldCgroups) > 0 && memswLimit(newCgroups) == -1 {
+		defaults = append(defaults, cgroupDefault{"memory", "memory.memsw.limit_in_bytes", "-1"})
+	}
+	if oldCgroups.Memory > 0 && newCgroups.Memory == 0 {
+		defaults = append(defaults, cgroupDefault{"memory", "memory.limit_in_bytes", "-1"})
+	}
+	if oldCgroups.MemoryReservation > 0 && newCgroups.MemoryReservation == 0 {
+		defaults = append(defaults, cgroupDefault{"memory", "memory.soft_limit_in_bytes", "-1"})
+	}
+	if oldCgroups.CpuShares > 0 && newCgroups.CpuShares == 0 {
+		defaults = append(defaults, cgroupDefault{"cpu", "cpu.shares", "1024"})
+	}
+	if oldCgroups.CpuQuota > 0 && newCgroups.CpuQuota == 0 {
+		defaults = append(defaults, cgroupDefault{"cpu", "cpu.cfs_quota_us", "-1"})
+	}
+
+	for _, d := range defaults {
+		dir := paths[d.subsystem]
+		if dir == "" {
+			continue
+		}
+		f, err := os.OpenFile(filepath.Join(dir, d.file), os.O_WRONLY|os.O_TRUNC, 0)
+		if err != nil {
+			if os.IsNotExist(err) {
+				// e.g. swap accounting is disabled
+				continue
+			}
+			return err
+		}
+		_, err = f.WriteString(d.value)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("Failed to write %s to %s: %v", d.value, d.file, err)
+		}
+	}
+	return nil
+}
+
+// GetNetworkInterfaceStats returns the network statistics of the host side
+// interfaceName of a veth pair.
+func GetNetworkInterfaceStats(interfaceName string) (*libcontainer.NetworkInterface, error) {
 	out := &libcontainer.NetworkInterface{Name: interfaceName}
 	// This can happen if the network runtime information is missing - possible if the
 	// container was created by an old version of libcontainer.
@@ -295,16 +473,51 @@ func Stats(containerDir string, containerMemoryLimit int64, machineMemory int64)
 	for _, iface := range state.Networks {
 		switch iface.Type {
 		case "veth":
-			istats, err := getNetworkInterfaceStats(iface.HostInterfaceName)
+			istats, err := GetNetworkInterfaceStats(iface.HostInterfaceName)
 			if err != nil {
 				return nil, err
 			}
 			stats.Interfaces = append(stats.Interfaces, istats)
 		}
 	}
+	// the pids cgroup may be gone already, the other stats are still worth
+	// reporting
+	pidsStats, err := GetPidsStats(state.CgroupPaths["pids"])
+	if err != nil {
+		logrus.Warnf("Error reading the pids stats of container %s: %s", filepath.Base(containerDir), err)
+		pidsStats = PidsStats{}
+	}
 	return &ResourceStats{
 		Stats:       stats,
+		PidsStats:   pidsStats,
 		Read:        now,
 		MemoryLimit: memoryLimit,
 	}, nil
 }
+
+// GetPidsStats reads the statistics of the pids cgroup at path. They are
+// empty if the container has no pids cgroup.
+func GetPidsStats(path string) (PidsStats, error) {
+	var stats PidsStats
+	if path == "" {
+		return stats, nil
+	}
+	current, err := ioutil.ReadFile(filepath.Join(path, "pids.current"))
+	if err != nil {
+		return stats, err
+	}
+	if stats.Current, err = strconv.ParseUint(strings.TrimSpace(string(current)), 10, 64); err != nil {
+		return stats, fmt.Errorf("failed to parse pids.current - %s", err)
+	}
+	max, err := ioutil.ReadFile(filepath.Join(path, "pids.max"))
+	if err != nil {
+		return stats, err
+	}
+	// the limit stays 0 if pids.max is "max"
+	if max := strings.TrimSpace(string(max)); max != "max" {
+		if stats.Limit, err = strconv.ParseUint(max, 10, 64); err != nil {
+			return stats, fmt.Errorf("failed to parse pids.max - %s", err)
+		}
+	}
+	return stats, nil
+}
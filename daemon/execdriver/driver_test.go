@@ -0,0 +1,125 @@
+package execdriver
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/libcontainer/configs"
+)
+
+func TestUpdateCgroups(t *testing.T) {
+	config := &configs.Config{Cgroups: &configs.Cgroup{Memory: 64, MemoryReservation: 64, MemorySwap: 128, CpuShares: 512}}
+
+	var applied []configs.Cgroup
+	set := func(c *configs.Config) error {
+		applied = append(applied, *c.Cgroups)
+		return nil
+	}
+
+	// lowering the memory limit is applied in one go
+	c := &Command{Resources: &Resources{Memory: 32, MemorySwap: 128, CpuShares: 1024}}
+	if err := UpdateCgroups(config, c, nil, set); err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 1 || applied[0].Memory != 32 || applied[0].CpuShares != 1024 {
+		t.Fatalf("Unexpected cgroup settings %+v", applied)
+	}
+	if config.Cgroups.Memory != 64 || config.Cgroups.CpuShares != 512 {
+		t.Fatalf("The current configuration was modified: %+v", config.Cgroups)
+	}
+
+	// raising it past the memory+swap limit raises the latter first
+	applied = nil
+	c = &Command{Resources: &Resources{Memory: 256, MemorySwap: 0}}
+	if err := UpdateCgroups(config, c, nil, set); err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 2 {
+		t.Fatalf("Expected the limits to be set in two steps, got %+v", applied)
+	}
+	if applied[0].Memory != 64 || applied[0].MemorySwap != 512 {
+		t.Fatalf("Expected the memory+swap limit to be raised first, got %+v", applied[0])
+	}
+	if applied[1].Memory != 256 || applied[1].MemorySwap != 0 {
+		t.Fatalf("Unexpected final cgroup settings %+v", applied[1])
+	}
+}
+
+func TestUpdateCgroupsRemoveLimits(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-cgroups")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// no memory.memsw.limit_in_bytes, as without swap accounting
+	files := map[string]string{
+		"memory.limit_in_bytes":      "67108864",
+		"memory.soft_limit_in_bytes": "67108864",
+		"cpu.cfs_quota_us":           "50000",
+		"cpu.shares":                 "512",
+	}
+	for name, value := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(value), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := &configs.Config{Cgroups: &configs.Cgroup{Memory: 67108864, MemoryReservation: 67108864, CpuShares: 512, CpuQuota: 50000}}
+	set := func(c *configs.Config) error { return nil }
+	paths := map[string]string{"memory": dir, "cpu": dir}
+	if err := UpdateCgroups(config, &Command{Resources: &Resources{}}, paths, set); err != nil {
+		t.Fatal(err)
+	}
+
+	files = map[string]string{
+		"memory.limit_in_bytes":      "-1",
+		"memory.soft_limit_in_bytes": "-1",
+		"cpu.cfs_quota_us":           "-1",
+		"cpu.shares":                 "1024",
+	}
+	for name, expected := range files {
+		value, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(value) != expected {
+			t.Fatalf("Expected %s to be %s, got %s", name, expected, value)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "memory.memsw.limit_in_bytes")); !os.IsNotExist(err) {
+		t.Fatalf("Expected memory.memsw.limit_in_bytes not to be created, got %v", err)
+	}
+}
+
+func TestGetPidsStats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-cgroups")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for max, limit := range map[string]uint64{"1024\n": 1024, "max\n": 0} {
+		files := map[string]string{
+			"pids.current": "12\n",
+			"pids.max":     max,
+		}
+		for name, value := range files {
+			if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(value), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		stats, err := GetPidsStats(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Current != 12 || stats.Limit != limit {
+			t.Fatalf("Expected 12 processes and a limit of %d, got %+v", limit, stats)
+		}
+	}
+
+	if stats, err := GetPidsStats(""); err != nil || stats.Current != 0 || stats.Limit != 0 {
+		t.Fatalf("Expected no statistics without pids cgroup, got %+v (%v)", stats, err)
+	}
+}
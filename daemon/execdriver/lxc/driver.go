@@ -24,6 +24,7 @@ import (
 	"github.com/docker/docker/pkg/version"
 	"github.com/docker/libcontainer"
 	"github.com/docker/libcontainer/cgroups"
+	"github.com/docker/libcontainer/cgroups/fs"
 	"github.com/docker/libcontainer/configs"
 	"github.com/docker/libcontainer/system"
 	"github.com/docker/libcontainer/user"
@@ -85,6 +86,10 @@ func (d *driver) Run(c *execdriver.Command, pipes *execdriver.Pipes, startCallba
 		dataPath = d.containerDir(c.ID)
 	)
 
+	if c.Network.NamespacePath != "" {
+		return execdriver.ExitStatus{ExitCode: -1}, fmt.Errorf("joining the network namespace %s is not supported by the lxc driver", c.Network.NamespacePath)
+	}
+
 	if c.ProcessConfig.Tty {
 		term, err = NewTtyConsole(&c.ProcessConfig, pipes)
 	} else {
@@ -488,6 +493,41 @@ func (d *driver) Unpause(c *execdriver.Command) error {
 	return err
 }
 
+func (d *driver) Update(c *execdriver.Command) error {
+	d.Lock()
+	active := d.activeContainers[c.ID]
+	d.Unlock()
+	if active == nil {
+		return execdriver.ErrNotRunning
+	}
+	paths, err := cgroupPaths(c.ID)
+	if err != nil {
+		return err
+	}
+	return execdriver.UpdateCgroups(active.container, c, paths, func(config *configs.Config) error {
+		m := &fs.Manager{Cgroups: config.Cgroups, Paths: paths}
+		if err := m.Set(config); err != nil {
+			return err
+		}
+		d.Lock()
+		active.container = config
+		d.Unlock()
+		return nil
+	})
+}
+
+func (d *driver) Checkpoint(c *execdriver.Command, opts *execdriver.CheckpointOpts) error {
+	return fmt.Errorf("Checkpoint is not supported by the lxc driver")
+}
+
+func (d *driver) Restore(c *execdriver.Command, pipes *execdriver.Pipes, restoreCallback execdriver.StartCallback, opts *execdriver.CheckpointOpts) (execdriver.ExitStatus, error) {
+	return execdriver.ExitStatus{ExitCode: -1}, fmt.Errorf("Restore is not supported by the lxc driver")
+}
+
+func (d *driver) Reattach(c *execdriver.Command, pipes *execdriver.Pipes, startCallback execdriver.StartCallback) (execdriver.ExitStatus, error) {
+	return execdriver.ExitStatus{ExitCode: -1}, fmt.Errorf("Reattach is not supported by the lxc driver")
+}
+
 func (d *driver) Terminate(c *execdriver.Command) error {
 	return KillLxc(c.ID, 9)
 }
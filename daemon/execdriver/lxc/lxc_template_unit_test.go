@@ -67,6 +67,78 @@ func TestLXCConfig(t *testing.T) {
 		fmt.Sprintf("lxc.cgroup.memory.memsw.limit_in_bytes = %d", mem*2))
 }
 
+func TestLXCConfigResources(t *testing.T) {
+	root, err := ioutil.TempDir("", "TestLXCConfigResources")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	os.MkdirAll(path.Join(root, "containers", "1"), 0777)
+
+	driver, err := NewDriver(root, root, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	swappiness := int64(0)
+	tests := []struct {
+		resources *execdriver.Resources
+		expected  []string
+	}{
+		{
+			resources: &execdriver.Resources{
+				Memory:            67108864,
+				MemoryReservation: 33554432,
+				KernelMemory:      16777216,
+				MemorySwappiness:  &swappiness,
+				OomKillDisable:    true,
+			},
+			expected: []string{
+				"lxc.cgroup.memory.limit_in_bytes = 67108864",
+				"lxc.cgroup.memory.soft_limit_in_bytes = 33554432",
+				"lxc.cgroup.memory.kmem.limit_in_bytes = 16777216",
+				"lxc.cgroup.memory.swappiness = 0",
+				"lxc.cgroup.memory.oom_control = 1",
+			},
+		},
+		{
+			resources: &execdriver.Resources{
+				PidsLimit:                    100,
+				BlkioWeight:                  300,
+				BlkioWeightDevice:            []*execdriver.WeightDevice{{Major: 8, Minor: 0, Weight: 500}},
+				BlkioThrottleReadBpsDevice:   []*execdriver.ThrottleDevice{{Major: 8, Minor: 0, Rate: 10485760}},
+				BlkioThrottleWriteIOPSDevice: []*execdriver.ThrottleDevice{{Major: 8, Minor: 16, Rate: 1000}},
+			},
+			expected: []string{
+				"lxc.cgroup.pids.max = 100",
+				"lxc.cgroup.blkio.weight = 300",
+				"lxc.cgroup.blkio.weight_device = 8:0 500",
+				"lxc.cgroup.blkio.throttle.read_bps_device = 8:0 10485760",
+				"lxc.cgroup.blkio.throttle.write_iops_device = 8:16 1000",
+			},
+		},
+	}
+	for _, test := range tests {
+		command := &execdriver.Command{
+			ID:        "1",
+			Resources: test.resources,
+			Network: &execdriver.Network{
+				Mtu:       1500,
+				Interface: nil,
+			},
+			AllowedDevices: make([]*configs.Device, 0),
+			ProcessConfig:  execdriver.ProcessConfig{},
+		}
+		p, err := driver.generateLXCConfig(command)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range test.expected {
+			grepFile(t, p, line)
+		}
+	}
+}
+
 func TestCustomLxcConfig(t *testing.T) {
 	root, err := ioutil.TempDir("", "TestCustomLxcConfig")
 	if err != nil {
@@ -5,16 +5,24 @@ package native
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net"
+	"os"
 	"path/filepath"
 	"strings"
 	"syscall"
 
+	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/daemon/execdriver/native/seccomp"
+	"github.com/docker/docker/daemon/networkdriver"
+	"github.com/docker/docker/daemon/networkdriver/sandbox"
+	"github.com/docker/docker/pkg/reaper"
 	"github.com/docker/docker/pkg/symlink"
 	"github.com/docker/libcontainer/apparmor"
 	"github.com/docker/libcontainer/configs"
 	"github.com/docker/libcontainer/devices"
+	"github.com/docker/libcontainer/netlink"
 	"github.com/docker/libcontainer/utils"
 )
 
@@ -35,6 +43,8 @@ func (d *driver) createContainer(c *execdriver.Command) (*configs.Config, error)
 		return nil, err
 	}
 
+	d.createUserNamespace(container, c)
+
 	if c.ProcessConfig.Privileged {
 		// clear readonly for /sys
 		for i := range container.Mounts {
@@ -60,10 +70,17 @@ func (d *driver) createContainer(c *execdriver.Command) (*configs.Config, error)
 	if err := execdriver.SetupCgroups(container, c); err != nil {
 		return nil, err
 	}
+	if err := setupAdditionalGroups(container, c); err != nil {
+		return nil, err
+	}
 
 	if err := d.setupMounts(container, c); err != nil {
 		return nil, err
 	}
+	d.setupInit(container, c)
+	if err := d.setupSeccomp(container, c); err != nil {
+		return nil, err
+	}
 
 	if err := d.setupLabels(container, c); err != nil {
 		return nil, err
@@ -94,6 +111,12 @@ func (d *driver) createNetwork(container *configs.Config, c *execdriver.Command)
 		return nil
 	}
 
+	if c.Network.NamespacePath != "" {
+		// the namespace has been set up by the network driver already
+		container.Namespaces.Add(configs.NEWNET, c.Network.NamespacePath)
+		return nil
+	}
+
 	container.Networks = []*configs.Network{
 		{
 			Type: "loopback",
@@ -114,6 +137,7 @@ func (d *driver) createNetwork(container *configs.Config, c *execdriver.Command)
 			Gateway:           c.Network.Interface.Gateway,
 			Type:              "veth",
 			Bridge:            c.Network.Interface.Bridge,
+			HairpinMode:       c.Network.Interface.HairpinMode,
 		}
 		if c.Network.Interface.GlobalIPv6Address != "" {
 			vethNetwork.IPv6Address = fmt.Sprintf("%s/%d", c.Network.Interface.GlobalIPv6Address, c.Network.Interface.GlobalIPv6PrefixLen)
@@ -142,6 +166,145 @@ func (d *driver) createNetwork(container *configs.Config, c *execdriver.Command)
 	return nil
 }
 
+// setupNetworkRates limits the bandwidth of the veth pair of a restored
+// container, which only exists once criu has restored the container.
+func setupNetworkRates(container *configs.Config, c *execdriver.Command) error {
+	iface := c.Network.Interface
+	if iface == nil || (iface.RateIngress == 0 && iface.RateEgress == 0) {
+		return nil
+	}
+	for _, n := range container.Networks {
+		if n.Type == "veth" {
+			return networkdriver.SetRateLimits(n.HostInterfaceName, iface.RateIngress, iface.RateEgress)
+		}
+	}
+	return nil
+}
+
+func (d *driver) netnsPath(id string) string {
+	return filepath.Join(d.root, "netns", id)
+}
+
+// shapedVethName returns the name of the host side of the veth pair of the
+// container id when its bandwidth is limited.
+func shapedVethName(id string) string {
+	if len(id) > 10 {
+		id = id[:10]
+	}
+	return "veth" + id
+}
+
+// createShapedNetwork sets up the veth pair of a container whose bandwidth is
+// limited in a network namespace of its own that the container joins.
+// libcontainer only creates the pair once the process of the container runs,
+// too late to shape its first packets.
+func (d *driver) createShapedNetwork(container *configs.Config, c *execdriver.Command) error {
+	iface := c.Network.Interface
+	if iface == nil || (iface.RateIngress == 0 && iface.RateEgress == 0) {
+		return nil
+	}
+	var (
+		veth     *configs.Network
+		networks []*configs.Network
+	)
+	for _, n := range container.Networks {
+		if n.Type == "veth" {
+			veth = n
+		} else {
+			networks = append(networks, n)
+		}
+	}
+	if veth == nil {
+		return nil
+	}
+
+	path := d.netnsPath(c.ID)
+	if err := sandbox.Create(path); err != nil {
+		return err
+	}
+	if err := setupShapedVeth(path, shapedVethName(c.ID), veth, iface); err != nil {
+		sandbox.Remove(path)
+		return err
+	}
+	container.Networks = networks
+	container.Namespaces.Add(configs.NEWNET, path)
+	return nil
+}
+
+// setupShapedVeth creates a veth pair named hostName on the host side, limits
+// its bandwidth and moves its peer configured as n into the network namespace
+// bind mounted at path.
+func setupShapedVeth(path, hostName string, n *configs.Network, iface *execdriver.NetworkInterface) error {
+	// the pair of a previous run of the container may not be gone yet
+	netlink.NetworkLinkDel(hostName)
+	peer, err := generateIfaceName()
+	if err != nil {
+		return err
+	}
+	if err := netlink.NetworkCreateVethPair(hostName, peer, n.TxQueueLen); err != nil {
+		return err
+	}
+	host := *n
+	host.HostInterfaceName = hostName
+	if err := attachVeth(&host); err != nil {
+		netlink.NetworkLinkDel(hostName)
+		return err
+	}
+	if err := networkdriver.SetRateLimits(hostName, iface.RateIngress, iface.RateEgress); err != nil {
+		netlink.NetworkLinkDel(hostName)
+		return err
+	}
+	if err := sandbox.MoveInterface(peer, path); err != nil {
+		netlink.NetworkLinkDel(hostName)
+		return err
+	}
+	return sandbox.Do(path, func() error {
+		child, err := net.InterfaceByName(peer)
+		if err != nil {
+			return err
+		}
+		if err := netlink.NetworkChangeName(child, n.Name); err != nil {
+			return err
+		}
+		// the interface has to be looked up again after the rename
+		if child, err = net.InterfaceByName(n.Name); err != nil {
+			return err
+		}
+		if n.MacAddress != "" {
+			if err := netlink.NetworkSetMacAddress(child, n.MacAddress); err != nil {
+				return err
+			}
+		}
+		for _, address := range []string{n.Address, n.IPv6Address} {
+			if address == "" {
+				continue
+			}
+			ip, ipNet, err := net.ParseCIDR(address)
+			if err != nil {
+				return err
+			}
+			if err := netlink.NetworkLinkAddIp(child, ip, ipNet); err != nil {
+				return err
+			}
+		}
+		if err := netlink.NetworkSetMTU(child, n.Mtu); err != nil {
+			return err
+		}
+		if err := netlink.NetworkLinkUp(child); err != nil {
+			return err
+		}
+		for _, gateway := range []string{n.Gateway, n.IPv6Gateway} {
+			if gateway == "" {
+				continue
+			}
+			if err := netlink.AddDefaultGw(gateway, n.Name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func (d *driver) createIpc(container *configs.Config, c *execdriver.Command) error {
 	if c.Ipc.HostIpc {
 		container.Namespaces.Remove(configs.NEWIPC)
@@ -176,6 +339,28 @@ func (d *driver) createPid(container *configs.Config, c *execdriver.Command) err
 	return nil
 }
 
+func (d *driver) createUserNamespace(container *configs.Config, c *execdriver.Command) {
+	if c.UIDMapping == nil && c.GIDMapping == nil {
+		return
+	}
+
+	container.Namespaces.Add(configs.NEWUSER, "")
+	for _, m := range c.UIDMapping {
+		container.UidMappings = append(container.UidMappings, configs.IDMap{
+			ContainerID: m.ContainerID,
+			HostID:      m.HostID,
+			Size:        m.Size,
+		})
+	}
+	for _, m := range c.GIDMapping {
+		container.GidMappings = append(container.GidMappings, configs.IDMap{
+			ContainerID: m.ContainerID,
+			HostID:      m.HostID,
+			Size:        m.Size,
+		})
+	}
+}
+
 func (d *driver) setPrivileged(container *configs.Config) (err error) {
 	container.Capabilities = execdriver.GetAllCapabilities()
 	container.Cgroups.AllowAllDevices = true
@@ -198,6 +383,30 @@ func (d *driver) setCapabilities(container *configs.Config, c *execdriver.Comman
 	return err
 }
 
+// setupSeccomp mounts dockerinit in the container to load its syscall
+// filter, if it has one, see setupSeccompInit. It would be created in the
+// /dev of the host if the container bind mounts it, such a container runs
+// without the default profile.
+func (d *driver) setupSeccomp(container *configs.Config, c *execdriver.Command) error {
+	filter, err := seccompFilter(c)
+	if err != nil {
+		return err
+	}
+	if filter == nil {
+		if c.SeccompProfile == "" && !c.ProcessConfig.Privileged && seccomp.IsEnabled() && hasDevVolume(c) {
+			logrus.Warnf("Container %s has a volume at /dev, it runs without the default seccomp profile", c.ID)
+		}
+		return nil
+	}
+	container.Mounts = append(container.Mounts, &configs.Mount{
+		Source:      d.initPath,
+		Destination: seccompInitPath,
+		Device:      "bind",
+		Flags:       syscall.MS_BIND | syscall.MS_RDONLY,
+	})
+	return nil
+}
+
 func (d *driver) setupRlimits(container *configs.Config, c *execdriver.Command) {
 	if c.Resources == nil {
 		return
@@ -231,6 +440,14 @@ func (d *driver) setupMounts(container *configs.Config, c *execdriver.Command) e
 	}
 	container.Mounts = defaultMounts
 
+	if c.ShmSize != 0 {
+		for _, m := range container.Mounts {
+			if m.Destination == "/dev/shm" {
+				m.Data = fmt.Sprintf("mode=1777,size=%d", c.ShmSize)
+			}
+		}
+	}
+
 	for _, m := range c.Mounts {
 		dest, err := symlink.FollowSymlinkInScope(filepath.Join(c.Rootfs, m.Destination), c.Rootfs)
 		if err != nil {
@@ -254,9 +471,46 @@ func (d *driver) setupMounts(container *configs.Config, c *execdriver.Command) e
 	return nil
 }
 
+// setupInit mounts dockerinit in the container to run as its init process,
+// see Run.
+func (d *driver) setupInit(container *configs.Config, c *execdriver.Command) {
+	if !c.Init {
+		return
+	}
+	container.Mounts = append(container.Mounts, &configs.Mount{
+		Source:      d.initPath,
+		Destination: reaper.Path,
+		Device:      "bind",
+		Flags:       syscall.MS_BIND | syscall.MS_RDONLY,
+	})
+}
+
 func (d *driver) setupLabels(container *configs.Config, c *execdriver.Command) error {
 	container.ProcessLabel = c.ProcessLabel
 	container.MountLabel = c.MountLabel
 
 	return nil
 }
+
+// setupAdditionalGroups resolves the groups added to the container's user,
+// by name or gid, with the /etc/group of the container's rootfs.
+func setupAdditionalGroups(container *configs.Config, c *execdriver.Command) error {
+	if len(c.GroupAdd) == 0 {
+		return nil
+	}
+	groupPath, err := symlink.FollowSymlinkInScope(filepath.Join(c.Rootfs, "etc", "group"), c.Rootfs)
+	if err != nil {
+		return err
+	}
+	var group io.Reader
+	if f, err := os.Open(groupPath); err == nil {
+		defer f.Close()
+		group = f
+	}
+	gids, err := execdriver.GetAdditionalGroups(c.GroupAdd, group)
+	if err != nil {
+		return err
+	}
+	container.AdditionalGroups = gids
+	return nil
+}
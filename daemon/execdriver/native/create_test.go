@@ -0,0 +1,103 @@
+// +build linux,cgo
+
+package native
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/daemon/networkdriver/sandbox"
+	"github.com/docker/libcontainer/configs"
+	"github.com/docker/libcontainer/netlink"
+)
+
+func TestCreateShapedNetwork(t *testing.T) {
+	if _, err := exec.LookPath("tc"); err != nil {
+		t.Skip("tc is not available")
+	}
+	tmp, err := ioutil.TempDir("", "docker-shaped-network")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	// keep the bridge and the host side of the veth pair off the host
+	host := filepath.Join(tmp, "host")
+	if err := sandbox.Create(host); err != nil {
+		t.Skipf("Unable to create a network namespace: %v", err)
+	}
+	defer sandbox.Remove(host)
+
+	d := &driver{root: tmp}
+	id := "0123456789abcdef"
+	container := &configs.Config{
+		Namespaces: configs.Namespaces{{Type: configs.NEWNET}},
+		Networks: []*configs.Network{
+			{Type: "loopback"},
+			{
+				Type:    "veth",
+				Name:    "eth0",
+				Bridge:  "dockertest0",
+				Mtu:     1500,
+				Address: "172.31.250.2/24",
+				Gateway: "172.31.250.1",
+			},
+		},
+	}
+	c := &execdriver.Command{
+		ID: id,
+		Network: &execdriver.Network{
+			Interface: &execdriver.NetworkInterface{RateIngress: 1000000},
+		},
+	}
+
+	err = sandbox.Do(host, func() error {
+		if err := netlink.CreateBridge("dockertest0", false); err != nil {
+			return err
+		}
+		if err := d.createShapedNetwork(container, c); err != nil {
+			return err
+		}
+		defer d.Clean(id)
+
+		if len(container.Networks) != 1 || container.Networks[0].Type != "loopback" {
+			t.Fatalf("Expected only the loopback network left to libcontainer, got %v", container.Networks)
+		}
+		if !container.Namespaces.Contains(configs.NEWNET) || container.Namespaces[0].Path != d.netnsPath(id) {
+			t.Fatalf("Expected the container to join %s, got %v", d.netnsPath(id), container.Namespaces)
+		}
+		output, err := exec.Command("tc", "qdisc", "show", "dev", shapedVethName(id)).CombinedOutput()
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(string(output), "tbf") {
+			t.Fatalf("Expected the veth pair to be shaped before the container starts, got %s", output)
+		}
+		return sandbox.Do(d.netnsPath(id), func() error {
+			iface, err := net.InterfaceByName("eth0")
+			if err != nil {
+				return err
+			}
+			addrs, err := iface.Addrs()
+			if err != nil {
+				return err
+			}
+			if len(addrs) == 0 || addrs[0].String() != "172.31.250.2/24" {
+				t.Fatalf("Unexpected addresses of eth0 %v", addrs)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(d.netnsPath(id)); !os.IsNotExist(err) {
+		t.Fatalf("Expected the network namespace to be removed, got %v", err)
+	}
+}
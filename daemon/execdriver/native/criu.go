@@ -0,0 +1,493 @@
+// +build linux,cgo
+
+package native
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/libcontainer"
+	"github.com/docker/libcontainer/cgroups"
+	"github.com/docker/libcontainer/configs"
+	"github.com/docker/libcontainer/netlink"
+	"github.com/docker/libcontainer/system"
+)
+
+// The containers are checkpointed and restored with criu(8).
+const (
+	criuPath = "criu"
+	// descriptorsFilename holds, in the images of a checkpoint, what the
+	// stdio of the init process pointed to
+	descriptorsFilename = "descriptors.json"
+	criuWorkDirectory   = "criu.work"
+)
+
+// Checkpoint dumps the processes of the running container c to disk. They
+// are stopped unless opts.LeaveRunning is set.
+func (d *driver) Checkpoint(c *execdriver.Command, opts *execdriver.CheckpointOpts) error {
+	d.Lock()
+	active := d.activeContainers[c.ID]
+	d.Unlock()
+	if active == nil {
+		return fmt.Errorf("active container for %s does not exist", c.ID)
+	}
+	state, err := active.State()
+	if err != nil {
+		return err
+	}
+	config := active.Config()
+
+	workDir, err := d.criuWorkDirectory(c.ID, opts)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(opts.ImagesDirectory, 0700); err != nil {
+		return err
+	}
+	pid := state.InitProcessPid
+	// the stdio of the init process are pipes to the daemon, they have to be
+	// handed to criu again to restore it
+	descriptors, err := getStdioDescriptors(pid)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(descriptors)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(opts.ImagesDirectory, descriptorsFilename), data, 0600); err != nil {
+		return err
+	}
+
+	return criu(dumpArgs(&config, pid, opts, workDir), workDir, nil)
+}
+
+// dumpArgs returns the arguments of criu to dump the process tree of pid.
+func dumpArgs(container *configs.Config, pid int, opts *execdriver.CheckpointOpts, workDir string) []string {
+	args := []string{"dump",
+		"--tree", strconv.Itoa(pid),
+		"--log-file", "dump.log",
+	}
+	args = append(args, criuArgs(container, opts, workDir)...)
+	for _, m := range container.Mounts {
+		if m.Device == "bind" {
+			dest := criuMountpoint(container, m)
+			args = append(args, "--ext-mount-map", fmt.Sprintf("%s:%s", dest, dest))
+		}
+	}
+	if opts.LeaveRunning {
+		args = append(args, "--leave-running")
+	}
+	return args
+}
+
+// Restore restores the checkpointed processes of the container c and waits
+// for them like Run.
+func (d *driver) Restore(c *execdriver.Command, pipes *execdriver.Pipes, restoreCallback execdriver.StartCallback, opts *execdriver.CheckpointOpts) (execdriver.ExitStatus, error) {
+	if c.ProcessConfig.Tty {
+		return execdriver.ExitStatus{ExitCode: -1}, fmt.Errorf("restoring a container with a tty is not supported")
+	}
+	container, err := d.createContainer(c)
+	if err != nil {
+		return execdriver.ExitStatus{ExitCode: -1}, err
+	}
+
+	p := &libcontainer.Process{}
+	if err := setupPipes(container, &c.ProcessConfig, p, pipes); err != nil {
+		return execdriver.ExitStatus{ExitCode: -1}, err
+	}
+
+	cp, err := d.restore(c, container, p, opts)
+	if err != nil {
+		d.cleanContainer(c.ID)
+		return execdriver.ExitStatus{ExitCode: -1}, err
+	}
+	cont, err := d.factory.Load(c.ID)
+	if err != nil {
+		cp.Signal(os.Kill)
+		cp.Wait()
+		d.cleanContainer(c.ID)
+		return execdriver.ExitStatus{ExitCode: -1}, err
+	}
+	d.Lock()
+	d.activeContainers[c.ID] = cont
+	d.Unlock()
+	defer func() {
+		cont.Destroy()
+		d.cleanContainer(c.ID)
+	}()
+
+	if err := setupNetworkRates(container, c); err != nil {
+		cp.Signal(os.Kill)
+		cp.Wait()
+		return execdriver.ExitStatus{ExitCode: -1}, err
+	}
+	return d.wait(c, container, cont, cp, restoreCallback)
+}
+
+// restore restores the init process of the container c with criu, whose
+// stdio are connected to those of p, and saves the state of the container
+// for the factory to load it.
+func (d *driver) restore(c *execdriver.Command, container *configs.Config, p *libcontainer.Process, opts *execdriver.CheckpointOpts) (*criuProcess, error) {
+	if err := d.createContainerRoot(c.ID); err != nil {
+		return nil, err
+	}
+	workDir, err := d.criuWorkDirectory(c.ID, opts)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(filepath.Join(opts.ImagesDirectory, descriptorsFilename))
+	if err != nil {
+		return nil, err
+	}
+	var descriptors []string
+	if err := json.Unmarshal(data, &descriptors); err != nil {
+		return nil, err
+	}
+
+	pidfile := filepath.Join(workDir, "restore.pid")
+	os.Remove(pidfile)
+	args := restoreArgs(container, pidfile, opts, workDir)
+
+	cp := &criuProcess{}
+	files, err := cp.setupIO(p, descriptors)
+	if err != nil {
+		cp.closeIO()
+		return nil, err
+	}
+	extraFiles := make([]*os.File, len(files))
+	for i, f := range files {
+		// criu gets the files after stdio, as fds 3 and up
+		args = append(args, "--inherit-fd", fmt.Sprintf("fd[%d]:%s", i+3, descriptors[f.index]))
+		extraFiles[i] = f.file
+	}
+	err = criu(args, workDir, extraFiles)
+	for _, f := range extraFiles {
+		f.Close()
+	}
+	if err != nil {
+		cp.closeIO()
+		return nil, err
+	}
+	if err := cp.load(pidfile); err != nil {
+		cp.closeIO()
+		return nil, err
+	}
+
+	// criu restored the cgroups of the container along with its processes
+	// (--manage-cgroups), they are not set up again
+	cgroupPaths, err := restoredCgroupPaths(cp.process.Pid)
+	if err != nil {
+		cp.Signal(os.Kill)
+		cp.Wait()
+		return nil, err
+	}
+	if err := d.restoreState(c.ID, container, cp.process.Pid, cgroupPaths); err != nil {
+		cp.Signal(os.Kill)
+		cp.Wait()
+		cgroups.RemovePaths(cgroupPaths)
+		return nil, err
+	}
+	return cp, nil
+}
+
+// restoredCgroupPaths returns the cgroups of the process pid by subsystem.
+func restoredCgroupPaths(pid int) (map[string]string, error) {
+	mounts, err := cgroups.GetCgroupMounts()
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return nil, err
+	}
+	return parseCgroupPaths(mounts, data)
+}
+
+// parseCgroupPaths returns the cgroups listed in the /proc/<pid>/cgroup file
+// data within the hierarchies mounted at mounts. Named hierarchies, like the
+// one of systemd, are not managed by libcontainer and are left out.
+func parseCgroupPaths(mounts []cgroups.Mount, data []byte) (map[string]string, error) {
+	paths := make(map[string]string)
+	for _, m := range mounts {
+		for _, subsystem := range m.Subsystems {
+			if strings.HasPrefix(subsystem, "name=") {
+				continue
+			}
+			dir, err := cgroups.ParseCgroupFile(subsystem, bytes.NewReader(data))
+			if err != nil {
+				if cgroups.IsNotFound(err) {
+					continue
+				}
+				return nil, err
+			}
+			paths[subsystem] = filepath.Join(m.Mountpoint, dir)
+		}
+	}
+	return paths, nil
+}
+
+// restoreState connects the network of the container whose init process
+// pid criu restored, and writes the state of the container that
+// libcontainer loads. libcontainer has no API to adopt a running process,
+// so the state is written the way it saves it itself, in the state.json
+// file of the container. TestRestoreState checks that the factory loads it.
+func (d *driver) restoreState(id string, container *configs.Config, pid int, cgroupPaths map[string]string) error {
+	for _, n := range container.Networks {
+		if n.Type == "veth" {
+			if err := attachVeth(n); err != nil {
+				return err
+			}
+		}
+	}
+
+	startTime, err := system.GetProcessStartTime(pid)
+	if err != nil {
+		return err
+	}
+	state := &libcontainer.State{
+		ID:                   id,
+		InitProcessPid:       pid,
+		InitProcessStartTime: startTime,
+		CgroupPaths:          cgroupPaths,
+		NamespacePaths:       make(map[configs.NamespaceType]string),
+		Config:               *container,
+	}
+	for _, ns := range container.Namespaces {
+		state.NamespacePaths[ns.Type] = ns.GetPath(pid)
+	}
+	f, err := os.Create(filepath.Join(d.root, id, "state.json"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(state)
+}
+
+// attachVeth connects the host side of the veth pair of the network n,
+// which criu restored, to its bridge and brings it up, as libcontainer does
+// for the veth pairs it creates.
+func attachVeth(n *configs.Network) error {
+	bridge, err := net.InterfaceByName(n.Bridge)
+	if err != nil {
+		return err
+	}
+	host, err := net.InterfaceByName(n.HostInterfaceName)
+	if err != nil {
+		return err
+	}
+	if err := netlink.AddToBridge(host, bridge); err != nil {
+		return err
+	}
+	if err := netlink.NetworkSetMTU(host, n.Mtu); err != nil {
+		return err
+	}
+	if n.HairpinMode {
+		if err := netlink.SetHairpinMode(host, true); err != nil {
+			return err
+		}
+	}
+	return netlink.NetworkLinkUp(host)
+}
+
+// criuWorkDirectory creates and returns the directory for the logs and pid
+// files of criu, which defaults to one in the directory of the container.
+func (d *driver) criuWorkDirectory(id string, opts *execdriver.CheckpointOpts) (string, error) {
+	workDir := opts.WorkDirectory
+	if workDir == "" {
+		workDir = filepath.Join(d.root, id, criuWorkDirectory)
+	}
+	if err := os.MkdirAll(workDir, 0700); err != nil {
+		return "", err
+	}
+	return workDir, nil
+}
+
+// restoreArgs returns the arguments of criu to restore the processes of
+// container, writing the pid of their root to pidfile.
+func restoreArgs(container *configs.Config, pidfile string, opts *execdriver.CheckpointOpts, workDir string) []string {
+	args := []string{"restore",
+		"--restore-detached",
+		"--restore-sibling",
+		"--pidfile", pidfile,
+		"--log-file", "restore.log",
+	}
+	args = append(args, criuArgs(container, opts, workDir)...)
+	for _, m := range container.Mounts {
+		if m.Device == "bind" {
+			args = append(args, "--ext-mount-map", fmt.Sprintf("%s:%s", criuMountpoint(container, m), m.Source))
+		}
+	}
+	for _, n := range container.Networks {
+		if n.Type == "veth" {
+			args = append(args, "--veth-pair", fmt.Sprintf("%s=%s", n.Name, n.HostInterfaceName))
+		}
+	}
+	return args
+}
+
+// criuMountpoint returns where the mount m is in the container, as criu
+// sees it. The destinations of the mounts were resolved to paths in the
+// rootfs of the container when it was created.
+func criuMountpoint(container *configs.Config, m *configs.Mount) string {
+	dest := m.Destination
+	if strings.HasPrefix(dest, container.Rootfs) {
+		dest = filepath.Join("/", dest[len(container.Rootfs):])
+	}
+	return dest
+}
+
+// criuArgs returns the arguments common to dumps and restores.
+func criuArgs(container *configs.Config, opts *execdriver.CheckpointOpts, workDir string) []string {
+	args := []string{
+		"-v4",
+		"--images-dir", opts.ImagesDirectory,
+		"--work-dir", workDir,
+		"--root", container.Rootfs,
+		"--manage-cgroups",
+		"--evasive-devices",
+	}
+	if opts.TcpEstablished {
+		args = append(args, "--tcp-established")
+	}
+	if opts.ExternalUnixConnections {
+		args = append(args, "--ext-unix-sk")
+	}
+	if opts.ShellJob {
+		args = append(args, "--shell-job")
+	}
+	return args
+}
+
+func criu(args []string, workDir string, extraFiles []*os.File) error {
+	cmd := exec.Command(criuPath, args...)
+	cmd.ExtraFiles = extraFiles
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("criu %s failed: %v: %s (see the logs in %s)", args[0], err, strings.TrimSpace(string(out)), workDir)
+	}
+	return nil
+}
+
+// getStdioDescriptors returns what the stdio of the process pid point to.
+func getStdioDescriptors(pid int) ([]string, error) {
+	descriptors := make([]string, 3)
+	for i := range descriptors {
+		link, err := os.Readlink(fmt.Sprintf("/proc/%d/fd/%d", pid, i))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		descriptors[i] = link
+	}
+	return descriptors, nil
+}
+
+// inheritedFile is a file that replaces the stdio descriptor index of the
+// restored process.
+type inheritedFile struct {
+	index int
+	file  *os.File
+}
+
+// criuProcess is the init process of a container restored by criu. It is a
+// child of the daemon but was not started by it.
+type criuProcess struct {
+	process *os.Process
+	closers []io.Closer
+	copies  sync.WaitGroup
+}
+
+// setupIO creates pipes for the stdio of p that were pipes when the
+// container was checkpointed and returns their ends for the restored process.
+func (cp *criuProcess) setupIO(p *libcontainer.Process, descriptors []string) ([]inheritedFile, error) {
+	var files []inheritedFile
+	for i, d := range descriptors {
+		if !strings.HasPrefix(d, "pipe:") {
+			continue
+		}
+		r, w, err := os.Pipe()
+		if err != nil {
+			for _, f := range files {
+				f.file.Close()
+			}
+			return nil, err
+		}
+		if i == 0 {
+			files = append(files, inheritedFile{i, r})
+			cp.closers = append(cp.closers, w)
+			if p.Stdin != nil {
+				go func() {
+					io.Copy(w, p.Stdin)
+					w.Close()
+				}()
+			}
+			continue
+		}
+		out := p.Stdout
+		if i == 2 {
+			out = p.Stderr
+		}
+		if out == nil {
+			out = ioutil.Discard
+		}
+		files = append(files, inheritedFile{i, w})
+		cp.closers = append(cp.closers, r)
+		cp.copies.Add(1)
+		go func() {
+			io.Copy(out, r)
+			cp.copies.Done()
+		}()
+	}
+	return files, nil
+}
+
+func (cp *criuProcess) closeIO() {
+	for _, c := range cp.closers {
+		c.Close()
+	}
+}
+
+// load finds the process restored by criu from its pid file.
+func (cp *criuProcess) load(pidfile string) error {
+	data, err := ioutil.ReadFile(pidfile)
+	if err != nil {
+		return err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return err
+	}
+	cp.process, err = os.FindProcess(pid)
+	return err
+}
+
+func (cp *criuProcess) Pid() (int, error) {
+	return cp.process.Pid, nil
+}
+
+func (cp *criuProcess) Signal(sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return fmt.Errorf("os: unsupported signal type")
+	}
+	return syscall.Kill(cp.process.Pid, s)
+}
+
+func (cp *criuProcess) Wait() (*os.ProcessState, error) {
+	ps, err := cp.process.Wait()
+	// wait for the output of the process to be copied, like exec.Cmd does
+	cp.copies.Wait()
+	cp.closeIO()
+	return ps, err
+}
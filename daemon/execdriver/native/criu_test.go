@@ -0,0 +1,113 @@
+// +build linux,cgo
+
+package native
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/libcontainer"
+	"github.com/docker/libcontainer/cgroups"
+	"github.com/docker/libcontainer/configs"
+)
+
+func TestParseCgroupPaths(t *testing.T) {
+	mounts := []cgroups.Mount{
+		{Mountpoint: "/sys/fs/cgroup/systemd", Subsystems: []string{"name=systemd"}},
+		{Mountpoint: "/sys/fs/cgroup/cpu,cpuacct", Subsystems: []string{"cpu", "cpuacct"}},
+		{Mountpoint: "/sys/fs/cgroup/memory", Subsystems: []string{"memory"}},
+		{Mountpoint: "/sys/fs/cgroup/pids", Subsystems: []string{"pids"}},
+	}
+	data := []byte(`4:memory:/docker/abc
+3:cpu,cpuacct:/docker/abc
+1:name=systemd:/system.slice/docker.service
+`)
+	paths, err := parseCgroupPaths(mounts, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"cpu":     "/sys/fs/cgroup/cpu,cpuacct/docker/abc",
+		"cpuacct": "/sys/fs/cgroup/cpu,cpuacct/docker/abc",
+		"memory":  "/sys/fs/cgroup/memory/docker/abc",
+	}
+	if len(paths) != len(expected) {
+		t.Fatalf("Expected the cgroups %v, got %v", expected, paths)
+	}
+	for subsystem, path := range expected {
+		if paths[subsystem] != path {
+			t.Fatalf("Expected the %s cgroup at %s, got %s", subsystem, path, paths[subsystem])
+		}
+	}
+}
+
+func TestRestoreState(t *testing.T) {
+	root, err := ioutil.TempDir("", "docker-criu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	if err := os.Mkdir(filepath.Join(root, "abc"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	d := &driver{root: root}
+	container := &configs.Config{
+		Rootfs:     "/",
+		Cgroups:    &configs.Cgroup{Name: "abc"},
+		Namespaces: configs.Namespaces{{Type: configs.NEWNET}},
+	}
+	paths := map[string]string{"memory": "/sys/fs/cgroup/memory/docker/abc"}
+	if err := d.restoreState("abc", container, os.Getpid(), paths); err != nil {
+		t.Fatal(err)
+	}
+
+	// the state must stay one that libcontainer loads
+	factory, err := libcontainer.New(root, libcontainer.Cgroupfs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cont, err := factory.Load("abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := cont.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.InitProcessPid != os.Getpid() || state.CgroupPaths["memory"] != paths["memory"] {
+		t.Fatalf("Unexpected state %+v", state)
+	}
+}
+
+func TestCriuMountArgs(t *testing.T) {
+	container := &configs.Config{
+		Rootfs: "/var/lib/docker/aufs/mnt/abc",
+		Mounts: []*configs.Mount{
+			{Source: "/var/lib/docker/containers/abc/hosts", Destination: "/var/lib/docker/aufs/mnt/abc/etc/hosts", Device: "bind"},
+			{Source: "/data", Destination: "/var/lib/docker/aufs/mnt/abc/data", Device: "bind"},
+			{Source: "tmpfs", Destination: "/dev", Device: "tmpfs"},
+		},
+	}
+	opts := &execdriver.CheckpointOpts{ImagesDirectory: "/images"}
+
+	dump := strings.Join(dumpArgs(container, 1, opts, "/work"), " ")
+	for _, arg := range []string{"--ext-mount-map /etc/hosts:/etc/hosts", "--ext-mount-map /data:/data"} {
+		if !strings.Contains(dump, arg) {
+			t.Fatalf("Expected %q in the dump arguments %q", arg, dump)
+		}
+	}
+	restore := strings.Join(restoreArgs(container, "/work/restore.pid", opts, "/work"), " ")
+	for _, arg := range []string{"--ext-mount-map /etc/hosts:/var/lib/docker/containers/abc/hosts", "--ext-mount-map /data:/data"} {
+		if !strings.Contains(restore, arg) {
+			t.Fatalf("Expected %q in the restore arguments %q", arg, restore)
+		}
+	}
+	if strings.Count(dump, "--ext-mount-map") != 2 || strings.Count(restore, "--ext-mount-map") != 2 {
+		t.Fatalf("Expected external mounts only for the bind mounts, got %q and %q", dump, restore)
+	}
+}
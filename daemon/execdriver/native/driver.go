@@ -14,12 +14,12 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/daemon/execdriver"
-	"github.com/docker/docker/pkg/reexec"
+	"github.com/docker/docker/daemon/networkdriver/sandbox"
+	"github.com/docker/docker/pkg/reaper"
 	sysinfo "github.com/docker/docker/pkg/system"
 	"github.com/docker/docker/pkg/term"
 	"github.com/docker/libcontainer"
 	"github.com/docker/libcontainer/apparmor"
-	"github.com/docker/libcontainer/cgroups/systemd"
 	"github.com/docker/libcontainer/configs"
 	"github.com/docker/libcontainer/system"
 	"github.com/docker/libcontainer/utils"
@@ -36,10 +36,14 @@ type driver struct {
 	activeContainers map[string]libcontainer.Container
 	machineMemory    int64
 	factory          libcontainer.Factory
+	liveRestore      bool
 	sync.Mutex
 }
 
-func NewDriver(root, initPath string) (*driver, error) {
+// NewDriver returns the native driver. With liveRestore the containers are
+// run by shims, they keep running when the daemon exits and are reattached
+// to when it starts again.
+func NewDriver(root, initPath string, liveRestore bool) (*driver, error) {
 	meminfo, err := sysinfo.ReadMemInfo()
 	if err != nil {
 		return nil, err
@@ -52,16 +56,7 @@ func NewDriver(root, initPath string) (*driver, error) {
 	if err := apparmor.InstallDefaultProfile(); err != nil {
 		return nil, err
 	}
-	cgm := libcontainer.Cgroupfs
-	if systemd.UseSystemd() {
-		cgm = libcontainer.SystemdCgroups
-	}
-
-	f, err := libcontainer.New(
-		root,
-		cgm,
-		libcontainer.InitPath(reexec.Self(), DriverName),
-	)
+	f, err := newFactory(root, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -72,6 +67,7 @@ func NewDriver(root, initPath string) (*driver, error) {
 		activeContainers: make(map[string]libcontainer.Container),
 		machineMemory:    meminfo.MemTotal,
 		factory:          f,
+		liveRestore:      liveRestore,
 	}, nil
 }
 
@@ -81,27 +77,50 @@ type execOutput struct {
 }
 
 func (d *driver) Run(c *execdriver.Command, pipes *execdriver.Pipes, startCallback execdriver.StartCallback) (execdriver.ExitStatus, error) {
+	if d.liveRestore {
+		return d.runShim(c, pipes, startCallback)
+	}
+
 	// take the Command and populate the libcontainer.Config from it
 	container, err := d.createContainer(c)
 	if err != nil {
 		return execdriver.ExitStatus{ExitCode: -1}, err
 	}
+	if err := d.createShapedNetwork(container, c); err != nil {
+		return execdriver.ExitStatus{ExitCode: -1}, err
+	}
+	created := false
+	defer func() {
+		// cleanContainer releases the network namespace once the
+		// container is created
+		if !created {
+			sandbox.Remove(d.netnsPath(c.ID))
+		}
+	}()
 
 	p := &libcontainer.Process{
-		Args: append([]string{c.ProcessConfig.Entrypoint}, c.ProcessConfig.Arguments...),
+		Args: processArgs(c),
 		Env:  c.ProcessConfig.Env,
 		Cwd:  c.WorkingDir,
 		User: c.ProcessConfig.User,
 	}
+	if err := setupSeccompInit(c, p); err != nil {
+		return execdriver.ExitStatus{ExitCode: -1}, err
+	}
 
 	if err := setupPipes(container, &c.ProcessConfig, p, pipes); err != nil {
 		return execdriver.ExitStatus{ExitCode: -1}, err
 	}
 
-	cont, err := d.factory.Create(c.ID, container)
+	factory, err := newFactory(d.root, newSettings(c))
+	if err != nil {
+		return execdriver.ExitStatus{ExitCode: -1}, err
+	}
+	cont, err := factory.Create(c.ID, container)
 	if err != nil {
 		return execdriver.ExitStatus{ExitCode: -1}, err
 	}
+	created = true
 	d.Lock()
 	d.activeContainers[c.ID] = cont
 	d.Unlock()
@@ -113,7 +132,29 @@ func (d *driver) Run(c *execdriver.Command, pipes *execdriver.Pipes, startCallba
 	if err := cont.Start(p); err != nil {
 		return execdriver.ExitStatus{ExitCode: -1}, err
 	}
+	return d.wait(c, container, cont, p, startCallback)
+}
+
+// processArgs returns the command line of the init process of c.
+func processArgs(c *execdriver.Command) []string {
+	args := append([]string{c.ProcessConfig.Entrypoint}, c.ProcessConfig.Arguments...)
+	if c.Init {
+		args = append([]string{reaper.Path, "--"}, args...)
+	}
+	return args
+}
 
+// process is the init process of a container, which the driver started or
+// restored.
+type process interface {
+	Pid() (int, error)
+	Signal(os.Signal) error
+	Wait() (*os.ProcessState, error)
+}
+
+// wait waits for the init process p of the container to exit, after
+// reporting it to startCallback.
+func (d *driver) wait(c *execdriver.Command, container *configs.Config, cont libcontainer.Container, p process, startCallback execdriver.StartCallback) (execdriver.ExitStatus, error) {
 	if startCallback != nil {
 		pid, err := p.Pid()
 		if err != nil {
@@ -186,7 +227,7 @@ func killCgroupProcs(c libcontainer.Container) {
 	}
 }
 
-func waitInPIDHost(p *libcontainer.Process, c libcontainer.Container) func() (*os.ProcessState, error) {
+func waitInPIDHost(p process, c libcontainer.Container) func() (*os.ProcessState, error) {
 	return func() (*os.ProcessState, error) {
 		pid, err := p.Pid()
 		if err != nil {
@@ -285,7 +326,7 @@ func (d *driver) cleanContainer(id string) error {
 	d.Lock()
 	delete(d.activeContainers, id)
 	d.Unlock()
-	return os.RemoveAll(filepath.Join(d.root, id))
+	return d.Clean(id)
 }
 
 func (d *driver) createContainerRoot(id string) error {
@@ -293,6 +334,12 @@ func (d *driver) createContainerRoot(id string) error {
 }
 
 func (d *driver) Clean(id string) error {
+	if err := sandbox.Remove(d.netnsPath(id)); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(d.shimDir(id)); err != nil {
+		return err
+	}
 	return os.RemoveAll(filepath.Join(d.root, id))
 }
 
@@ -306,6 +353,36 @@ func (d *driver) Stats(id string) (*execdriver.ResourceStats, error) {
 	if err != nil {
 		return nil, err
 	}
+	// libcontainer reports the host side of the veth pairs, name them
+	// as the container sees them
+	for _, iface := range stats.Interfaces {
+		for _, n := range c.Config().Networks {
+			if n.HostInterfaceName != "" && n.HostInterfaceName == iface.Name {
+				iface.Name = n.Name
+			}
+		}
+	}
+	// libcontainer doesn't know the veth pair set up for a container whose
+	// bandwidth is limited, see createShapedNetwork
+	if _, err := os.Stat(d.netnsPath(id)); err == nil {
+		istats, err := execdriver.GetNetworkInterfaceStats(shapedVethName(id))
+		if err != nil {
+			return nil, err
+		}
+		istats.Name = "eth0"
+		stats.Interfaces = append(stats.Interfaces, istats)
+	}
+	state, err := c.State()
+	if err != nil {
+		return nil, err
+	}
+	// the pids cgroup may be gone already, the other stats are still worth
+	// reporting
+	pidsStats, err := execdriver.GetPidsStats(state.CgroupPaths["pids"])
+	if err != nil {
+		logrus.Warnf("Error reading the pids stats of container %s: %s", id, err)
+		pidsStats = execdriver.PidsStats{}
+	}
 	memoryLimit := c.Config().Cgroups.Memory
 	// if the container does not have any memory limit specified set the
 	// limit to the machines memory
@@ -314,11 +391,29 @@ func (d *driver) Stats(id string) (*execdriver.ResourceStats, error) {
 	}
 	return &execdriver.ResourceStats{
 		Stats:       stats,
+		PidsStats:   pidsStats,
 		Read:        now,
 		MemoryLimit: memoryLimit,
 	}, nil
 }
 
+func (d *driver) Update(c *execdriver.Command) error {
+	d.Lock()
+	active := d.activeContainers[c.ID]
+	d.Unlock()
+	if active == nil {
+		return execdriver.ErrNotRunning
+	}
+	state, err := active.State()
+	if err != nil {
+		return err
+	}
+	config := active.Config()
+	return execdriver.UpdateCgroups(&config, c, state.CgroupPaths, func(config *configs.Config) error {
+		return active.Set(*config)
+	})
+}
+
 type TtyConsole struct {
 	console libcontainer.Console
 }
@@ -22,8 +22,8 @@ func (d *driver) Exec(c *execdriver.Command, processConfig *execdriver.ProcessCo
 
 	p := &libcontainer.Process{
 		Args: append([]string{processConfig.Entrypoint}, processConfig.Arguments...),
-		Env:  c.ProcessConfig.Env,
-		Cwd:  c.WorkingDir,
+		Env:  processConfig.Env,
+		Cwd:  processConfig.Dir,
 		User: processConfig.User,
 	}
 
@@ -32,6 +32,9 @@ func (d *driver) Exec(c *execdriver.Command, processConfig *execdriver.ProcessCo
 	}
 
 	config := active.Config()
+	if err := setupSeccompInit(c, p); err != nil {
+		return -1, err
+	}
 	if err := setupPipes(&config, processConfig, p, pipes); err != nil {
 		return -1, err
 	}
@@ -40,14 +43,25 @@ func (d *driver) Exec(c *execdriver.Command, processConfig *execdriver.ProcessCo
 		return -1, err
 	}
 
+	processConfig.Signal = p.Signal
+
+	pid, err := p.Pid()
+	if err != nil {
+		p.Signal(os.Kill)
+		p.Wait()
+		return -1, err
+	}
+	// the process was started by the daemon rather than by the init process
+	// of the container, it gets the OOM score adjustment of the container
+	// only now
+	if err := setOomScoreAdj(pid, c.OomScoreAdj); err != nil {
+		p.Signal(os.Kill)
+		p.Wait()
+		return -1, err
+	}
+
 	if startCallback != nil {
-		pid, err := p.Pid()
-		if err != nil {
-			p.Signal(os.Kill)
-			p.Wait()
-			return -1, err
-		}
-		startCallback(&c.ProcessConfig, pid)
+		startCallback(processConfig, pid)
 	}
 
 	ps, err := p.Wait()
@@ -0,0 +1,125 @@
+// +build linux
+
+package native
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"syscall"
+
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/daemon/execdriver/native/seccomp"
+	"github.com/docker/docker/pkg/reexec"
+	"github.com/docker/libcontainer"
+	"github.com/syndtr/gocapability/capability"
+)
+
+// seccompInitPath is where dockerinit is mounted in containers with a
+// syscall filter. The processes of the container start through it, it loads
+// the filter before running them, see setupSeccompInit.
+const seccompInitPath = "/dev/.seccomp-init"
+
+// prSetNoNewPrivs is PR_SET_NO_NEW_PRIVS, which the syscall package lacks.
+const prSetNoNewPrivs = 38
+
+func init() {
+	reexec.Register(seccompInitPath, seccompInitializer)
+}
+
+// seccompFilter returns the syscall filter of the container c. Privileged
+// containers and containers with the "unconfined" profile run without a
+// filter, the default profile is applied if the kernel supports seccomp and
+// no profile was given. Seccomp init can't be mounted in containers with a
+// volume at /dev, they don't get the default profile and can't be given one.
+func seccompFilter(c *execdriver.Command) (*seccomp.Seccomp, error) {
+	if c.ProcessConfig.Privileged {
+		return nil, nil
+	}
+	switch c.SeccompProfile {
+	case "unconfined":
+		return nil, nil
+	case "":
+		if seccomp.IsEnabled() && !hasDevVolume(c) {
+			return seccomp.DefaultProfile(), nil
+		}
+		return nil, nil
+	}
+	if !seccomp.IsEnabled() {
+		return nil, fmt.Errorf("Seccomp is not supported by the kernel, cannot apply the seccomp profile")
+	}
+	if hasDevVolume(c) {
+		return nil, fmt.Errorf("A seccomp profile can't be applied to a container with a volume at /dev")
+	}
+	return seccomp.LoadProfile(c.SeccompProfile)
+}
+
+func hasDevVolume(c *execdriver.Command) bool {
+	for _, m := range c.Mounts {
+		if filepath.Clean(m.Destination) == "/dev" {
+			return true
+		}
+	}
+	return false
+}
+
+// setupSeccompInit makes the process p of the container c start through
+// seccomp init if the container has a syscall filter. Seccomp init runs with
+// the user and the capabilities of p.
+func setupSeccompInit(c *execdriver.Command, p *libcontainer.Process) error {
+	filter, err := seccompFilter(c)
+	if err != nil || filter == nil {
+		return err
+	}
+	data, err := json.Marshal(filter)
+	if err != nil {
+		return err
+	}
+	p.Args = append([]string{seccompInitPath, string(data), "--"}, p.Args...)
+	return nil
+}
+
+func seccompInitializer() {
+	// the filter is loaded for the calling thread only, which then runs
+	// the process
+	runtime.LockOSThread()
+	if err := seccompInit(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "seccomp init: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// seccompInit loads the filter given in args and runs the command that
+// follows it on the command line, after a "--" argument. The filter only
+// applies to the command.
+func seccompInit(args []string) error {
+	if len(args) < 3 || args[1] != "--" {
+		return fmt.Errorf("no command to run")
+	}
+	var filter seccomp.Seccomp
+	if err := json.Unmarshal([]byte(args[0]), &filter); err != nil {
+		return err
+	}
+	name, err := exec.LookPath(args[2])
+	if err != nil {
+		return err
+	}
+	caps, err := capability.NewPid(0)
+	if err != nil {
+		return err
+	}
+	// loading a filter takes CAP_SYS_ADMIN, a process without it may only
+	// load one once it can't gain privileges anymore
+	if !caps.Get(capability.EFFECTIVE, capability.CAP_SYS_ADMIN) {
+		if _, _, errno := syscall.RawSyscall6(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0, 0, 0, 0); errno != 0 {
+			return fmt.Errorf("unable to set no_new_privs: %v", errno)
+		}
+	}
+	if err := seccomp.InitSeccomp(&filter); err != nil {
+		return err
+	}
+	return syscall.Exec(name, args[2:], os.Environ())
+}
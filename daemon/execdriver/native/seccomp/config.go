@@ -0,0 +1,49 @@
+package seccomp
+
+// Action is taken when a syscall matches a seccomp rule.
+type Action int
+
+const (
+	Kill Action = iota + 1
+	Errno
+	Trap
+	Allow
+	Trace
+)
+
+// Operator compares a syscall argument with the value of a rule.
+type Operator int
+
+const (
+	EqualTo Operator = iota + 1
+	NotEqualTo
+	GreaterThan
+	GreaterThanOrEqualTo
+	LessThan
+	LessThanOrEqualTo
+	MaskEqualTo
+)
+
+// Arg is a condition on the argument at Index of a syscall. For MaskEqualTo,
+// the argument is masked with Value and compared with ValueTwo.
+type Arg struct {
+	Index    uint     `json:"index"`
+	Value    uint64   `json:"value"`
+	ValueTwo uint64   `json:"value_two"`
+	Op       Operator `json:"op"`
+}
+
+// Syscall is a seccomp rule. The action is taken when the syscall is made
+// with arguments meeting all of the conditions in Args.
+type Syscall struct {
+	Name   string `json:"name"`
+	Action Action `json:"action"`
+	Args   []*Arg `json:"args"`
+}
+
+// Seccomp is a syscall filter. The first rule a syscall matches decides
+// its action; DefaultAction is taken for syscalls no rule matches.
+type Seccomp struct {
+	DefaultAction Action     `json:"default_action"`
+	Syscalls      []*Syscall `json:"syscalls"`
+}
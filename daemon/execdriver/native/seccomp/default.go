@@ -0,0 +1,85 @@
+package seccomp
+
+// blockedSyscalls fail with EPERM in the default profile. They manage the
+// kernel and the host, reach outside the container or expose kernel
+// interfaces that are not namespaced.
+var blockedSyscalls = []string{
+	"acct",
+	"add_key",
+	"adjtimex",
+	"bpf",
+	"clock_adjtime",
+	"clock_settime",
+	"create_module",
+	"delete_module",
+	"finit_module",
+	"get_kernel_syms",
+	"get_mempolicy",
+	"init_module",
+	"ioperm",
+	"iopl",
+	"kcmp",
+	"kexec_file_load",
+	"kexec_load",
+	"keyctl",
+	"lookup_dcookie",
+	"mbind",
+	"move_pages",
+	"name_to_handle_at",
+	"nfsservctl",
+	"open_by_handle_at",
+	"perf_event_open",
+	"process_vm_readv",
+	"process_vm_writev",
+	"ptrace",
+	"query_module",
+	"quotactl",
+	"reboot",
+	"request_key",
+	"set_mempolicy",
+	"settimeofday",
+	"stime",
+	"swapon",
+	"swapoff",
+	"sysfs",
+	"_sysctl",
+	"uselib",
+	"userfaultfd",
+	"ustat",
+	"vm86",
+	"vm86old",
+}
+
+// personalities are the execution domains containers may switch to with
+// personality(2): PER_LINUX, PER_LINUX32 and the query for the current one.
+var personalities = []uint64{0x0, 0x8, 0xffffffff}
+
+// DefaultProfile returns the syscall filter applied to containers that are
+// not privileged and have no seccomp profile of their own. It allows every
+// syscall except for the ones in blockedSyscalls and personality(2) for
+// execution domains other than personalities.
+func DefaultProfile() *Seccomp {
+	config := &Seccomp{
+		DefaultAction: Allow,
+	}
+	for _, p := range personalities {
+		config.Syscalls = append(config.Syscalls, &Syscall{
+			Name:   "personality",
+			Action: Allow,
+			Args: []*Arg{
+				{Index: 0, Value: p, Op: EqualTo},
+			},
+		})
+	}
+	config.Syscalls = append(config.Syscalls, &Syscall{
+		Name:   "personality",
+		Action: Errno,
+	})
+	for _, name := range blockedSyscalls {
+		config.Syscalls = append(config.Syscalls, &Syscall{
+			Name:   name,
+			Action: Errno,
+		})
+	}
+	return config
+}
@@ -0,0 +1,105 @@
+package seccomp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// profile is the JSON representation of a syscall filter profile as given
+// with --security-opt seccomp=<profile.json>.
+type profile struct {
+	DefaultAction string            `json:"defaultAction"`
+	Syscalls      []*profileSyscall `json:"syscalls"`
+}
+
+// profileSyscall is a rule applying Action to the syscall Name when all the
+// conditions in Args hold.
+type profileSyscall struct {
+	Name   string        `json:"name"`
+	Action string        `json:"action"`
+	Args   []*profileArg `json:"args"`
+}
+
+// profileArg is a condition on the syscall argument at Index. For
+// SCMP_CMP_MASKED_EQ the argument is masked with Value and compared with
+// ValueTwo.
+type profileArg struct {
+	Index    uint   `json:"index"`
+	Value    uint64 `json:"value"`
+	ValueTwo uint64 `json:"valueTwo"`
+	Op       string `json:"op"`
+}
+
+var actions = map[string]Action{
+	"SCMP_ACT_KILL":  Kill,
+	"SCMP_ACT_ERRNO": Errno,
+	"SCMP_ACT_TRAP":  Trap,
+	"SCMP_ACT_ALLOW": Allow,
+	"SCMP_ACT_TRACE": Trace,
+}
+
+var operators = map[string]Operator{
+	"SCMP_CMP_NE":        NotEqualTo,
+	"SCMP_CMP_LT":        LessThan,
+	"SCMP_CMP_LE":        LessThanOrEqualTo,
+	"SCMP_CMP_EQ":        EqualTo,
+	"SCMP_CMP_GE":        GreaterThanOrEqualTo,
+	"SCMP_CMP_GT":        GreaterThan,
+	"SCMP_CMP_MASKED_EQ": MaskEqualTo,
+}
+
+// LoadProfile parses the JSON seccomp profile in body into a syscall filter.
+func LoadProfile(body string) (*Seccomp, error) {
+	var p profile
+	if err := json.Unmarshal([]byte(body), &p); err != nil {
+		return nil, fmt.Errorf("Decoding seccomp profile failed: %v", err)
+	}
+	return setupSeccomp(&p)
+}
+
+func setupSeccomp(p *profile) (*Seccomp, error) {
+	defaultAction, err := parseAction(p.DefaultAction)
+	if err != nil {
+		return nil, err
+	}
+	config := &Seccomp{
+		DefaultAction: defaultAction,
+	}
+	for _, s := range p.Syscalls {
+		if s.Name == "" {
+			return nil, fmt.Errorf("Seccomp rule without a syscall name")
+		}
+		action, err := parseAction(s.Action)
+		if err != nil {
+			return nil, err
+		}
+		syscall := &Syscall{
+			Name:   s.Name,
+			Action: action,
+		}
+		for _, a := range s.Args {
+			if a.Index > 5 {
+				return nil, fmt.Errorf("Invalid argument index %d for syscall %s", a.Index, s.Name)
+			}
+			op, ok := operators[a.Op]
+			if !ok {
+				return nil, fmt.Errorf("Unknown seccomp operator %q for syscall %s", a.Op, s.Name)
+			}
+			syscall.Args = append(syscall.Args, &Arg{
+				Index:    a.Index,
+				Value:    a.Value,
+				ValueTwo: a.ValueTwo,
+				Op:       op,
+			})
+		}
+		config.Syscalls = append(config.Syscalls, syscall)
+	}
+	return config, nil
+}
+
+func parseAction(action string) (Action, error) {
+	if a, ok := actions[action]; ok {
+		return a, nil
+	}
+	return 0, fmt.Errorf("Unknown seccomp action %q", action)
+}
@@ -0,0 +1,68 @@
+package seccomp
+
+import (
+	"testing"
+)
+
+func TestLoadProfile(t *testing.T) {
+	profile := `{
+	"defaultAction": "SCMP_ACT_ERRNO",
+	"syscalls": [
+		{"name": "write", "action": "SCMP_ACT_ALLOW"},
+		{"name": "personality", "action": "SCMP_ACT_ALLOW", "args": [{"index": 0, "value": 8, "op": "SCMP_CMP_EQ"}]},
+		{"name": "clone", "action": "SCMP_ACT_KILL", "args": [{"index": 0, "value": 2080505856, "valueTwo": 0, "op": "SCMP_CMP_MASKED_EQ"}]}
+	]
+}`
+	config, err := LoadProfile(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.DefaultAction != Errno {
+		t.Fatalf("Expected default action %d, got %d", Errno, config.DefaultAction)
+	}
+	if len(config.Syscalls) != 3 {
+		t.Fatalf("Expected 3 syscall rules, got %d", len(config.Syscalls))
+	}
+	if s := config.Syscalls[0]; s.Name != "write" || s.Action != Allow || len(s.Args) != 0 {
+		t.Fatalf("Unexpected rule %+v", s)
+	}
+	if a := config.Syscalls[1].Args[0]; a.Index != 0 || a.Value != 8 || a.Op != EqualTo {
+		t.Fatalf("Unexpected condition %+v", a)
+	}
+	if s := config.Syscalls[2]; s.Action != Kill || s.Args[0].Value != 2080505856 || s.Args[0].Op != MaskEqualTo {
+		t.Fatalf("Unexpected rule %+v", s)
+	}
+}
+
+func TestLoadProfileInvalid(t *testing.T) {
+	for _, profile := range []string{
+		`not json`,
+		`{"defaultAction": "SCMP_ACT_NONE"}`,
+		`{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"name": "", "action": "SCMP_ACT_KILL"}]}`,
+		`{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"name": "ptrace", "action": "SCMP_ACT_NONE"}]}`,
+		`{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"name": "ptrace", "action": "SCMP_ACT_KILL", "args": [{"index": 6, "op": "SCMP_CMP_EQ"}]}]}`,
+		`{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"name": "ptrace", "action": "SCMP_ACT_KILL", "args": [{"index": 0, "op": "SCMP_CMP_LIKE"}]}]}`,
+	} {
+		if _, err := LoadProfile(profile); err == nil {
+			t.Fatalf("Expected an error loading %s", profile)
+		}
+	}
+}
+
+func TestDefaultProfile(t *testing.T) {
+	config := DefaultProfile()
+	if config.DefaultAction != Allow {
+		t.Fatalf("Expected the default profile to allow syscalls by default, got %d", config.DefaultAction)
+	}
+	blocked := map[string]bool{}
+	for _, s := range config.Syscalls {
+		if s.Action == Errno && len(s.Args) == 0 {
+			blocked[s.Name] = true
+		}
+	}
+	for _, name := range []string{"keyctl", "add_key", "ptrace", "personality"} {
+		if !blocked[name] {
+			t.Fatalf("Expected %s to be blocked by the default profile", name)
+		}
+	}
+}
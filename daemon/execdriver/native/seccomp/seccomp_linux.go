@@ -0,0 +1,286 @@
+// +build linux,amd64
+
+package seccomp
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// from linux/seccomp.h
+	seccompModeFilter = 2
+
+	retKill  = 0x00000000
+	retTrap  = 0x00030000
+	retErrno = 0x00050000
+	retTrace = 0x7ff00000
+	retAllow = 0x7fff0000
+
+	// offsets into struct seccomp_data
+	offsetNr   = 0
+	offsetArch = 4
+	offsetArgs = 16
+
+	// syscalls of the x32 ABI have this bit set in their number
+	x32SyscallBit = 0x40000000
+
+	// from linux/filter.h
+	bpfLD  = 0x00
+	bpfJMP = 0x05
+	bpfRET = 0x06
+	bpfALU = 0x04
+	bpfW   = 0x00
+	bpfABS = 0x20
+	bpfK   = 0x00
+	bpfAND = 0x50
+	bpfJEQ = 0x10
+	bpfJGT = 0x20
+	bpfJGE = 0x30
+
+	// the maximum number of instructions of a filter, BPF_MAXINSNS
+	maxInstructions = 4096
+)
+
+// labels that conditional jumps of a rule are resolved against
+const (
+	labelNext    = 0  // fall through to the next instruction
+	labelNoMatch = -1 // the rule doesn't match, go to the next rule
+	labelCondEnd = -2 // the condition holds, go to the next condition
+)
+
+type sockFilter struct {
+	code uint16
+	jt   uint8
+	jf   uint8
+	k    uint32
+}
+
+type sockFprog struct {
+	len    uint16
+	filter *sockFilter
+}
+
+// instruction is a BPF instruction whose jumps may still be labels.
+type instruction struct {
+	code   uint16
+	jt, jf int
+	k      uint32
+}
+
+func stmt(code uint16, k uint32) instruction {
+	return instruction{code: code, k: k}
+}
+
+func jump(code uint16, k uint32, jt, jf int) instruction {
+	return instruction{code: code, jt: jt, jf: jf, k: k}
+}
+
+func loadAbs(offset uint32) instruction {
+	return stmt(bpfLD|bpfW|bpfABS, offset)
+}
+
+func ret(k uint32) instruction {
+	return stmt(bpfRET|bpfK, k)
+}
+
+// IsEnabled returns true if the kernel supports seccomp.
+func IsEnabled() bool {
+	_, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, syscall.PR_GET_SECCOMP, 0, 0)
+	return errno != syscall.EINVAL
+}
+
+// InitSeccomp loads the syscall filter described by config into the
+// calling process. The filter is inherited by the process's children and
+// across execve.
+func InitSeccomp(config *Seccomp) error {
+	if config == nil {
+		return nil
+	}
+	filter, err := compile(config)
+	if err != nil {
+		return err
+	}
+	prog := sockFprog{
+		len:    uint16(len(filter)),
+		filter: &filter[0],
+	}
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, syscall.PR_SET_SECCOMP, seccompModeFilter, uintptr(unsafe.Pointer(&prog))); errno != 0 {
+		return fmt.Errorf("unable to load seccomp filter: %v", errno)
+	}
+	return nil
+}
+
+// compile translates config into a BPF program. The rules only cover the
+// syscalls of the native architecture and ABI, the syscalls of the others
+// are given the default action, unless it allows them: they could bypass the
+// rules then, so they fail with EPERM instead.
+func compile(config *Seccomp) ([]sockFilter, error) {
+	defaultAction, err := actionValue(config.DefaultAction)
+	if err != nil {
+		return nil, err
+	}
+	foreignAction := defaultAction
+	if config.DefaultAction == Allow {
+		foreignAction, _ = actionValue(Errno)
+	}
+
+	program := []instruction{
+		loadAbs(offsetArch),
+		jump(bpfJMP|bpfJEQ|bpfK, nativeArch, 1, 0),
+		ret(foreignAction),
+		loadAbs(offsetNr),
+		jump(bpfJMP|bpfJGE|bpfK, x32SyscallBit, 0, 1),
+		ret(foreignAction),
+	}
+	for _, rule := range config.Syscalls {
+		nr, ok := syscalls[rule.Name]
+		if !ok {
+			// the syscall doesn't exist on this architecture
+			continue
+		}
+		block, err := compileRule(nr, rule)
+		if err != nil {
+			return nil, err
+		}
+		program = append(program, block...)
+	}
+	program = append(program, ret(defaultAction))
+
+	if len(program) > maxInstructions {
+		return nil, fmt.Errorf("seccomp filter has too many instructions: %d", len(program))
+	}
+	filter := make([]sockFilter, len(program))
+	for i, ins := range program {
+		if ins.jt < 0 || ins.jt > 255 || ins.jf < 0 || ins.jf > 255 {
+			return nil, fmt.Errorf("seccomp filter jump out of range")
+		}
+		filter[i] = sockFilter{code: ins.code, jt: uint8(ins.jt), jf: uint8(ins.jf), k: ins.k}
+	}
+	return filter, nil
+}
+
+// compileRule translates a rule into a block of instructions that returns
+// the action of the rule if it matches, and otherwise falls through to the
+// instructions after the block.
+func compileRule(nr uint32, rule *Syscall) ([]instruction, error) {
+	action, err := actionValue(rule.Action)
+	if err != nil {
+		return nil, err
+	}
+
+	block := []instruction{
+		loadAbs(offsetNr),
+		jump(bpfJMP|bpfJEQ|bpfK, nr, labelNext, labelNoMatch),
+	}
+	for _, arg := range rule.Args {
+		cond, err := compileArg(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid condition for syscall %s: %v", rule.Name, err)
+		}
+		block = append(block, resolve(cond, labelCondEnd, len(cond))...)
+	}
+	block = append(block, ret(action))
+
+	// a rule that doesn't match continues with the instruction after the block
+	return resolve(block, labelNoMatch, len(block)), nil
+}
+
+// resolve replaces the jumps to label in block by jumps to the instruction
+// at index target.
+func resolve(block []instruction, label, target int) []instruction {
+	for i := range block {
+		if block[i].jt == label {
+			block[i].jt = target - i - 1
+		}
+		if block[i].jf == label {
+			block[i].jf = target - i - 1
+		}
+	}
+	return block
+}
+
+// compileArg translates a condition on a 64-bit syscall argument into
+// instructions comparing its high and low 32-bit words in turn.
+func compileArg(arg *Arg) ([]instruction, error) {
+	if arg.Index > 5 {
+		return nil, fmt.Errorf("argument index %d out of range", arg.Index)
+	}
+	var (
+		lo   = uint32(offsetArgs + 8*arg.Index)
+		hi   = lo + 4
+		vlo  = uint32(arg.Value)
+		vhi  = uint32(arg.Value >> 32)
+		v2lo = uint32(arg.ValueTwo)
+		v2hi = uint32(arg.ValueTwo >> 32)
+	)
+
+	switch arg.Op {
+	case EqualTo:
+		return []instruction{
+			loadAbs(hi),
+			jump(bpfJMP|bpfJEQ|bpfK, vhi, labelNext, labelNoMatch),
+			loadAbs(lo),
+			jump(bpfJMP|bpfJEQ|bpfK, vlo, labelCondEnd, labelNoMatch),
+		}, nil
+	case NotEqualTo:
+		return []instruction{
+			loadAbs(hi),
+			jump(bpfJMP|bpfJEQ|bpfK, vhi, labelNext, labelCondEnd),
+			loadAbs(lo),
+			jump(bpfJMP|bpfJEQ|bpfK, vlo, labelNoMatch, labelCondEnd),
+		}, nil
+	case GreaterThan, GreaterThanOrEqualTo:
+		cmp := uint16(bpfJGT)
+		if arg.Op == GreaterThanOrEqualTo {
+			cmp = bpfJGE
+		}
+		return []instruction{
+			loadAbs(hi),
+			jump(bpfJMP|bpfJGT|bpfK, vhi, labelCondEnd, labelNext),
+			jump(bpfJMP|bpfJEQ|bpfK, vhi, labelNext, labelNoMatch),
+			loadAbs(lo),
+			jump(bpfJMP|cmp|bpfK, vlo, labelCondEnd, labelNoMatch),
+		}, nil
+	case LessThan, LessThanOrEqualTo:
+		// x < v is !(x >= v), and x <= v is !(x > v)
+		cmp := uint16(bpfJGE)
+		if arg.Op == LessThanOrEqualTo {
+			cmp = bpfJGT
+		}
+		return []instruction{
+			loadAbs(hi),
+			jump(bpfJMP|bpfJGT|bpfK, vhi, labelNoMatch, labelNext),
+			jump(bpfJMP|bpfJEQ|bpfK, vhi, labelNext, labelCondEnd),
+			loadAbs(lo),
+			jump(bpfJMP|cmp|bpfK, vlo, labelNoMatch, labelCondEnd),
+		}, nil
+	case MaskEqualTo:
+		return []instruction{
+			loadAbs(hi),
+			stmt(bpfALU|bpfAND|bpfK, vhi),
+			jump(bpfJMP|bpfJEQ|bpfK, v2hi, labelNext, labelNoMatch),
+			loadAbs(lo),
+			stmt(bpfALU|bpfAND|bpfK, vlo),
+			jump(bpfJMP|bpfJEQ|bpfK, v2lo, labelCondEnd, labelNoMatch),
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown operator %d", arg.Op)
+}
+
+func actionValue(action Action) (uint32, error) {
+	switch action {
+	case Kill:
+		return retKill, nil
+	case Errno:
+		return retErrno | uint32(syscall.EPERM), nil
+	case Trap:
+		return retTrap, nil
+	case Allow:
+		return retAllow, nil
+	case Trace:
+		return retTrace, nil
+	}
+	return 0, fmt.Errorf("unknown seccomp action %d", action)
+}
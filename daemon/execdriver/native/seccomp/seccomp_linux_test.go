@@ -0,0 +1,141 @@
+// +build linux,amd64
+
+package seccomp
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// run interprets filter for a syscall of arch with number nr and args the
+// way the kernel does and returns the filter's verdict.
+func run(t *testing.T, filter []sockFilter, arch, nr uint32, args ...uint64) uint32 {
+	data := make([]byte, offsetArgs+6*8)
+	binary.LittleEndian.PutUint32(data[offsetNr:], nr)
+	binary.LittleEndian.PutUint32(data[offsetArch:], arch)
+	for i, a := range args {
+		binary.LittleEndian.PutUint64(data[offsetArgs+8*i:], a)
+	}
+	var acc uint32
+	for pc := 0; pc < len(filter); pc++ {
+		ins := filter[pc]
+		switch ins.code {
+		case bpfLD | bpfW | bpfABS:
+			acc = binary.LittleEndian.Uint32(data[ins.k:])
+		case bpfALU | bpfAND | bpfK:
+			acc &= ins.k
+		case bpfJMP | bpfJEQ | bpfK, bpfJMP | bpfJGT | bpfK, bpfJMP | bpfJGE | bpfK:
+			var cond bool
+			switch ins.code &^ (bpfJMP | bpfK) {
+			case bpfJEQ:
+				cond = acc == ins.k
+			case bpfJGT:
+				cond = acc > ins.k
+			case bpfJGE:
+				cond = acc >= ins.k
+			}
+			if cond {
+				pc += int(ins.jt)
+			} else {
+				pc += int(ins.jf)
+			}
+		case bpfRET | bpfK:
+			return ins.k
+		default:
+			t.Fatalf("unexpected instruction %#x at %d", ins.code, pc)
+		}
+	}
+	t.Fatal("filter did not return")
+	return 0
+}
+
+func TestCompile(t *testing.T) {
+	config := &Seccomp{
+		DefaultAction: Allow,
+		Syscalls: []*Syscall{
+			{Name: "ptrace", Action: Errno},
+			{Name: "personality", Action: Allow, Args: []*Arg{{Index: 0, Value: 8, Op: EqualTo}}},
+			{Name: "personality", Action: Kill},
+			{Name: "kill", Action: Trap, Args: []*Arg{
+				{Index: 0, Value: 1 << 32, Op: GreaterThanOrEqualTo},
+				{Index: 1, Value: 10, Op: LessThan},
+			}},
+			{Name: "clone", Action: Errno, Args: []*Arg{{Index: 0, Value: 0xf0, ValueTwo: 0x10, Op: MaskEqualTo}}},
+			{Name: "no_such_syscall", Action: Kill},
+		},
+	}
+	filter, err := compile(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errno := uint32(retErrno | 1)
+	for _, c := range []struct {
+		arch, nr uint32
+		args     []uint64
+		expected uint32
+	}{
+		{nativeArch, syscalls["ptrace"], nil, errno},
+		{nativeArch, syscalls["read"], nil, retAllow},
+		{nativeArch, syscalls["personality"], []uint64{8}, retAllow},
+		{nativeArch, syscalls["personality"], []uint64{8 | 1<<32}, retKill},
+		{nativeArch, syscalls["personality"], []uint64{0}, retKill},
+		{nativeArch, syscalls["kill"], []uint64{1 << 32, 9}, retTrap},
+		{nativeArch, syscalls["kill"], []uint64{2 << 32, 0}, retTrap},
+		{nativeArch, syscalls["kill"], []uint64{1<<32 - 1, 9}, retAllow},
+		{nativeArch, syscalls["kill"], []uint64{1 << 32, 10}, retAllow},
+		{nativeArch, syscalls["kill"], []uint64{1 << 32, 1 << 32}, retAllow},
+		{nativeArch, syscalls["clone"], []uint64{0x1f}, errno},
+		{nativeArch, syscalls["clone"], []uint64{0x2f}, retAllow},
+	} {
+		if v := run(t, filter, c.arch, c.nr, c.args...); v != c.expected {
+			t.Fatalf("syscall %d with args %v: expected %#x, got %#x", c.nr, c.args, c.expected, v)
+		}
+	}
+}
+
+func TestCompileForeignSyscalls(t *testing.T) {
+	const archI386 = 0x40000003
+	errno := uint32(retErrno | 1)
+	for _, c := range []struct {
+		defaultAction Action
+		expected      uint32
+	}{
+		// syscalls of other ABIs would get past the rules
+		{Allow, errno},
+		{Kill, retKill},
+		{Trap, retTrap},
+	} {
+		config := &Seccomp{
+			DefaultAction: c.defaultAction,
+			Syscalls:      []*Syscall{{Name: "read", Action: Allow}},
+		}
+		filter, err := compile(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, nr := range []uint32{syscalls["read"], syscalls["ptrace"]} {
+			if v := run(t, filter, archI386, nr); v != c.expected {
+				t.Fatalf("i386 syscall %d with default action %d: expected %#x, got %#x", nr, c.defaultAction, c.expected, v)
+			}
+			if v := run(t, filter, nativeArch, nr|x32SyscallBit); v != c.expected {
+				t.Fatalf("x32 syscall %d with default action %d: expected %#x, got %#x", nr, c.defaultAction, c.expected, v)
+			}
+		}
+		if v := run(t, filter, nativeArch, syscalls["read"]); v != retAllow {
+			t.Fatalf("Expected read to be allowed with default action %d, got %#x", c.defaultAction, v)
+		}
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	for _, config := range []*Seccomp{
+		{},
+		{DefaultAction: Allow, Syscalls: []*Syscall{{Name: "ptrace"}}},
+		{DefaultAction: Allow, Syscalls: []*Syscall{{Name: "ptrace", Action: Kill, Args: []*Arg{{Index: 6, Op: EqualTo}}}}},
+		{DefaultAction: Allow, Syscalls: []*Syscall{{Name: "ptrace", Action: Kill, Args: []*Arg{{Index: 0}}}}},
+	} {
+		if _, err := compile(config); err == nil {
+			t.Fatalf("Expected an error compiling %+v", config)
+		}
+	}
+}
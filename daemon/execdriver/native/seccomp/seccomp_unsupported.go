@@ -0,0 +1,22 @@
+// +build !linux !amd64
+
+package seccomp
+
+import (
+	"fmt"
+)
+
+// IsEnabled returns false, seccomp filters are not supported on this
+// platform.
+func IsEnabled() bool {
+	return false
+}
+
+// InitSeccomp returns an error if config is set since syscall filters are
+// not supported on this platform.
+func InitSeccomp(config *Seccomp) error {
+	if config != nil {
+		return fmt.Errorf("seccomp filters are not supported on this platform")
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+// +build linux
+
+package native
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/daemon/execdriver/native/seccomp"
+	"github.com/docker/libcontainer"
+	"github.com/docker/libcontainer/configs"
+)
+
+func TestSetupSeccompInit(t *testing.T) {
+	if !seccomp.IsEnabled() {
+		t.Skip("seccomp is not supported by the kernel")
+	}
+	c := &execdriver.Command{SeccompProfile: `{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"name": "mkdir", "action": "SCMP_ACT_ERRNO"}]}`}
+	p := &libcontainer.Process{
+		Args:         []string{"sh"},
+		Env:          []string{"PATH=/bin"},
+		User:         "daemon",
+		Capabilities: []string{"CHOWN", "KILL"},
+	}
+	if err := setupSeccompInit(c, p); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Args) != 4 || p.Args[0] != seccompInitPath || p.Args[2] != "--" || p.Args[3] != "sh" {
+		t.Fatalf("Expected sh to run through seccomp init, got %v", p.Args)
+	}
+	if p.User != "daemon" || len(p.Capabilities) != 2 {
+		t.Fatalf("Expected seccomp init to run with the user and the capabilities of sh, got %q %v", p.User, p.Capabilities)
+	}
+
+	var filter seccomp.Seccomp
+	if err := json.Unmarshal([]byte(p.Args[1]), &filter); err != nil {
+		t.Fatal(err)
+	}
+	if len(filter.Syscalls) != 1 || filter.Syscalls[0].Action != seccomp.Errno {
+		t.Fatalf("Unexpected filter %+v", filter)
+	}
+}
+
+func TestSetupSeccompInitWithoutFilter(t *testing.T) {
+	for _, c := range []*execdriver.Command{
+		{SeccompProfile: "unconfined"},
+		{ProcessConfig: execdriver.ProcessConfig{Privileged: true}},
+	} {
+		p := &libcontainer.Process{Args: []string{"sh"}}
+		if err := setupSeccompInit(c, p); err != nil {
+			t.Fatal(err)
+		}
+		if len(p.Args) != 1 || p.Capabilities != nil {
+			t.Fatalf("Expected the process to run without seccomp init, got %v", p.Args)
+		}
+	}
+}
+
+func TestSetupSeccompWithDevVolume(t *testing.T) {
+	if !seccomp.IsEnabled() {
+		t.Skip("seccomp is not supported by the kernel")
+	}
+	d := &driver{initPath: "/usr/lib/docker/dockerinit"}
+	c := &execdriver.Command{Mounts: []execdriver.Mount{{Source: "/dev", Destination: "/dev/", Writable: true}}}
+	container := &configs.Config{}
+	if err := d.setupSeccomp(container, c); err != nil {
+		t.Fatal(err)
+	}
+	if len(container.Mounts) != 0 {
+		t.Fatalf("Expected seccomp init not to be mounted in the /dev of the host, got %v", container.Mounts)
+	}
+	p := &libcontainer.Process{Args: []string{"sh"}}
+	if err := setupSeccompInit(c, p); err != nil || len(p.Args) != 1 {
+		t.Fatalf("Expected the process to run without the default profile, got %v (%v)", p.Args, err)
+	}
+
+	c.SeccompProfile = `{"defaultAction": "SCMP_ACT_ALLOW"}`
+	if err := d.setupSeccomp(container, c); err == nil {
+		t.Fatal("Expected an error applying a profile to a container with a volume at /dev")
+	}
+}
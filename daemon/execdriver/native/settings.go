@@ -0,0 +1,370 @@
+// +build linux,cgo
+
+package native
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/opts"
+	"github.com/docker/docker/pkg/reexec"
+	"github.com/docker/libcontainer"
+	"github.com/docker/libcontainer/cgroups"
+	"github.com/docker/libcontainer/cgroups/fs"
+	"github.com/docker/libcontainer/cgroups/systemd"
+	"github.com/docker/libcontainer/configs"
+	"github.com/docker/libcontainer/system"
+)
+
+// settings are the settings of a container that libcontainer doesn't
+// support. They are applied by the cgroup manager of the container when its
+// init process joins its cgroups, before the process of the container runs.
+type settings struct {
+	KernelMemory                 int64                        `json:"kernel_memory"`
+	MemorySwappiness             *int64                       `json:"memory_swappiness"`
+	PidsLimit                    int64                        `json:"pids_limit"`
+	BlkioWeightDevice            []*execdriver.WeightDevice   `json:"blkio_weight_device"`
+	BlkioThrottleReadBpsDevice   []*execdriver.ThrottleDevice `json:"blkio_throttle_read_bps_device"`
+	BlkioThrottleWriteBpsDevice  []*execdriver.ThrottleDevice `json:"blkio_throttle_write_bps_device"`
+	BlkioThrottleReadIOPSDevice  []*execdriver.ThrottleDevice `json:"blkio_throttle_read_iops_device"`
+	BlkioThrottleWriteIOPSDevice []*execdriver.ThrottleDevice `json:"blkio_throttle_write_iops_device"`
+	OomScoreAdj                  int                          `json:"oom_score_adj"`
+	Sysctls                      map[string]string            `json:"sysctls"`
+}
+
+// newSettings returns the settings of the container c.
+func newSettings(c *execdriver.Command) *settings {
+	s := &settings{
+		OomScoreAdj: c.OomScoreAdj,
+		Sysctls:     c.Sysctls,
+	}
+	if r := c.Resources; r != nil {
+		s.KernelMemory = r.KernelMemory
+		s.MemorySwappiness = r.MemorySwappiness
+		s.PidsLimit = r.PidsLimit
+		s.BlkioWeightDevice = r.BlkioWeightDevice
+		s.BlkioThrottleReadBpsDevice = r.BlkioThrottleReadBpsDevice
+		s.BlkioThrottleWriteBpsDevice = r.BlkioThrottleWriteBpsDevice
+		s.BlkioThrottleReadIOPSDevice = r.BlkioThrottleReadIOPSDevice
+		s.BlkioThrottleWriteIOPSDevice = r.BlkioThrottleWriteIOPSDevice
+	}
+	return s
+}
+
+// newFactory returns a libcontainer factory for the containers in root. The
+// containers it creates get the settings s, s is nil for a factory that only
+// loads containers.
+func newFactory(root string, s *settings) (libcontainer.Factory, error) {
+	withSettings := func(l *libcontainer.LinuxFactory) error {
+		l.NewCgroupsManager = func(config *configs.Cgroup, paths map[string]string) cgroups.Manager {
+			return newCgroupManager(config, paths, s)
+		}
+		return nil
+	}
+	return libcontainer.New(root, withSettings, libcontainer.InitPath(reexec.Self(), DriverName))
+}
+
+// newCgroupManager returns the cgroup manager of a container with the cgroup
+// config, which applies the settings s, and that joined the cgroups in paths
+// if it runs already.
+func newCgroupManager(config *configs.Cgroup, paths map[string]string, s *settings) cgroups.Manager {
+	m := &cgroupManager{
+		config:     config,
+		settings:   s,
+		systemd:    systemd.UseSystemd(),
+		mountpoint: cgroups.FindCgroupMountpoint,
+	}
+	if m.systemd {
+		m.Manager = &systemd.Manager{Cgroups: config, Paths: paths}
+	} else {
+		m.Manager = &fs.Manager{Cgroups: config, Paths: paths}
+	}
+	return m
+}
+
+// cgroupManager applies the settings of a container on top of the cgroup
+// manager of libcontainer.
+type cgroupManager struct {
+	cgroups.Manager
+	config   *configs.Cgroup
+	settings *settings
+	systemd  bool
+	// mountpoint finds where the hierarchy of a subsystem is mounted
+	mountpoint func(subsystem string) (string, error)
+	// pidsPath is the pids cgroup the container joined, the manager of
+	// libcontainer doesn't know about
+	pidsPath string
+}
+
+func (m *cgroupManager) Apply(pid int) error {
+	s := m.settings
+	if s == nil {
+		return m.Manager.Apply(pid)
+	}
+
+	if s.KernelMemory != 0 {
+		// kernel memory accounting can only be enabled before the first
+		// task joins the cgroup, the daemon rejects kernel memory limits
+		// with systemd, which creates the cgroup as the task joins it
+		if m.systemd {
+			return fmt.Errorf("Cannot set a kernel memory limit with the systemd cgroup manager")
+		}
+		path, err := m.memoryPath()
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return err
+		}
+		if err := writeFile(path, "memory.kmem.limit_in_bytes", strconv.FormatInt(s.KernelMemory, 10)); err != nil {
+			return err
+		}
+	}
+
+	if err := m.Manager.Apply(pid); err != nil {
+		return err
+	}
+	if err := m.apply(pid); err != nil {
+		m.Destroy()
+		return err
+	}
+	return nil
+}
+
+func (m *cgroupManager) GetPaths() map[string]string {
+	if m.pidsPath == "" {
+		return m.Manager.GetPaths()
+	}
+	paths := map[string]string{"pids": m.pidsPath}
+	for subsystem, path := range m.Manager.GetPaths() {
+		paths[subsystem] = path
+	}
+	return paths
+}
+
+func (m *cgroupManager) Destroy() error {
+	err := m.Manager.Destroy()
+	if m.pidsPath != "" {
+		if perr := cgroups.RemovePaths(map[string]string{"pids": m.pidsPath}); err == nil {
+			err = perr
+		}
+	}
+	return err
+}
+
+// apply applies the settings to the process pid once it joined the cgroups
+// of the container.
+func (m *cgroupManager) apply(pid int) error {
+	s := m.settings
+	if m.systemd {
+		// systemd only sets the memory limit
+		if m.config.MemoryReservation != 0 {
+			if err := m.writeFile("memory", "memory.soft_limit_in_bytes", strconv.FormatInt(m.config.MemoryReservation, 10)); err != nil {
+				return err
+			}
+		}
+		if m.config.OomKillDisable {
+			if err := m.writeFile("memory", "memory.oom_control", "1"); err != nil {
+				return err
+			}
+		}
+	}
+	if s.MemorySwappiness != nil {
+		if err := m.writeFile("memory", "memory.swappiness", strconv.FormatInt(*s.MemorySwappiness, 10)); err != nil {
+			return err
+		}
+	}
+
+	if err := m.joinPids(pid); err != nil {
+		return err
+	}
+
+	// libcontainer only sets the weight of all the devices
+	for _, wd := range s.BlkioWeightDevice {
+		if err := m.writeFile("blkio", "blkio.weight_device", wd.String()); err != nil {
+			return err
+		}
+	}
+	for file, devices := range map[string][]*execdriver.ThrottleDevice{
+		"blkio.throttle.read_bps_device":   s.BlkioThrottleReadBpsDevice,
+		"blkio.throttle.write_bps_device":  s.BlkioThrottleWriteBpsDevice,
+		"blkio.throttle.read_iops_device":  s.BlkioThrottleReadIOPSDevice,
+		"blkio.throttle.write_iops_device": s.BlkioThrottleWriteIOPSDevice,
+	} {
+		for _, td := range devices {
+			if err := m.writeFile("blkio", file, td.String()); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := setSysctls(pid, s.Sysctls); err != nil {
+		return err
+	}
+	return setOomScoreAdj(pid, s.OomScoreAdj)
+}
+
+// joinPids moves the process pid into the pids cgroup of the container,
+// which libcontainer doesn't support, if the hierarchy is mounted. The
+// cgroup is at the same place in the pids hierarchy as the cgroups that the
+// manager of libcontainer made in the others.
+func (m *cgroupManager) joinPids(pid int) error {
+	limit := m.settings.PidsLimit
+	path, err := m.pidsCgroupPath()
+	if err != nil {
+		if cgroups.IsNotFound(err) && limit == 0 {
+			return nil
+		}
+		return err
+	}
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return err
+	}
+	m.pidsPath = path
+	if err := setPidsLimit(path, limit); err != nil {
+		return err
+	}
+	return writeFile(path, "cgroup.procs", strconv.Itoa(pid))
+}
+
+// pidsCgroupPath returns the path of the pids cgroup of the container,
+// from the path of its cgroup in another hierarchy.
+func (m *cgroupManager) pidsCgroupPath() (string, error) {
+	mnt, err := m.mountpoint("pids")
+	if err != nil {
+		return "", err
+	}
+	for subsystem, path := range m.Manager.GetPaths() {
+		subsystemMnt, err := m.mountpoint(subsystem)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(subsystemMnt, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		return filepath.Join(mnt, rel), nil
+	}
+	return "", fmt.Errorf("the container joined no cgroup to find its pids cgroup from")
+}
+
+// setPidsLimit sets the limit of the pids cgroup at path, a negative limit
+// lifts it. The cgroup keeps its limit if limit is 0.
+func setPidsLimit(path string, limit int64) error {
+	if limit == 0 {
+		return nil
+	}
+	max := "max"
+	if limit > 0 {
+		max = strconv.FormatInt(limit, 10)
+	}
+	return writeFile(path, "pids.max", max)
+}
+
+// memoryPath returns the path of the memory cgroup of the container, as
+// the fs cgroup manager of libcontainer makes it, before it does.
+func (m *cgroupManager) memoryPath() (string, error) {
+	mnt, err := m.mountpoint("memory")
+	if err != nil {
+		return "", err
+	}
+	cgroup := m.config.Name
+	if m.config.Parent != "" {
+		cgroup = filepath.Join(m.config.Parent, cgroup)
+	}
+	if filepath.IsAbs(cgroup) {
+		root, err := cgroups.FindCgroupMountpointDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(root, "memory", cgroup), nil
+	}
+	initPath, err := cgroups.GetInitCgroupDir("memory")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(mnt, initPath, cgroup), nil
+}
+
+// writeFile writes data to file in the cgroup the container joined in the
+// hierarchy of subsystem.
+func (m *cgroupManager) writeFile(subsystem, file, data string) error {
+	path, ok := m.Manager.GetPaths()[subsystem]
+	if !ok {
+		return fmt.Errorf("the %s cgroup hierarchy is not mounted", subsystem)
+	}
+	return writeFile(path, file, data)
+}
+
+func writeFile(dir, file, data string) error {
+	return ioutil.WriteFile(filepath.Join(dir, file), []byte(data), 0700)
+}
+
+// setOomScoreAdj sets the OOM score adjustment of the process pid, which its
+// children inherit. The process keeps the one of its parent if score is 0.
+func setOomScoreAdj(pid, score int) error {
+	if score == 0 {
+		return nil
+	}
+	return ioutil.WriteFile(fmt.Sprintf("/proc/%d/oom_score_adj", pid), []byte(strconv.Itoa(score)), 0644)
+}
+
+// setSysctls writes the kernel parameters sysctls, which belong to the
+// network or the IPC namespace, in the namespaces of the process pid.
+func setSysctls(pid int, sysctls map[string]string) error {
+	for key, value := range sysctls {
+		ns := "ipc"
+		if opts.IsNetSysctl(key) {
+			ns = "net"
+		}
+		err := inNamespace(pid, ns, func() error {
+			return ioutil.WriteFile(filepath.Join("/proc/sys", strings.Replace(key, ".", "/", -1)), []byte(value), 0644)
+		})
+		if err != nil {
+			return fmt.Errorf("error setting sysctl %s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// inNamespace runs fn with the calling thread switched into the namespace ns,
+// like "net", of the process pid. The thread stays locked if it can't switch
+// back, so that no other goroutine runs in the namespace of the process.
+func inNamespace(pid int, ns string, fn func() error) error {
+	runtime.LockOSThread()
+	stuck := false
+	defer func() {
+		if !stuck {
+			runtime.UnlockOSThread()
+		}
+	}()
+
+	origns, err := os.Open(fmt.Sprintf("/proc/%d/task/%d/ns/%s", os.Getpid(), syscall.Gettid(), ns))
+	if err != nil {
+		return err
+	}
+	defer origns.Close()
+
+	f, err := os.Open(fmt.Sprintf("/proc/%d/ns/%s", pid, ns))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := system.Setns(f.Fd(), 0); err != nil {
+		return fmt.Errorf("Unable to enter %s namespace of process %d: %v", ns, pid, err)
+	}
+	err = fn()
+	if serr := system.Setns(origns.Fd(), 0); serr != nil {
+		stuck = true
+		return fmt.Errorf("Unable to leave %s namespace of process %d: %v", ns, pid, serr)
+	}
+	return err
+}
@@ -0,0 +1,210 @@
+// +build linux,cgo
+
+package native
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/libcontainer/cgroups"
+	"github.com/docker/libcontainer/configs"
+)
+
+// fakeManager is a cgroup manager whose cgroups are the directories in paths.
+type fakeManager struct {
+	cgroups.Manager
+	paths map[string]string
+}
+
+func (m *fakeManager) Apply(pid int) error {
+	return nil
+}
+
+func (m *fakeManager) GetPaths() map[string]string {
+	return m.paths
+}
+
+func (m *fakeManager) Destroy() error {
+	return nil
+}
+
+func newTestManager(t *testing.T, config *configs.Cgroup, s *settings, subsystems ...string) (*cgroupManager, string) {
+	dir, err := ioutil.TempDir("", "docker-cgroups")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the hierarchies are mounted in dir, the manager of libcontainer
+	// doesn't join the pids cgroup
+	paths := make(map[string]string)
+	for _, subsystem := range subsystems {
+		path := filepath.Join(dir, subsystem)
+		if err := os.Mkdir(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if subsystem != "pids" {
+			paths[subsystem] = path
+		}
+	}
+	mountpoint := func(subsystem string) (string, error) {
+		path := filepath.Join(dir, subsystem)
+		if _, err := os.Stat(path); err != nil {
+			return "", cgroups.NewNotFoundError(subsystem)
+		}
+		return path, nil
+	}
+	return &cgroupManager{
+		Manager:    &fakeManager{paths: paths},
+		config:     config,
+		settings:   s,
+		systemd:    true,
+		mountpoint: mountpoint,
+	}, dir
+}
+
+func readCgroupFile(t *testing.T, dir, subsystem, file string) string {
+	data, err := ioutil.ReadFile(filepath.Join(dir, subsystem, file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestCgroupManagerApplyMemory(t *testing.T) {
+	swappiness := int64(10)
+	config := &configs.Cgroup{MemoryReservation: 33554432, OomKillDisable: true}
+	m, dir := newTestManager(t, config, &settings{MemorySwappiness: &swappiness}, "memory")
+	defer os.RemoveAll(dir)
+
+	if err := m.Apply(os.Getpid()); err != nil {
+		t.Fatal(err)
+	}
+	for file, expected := range map[string]string{
+		"memory.soft_limit_in_bytes": "33554432",
+		"memory.oom_control":         "1",
+		"memory.swappiness":          "10",
+	} {
+		if value := readCgroupFile(t, dir, "memory", file); value != expected {
+			t.Fatalf("Expected %s to be %s, got %s", file, expected, value)
+		}
+	}
+}
+
+func TestCgroupManagerApplyKernelMemoryWithSystemd(t *testing.T) {
+	m, dir := newTestManager(t, &configs.Cgroup{}, &settings{KernelMemory: 8388608}, "memory")
+	defer os.RemoveAll(dir)
+
+	if err := m.Apply(os.Getpid()); err == nil {
+		t.Fatal("Expected an error for a kernel memory limit with systemd")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "memory", "memory.kmem.limit_in_bytes")); !os.IsNotExist(err) {
+		t.Fatalf("Expected memory.kmem.limit_in_bytes not to be written, got %v", err)
+	}
+}
+
+func TestCgroupManagerApplyBlkio(t *testing.T) {
+	s := &settings{
+		BlkioWeightDevice:            []*execdriver.WeightDevice{{Major: 8, Minor: 0, Weight: 500}},
+		BlkioThrottleReadBpsDevice:   []*execdriver.ThrottleDevice{{Major: 8, Minor: 0, Rate: 2048}},
+		BlkioThrottleWriteIOPSDevice: []*execdriver.ThrottleDevice{{Major: 8, Minor: 16, Rate: 100}},
+	}
+	m, dir := newTestManager(t, &configs.Cgroup{}, s, "blkio")
+	defer os.RemoveAll(dir)
+
+	if err := m.Apply(os.Getpid()); err != nil {
+		t.Fatal(err)
+	}
+	for file, expected := range map[string]string{
+		"blkio.weight_device":              "8:0 500",
+		"blkio.throttle.read_bps_device":   "8:0 2048",
+		"blkio.throttle.write_iops_device": "8:16 100",
+	} {
+		if value := readCgroupFile(t, dir, "blkio", file); value != expected {
+			t.Fatalf("Expected %s to be %s, got %s", file, expected, value)
+		}
+	}
+	for _, file := range []string{"blkio.throttle.write_bps_device", "blkio.throttle.read_iops_device"} {
+		if _, err := os.Stat(filepath.Join(dir, "blkio", file)); !os.IsNotExist(err) {
+			t.Fatalf("Expected %s not to be written, got %v", file, err)
+		}
+	}
+}
+
+func TestCgroupManagerApplyWithoutHierarchy(t *testing.T) {
+	swappiness := int64(10)
+	m, dir := newTestManager(t, &configs.Cgroup{}, &settings{MemorySwappiness: &swappiness})
+	defer os.RemoveAll(dir)
+
+	if err := m.Apply(os.Getpid()); err == nil {
+		t.Fatal("Expected an error for a memory swappiness without memory cgroup")
+	}
+}
+
+func TestCgroupManagerApplyPids(t *testing.T) {
+	m, dir := newTestManager(t, &configs.Cgroup{}, &settings{PidsLimit: 100}, "memory", "pids")
+	defer os.RemoveAll(dir)
+
+	if err := m.Apply(os.Getpid()); err != nil {
+		t.Fatal(err)
+	}
+	if m.pidsPath != filepath.Join(dir, "pids") {
+		t.Fatalf("Expected the pids cgroup next to the memory cgroup, got %s", m.pidsPath)
+	}
+	for file, expected := range map[string]string{
+		"pids.max":     "100",
+		"cgroup.procs": strconv.Itoa(os.Getpid()),
+	} {
+		if value := readCgroupFile(t, dir, "pids", file); value != expected {
+			t.Fatalf("Expected %s to be %s, got %s", file, expected, value)
+		}
+	}
+}
+
+func TestCgroupManagerApplyPidsWithoutHierarchy(t *testing.T) {
+	m, dir := newTestManager(t, &configs.Cgroup{}, &settings{PidsLimit: 100}, "memory")
+	defer os.RemoveAll(dir)
+
+	if err := m.Apply(os.Getpid()); err == nil {
+		t.Fatal("Expected an error for a pids limit without pids cgroup")
+	}
+}
+
+func TestSetPidsLimit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-cgroups")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, test := range []struct {
+		limit    int64
+		expected string
+	}{
+		{1024, "1024"},
+		{0, "1024"},
+		{-1, "max"},
+	} {
+		if err := setPidsLimit(dir, test.limit); err != nil {
+			t.Fatal(err)
+		}
+		if value := readCgroupFile(t, dir, "", "pids.max"); value != test.expected {
+			t.Fatalf("Expected pids.max to be %s for a limit of %d, got %s", test.expected, test.limit, value)
+		}
+	}
+}
+
+func TestCgroupManagerGetPaths(t *testing.T) {
+	m, dir := newTestManager(t, &configs.Cgroup{}, &settings{}, "memory")
+	defer os.RemoveAll(dir)
+
+	if paths := m.GetPaths(); len(paths) != 1 || paths["memory"] == "" {
+		t.Fatalf("Expected the memory cgroup only, got %v", paths)
+	}
+	m.pidsPath = filepath.Join(dir, "pids")
+	if paths := m.GetPaths(); len(paths) != 2 || paths["memory"] == "" || paths["pids"] != m.pidsPath {
+		t.Fatalf("Expected the memory and pids cgroups, got %v", paths)
+	}
+}
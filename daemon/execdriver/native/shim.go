@@ -0,0 +1,684 @@
+// +build linux,cgo
+
+package native
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/daemon/networkdriver/sandbox"
+	"github.com/docker/docker/pkg/reexec"
+	"github.com/docker/docker/pkg/term"
+	"github.com/docker/libcontainer"
+	"github.com/docker/libcontainer/configs"
+	"github.com/docker/libcontainer/system"
+	"github.com/docker/libcontainer/utils"
+)
+
+// In live restore mode the init process of a container is the child of a
+// shim instead of the daemon. The shim passes the stdio of the container
+// through FIFOs, keeps its output while the daemon is down and records its
+// exit status, so that the container keeps running while the daemon is down
+// and the daemon can reattach to it when it starts again.
+const shimName = "docker-shim"
+
+// The files in the directory of the shim of a container.
+const (
+	shimSpecFile   = "spec.json"
+	shimPidFile    = "pid"
+	shimStatusFile = "status.json"
+	shimStdin      = "stdin"
+	shimStdout     = "stdout"
+	shimStderr     = "stderr"
+	shimResize     = "resize"
+	// shimExit is held open by the shim until it exits
+	shimExit = "exit"
+	// shimLogSuffix is appended to the name of the FIFO of an output stream
+	// for the file that keeps the output the daemon didn't read before the
+	// container exited
+	shimLogSuffix = ".log"
+)
+
+const (
+	// shimOutputSize is the most output of a stream the shim keeps while
+	// the daemon doesn't read it, the oldest output is dropped beyond it
+	shimOutputSize = 1 << 20
+	// shimOutputRetry is how often the shim checks if the daemon came back
+	// to read the output it keeps
+	shimOutputRetry = 100 * time.Millisecond
+)
+
+// shimSpec is what the shim needs to create a container and start its init
+// process.
+type shimSpec struct {
+	ID           string          `json:"id"`
+	Root         string          `json:"root"`
+	Config       *configs.Config `json:"config"`
+	Args         []string        `json:"args"`
+	Env          []string        `json:"env"`
+	Cwd          string          `json:"cwd"`
+	User         string          `json:"user"`
+	Capabilities []string        `json:"capabilities"`
+	Tty          bool            `json:"tty"`
+	OpenStdin    bool            `json:"open_stdin"`
+	Settings     *settings       `json:"settings"`
+}
+
+func init() {
+	reexec.Register(shimName, shim)
+}
+
+// shim is the main function of the shim, which is started with the directory
+// holding the spec and the FIFOs of the container. It reports the errors to
+// start the container on its stdout, and closes it once the container runs.
+func shim() {
+	if len(os.Args) != 2 {
+		fmt.Fprintf(os.Stdout, "usage: %s DIR", shimName)
+		os.Exit(1)
+	}
+	if err := supervise(os.Args[1]); err != nil {
+		fmt.Fprint(os.Stdout, err)
+		os.Exit(1)
+	}
+}
+
+// supervise starts the container of the shim in dir and waits for it to
+// exit, recording its exit status.
+func supervise(dir string) error {
+	var spec shimSpec
+	data, err := ioutil.ReadFile(filepath.Join(dir, shimSpecFile))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return err
+	}
+
+	factory, err := newFactory(spec.Root, spec.Settings)
+	if err != nil {
+		return err
+	}
+
+	// the container writes its output to the shim, which never blocks it,
+	// rather than to the FIFOs, which fill up while the daemon is down
+	stdout := newShimOutput(filepath.Join(dir, shimStdout))
+	stderr := newShimOutput(filepath.Join(dir, shimStderr))
+	go stdout.forward()
+	go stderr.forward()
+
+	exit, err := os.OpenFile(filepath.Join(dir, shimExit), os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer exit.Close()
+
+	p := &libcontainer.Process{
+		Args:         spec.Args,
+		Env:          spec.Env,
+		Cwd:          spec.Cwd,
+		User:         spec.User,
+		Capabilities: spec.Capabilities,
+	}
+	if spec.Tty {
+		rootuid, err := spec.Config.HostUID()
+		if err != nil {
+			return err
+		}
+		console, err := p.NewConsole(rootuid)
+		if err != nil {
+			return err
+		}
+		defer console.Close()
+		resize, err := os.OpenFile(filepath.Join(dir, shimResize), os.O_RDWR, 0)
+		if err != nil {
+			return err
+		}
+		defer resize.Close()
+		go io.Copy(stdout, console)
+		go resizeConsole(console, resize)
+		if spec.OpenStdin {
+			go copyStdin(dir, console)
+		}
+	} else {
+		p.Stdout = stdout
+		p.Stderr = stderr
+		if spec.OpenStdin {
+			r, w, err := os.Pipe()
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+			p.Stdin = r
+			go func() {
+				copyStdin(dir, w)
+				w.Close()
+			}()
+		}
+	}
+
+	cont, err := factory.Create(spec.ID, spec.Config)
+	if err != nil {
+		return err
+	}
+	if err := cont.Start(p); err != nil {
+		cont.Destroy()
+		return err
+	}
+	pid, err := p.Pid()
+	if err != nil {
+		p.Signal(os.Kill)
+		p.Wait()
+		cont.Destroy()
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, shimPidFile), []byte(strconv.Itoa(pid)), 0600); err != nil {
+		p.Signal(os.Kill)
+		p.Wait()
+		cont.Destroy()
+		return err
+	}
+	// the container is running, let the daemon attach to it
+	if err := releaseStdout(); err != nil {
+		p.Signal(os.Kill)
+		p.Wait()
+		cont.Destroy()
+		return err
+	}
+
+	waitF := p.Wait
+	if nss := cont.Config().Namespaces; !nss.Contains(configs.NEWPID) {
+		waitF = waitInPIDHost(p, cont)
+	}
+	ps, err := waitF()
+	if err != nil {
+		execErr, ok := err.(*exec.ExitError)
+		if !ok {
+			cont.Destroy()
+			return err
+		}
+		ps = execErr.ProcessState
+	}
+	cont.Destroy()
+	for _, o := range []*shimOutput{stdout, stderr} {
+		if err := o.Close(); err != nil {
+			return err
+		}
+	}
+
+	status := execdriver.ExitStatus{ExitCode: utils.ExitStatus(ps.Sys().(syscall.WaitStatus))}
+	if data, err = json.Marshal(status); err != nil {
+		return err
+	}
+	// the status must be complete when the daemon reads it
+	tmp := filepath.Join(dir, "."+shimStatusFile)
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, shimStatusFile))
+}
+
+// releaseStdout closes the stdout of the shim for the daemon. /dev/null
+// takes its descriptor, rather than a FIFO of the container whose write
+// errors would then kill the shim with SIGPIPE.
+func releaseStdout() error {
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer devNull.Close()
+	return syscall.Dup2(int(devNull.Fd()), int(os.Stdout.Fd()))
+}
+
+// shimOutput is an output stream of a container run by a shim. It is
+// forwarded to the FIFO at path while the daemon reads it and kept, up to
+// shimOutputSize bytes, while the daemon is down.
+type shimOutput struct {
+	path string
+	done chan struct{}
+
+	sync.Mutex
+	cond   *sync.Cond
+	buf    []byte
+	start  int64 // offset in the stream of buf[0]
+	closed bool
+}
+
+func newShimOutput(path string) *shimOutput {
+	o := &shimOutput{path: path, done: make(chan struct{})}
+	o.cond = sync.NewCond(o)
+	return o
+}
+
+func (o *shimOutput) Write(p []byte) (int, error) {
+	o.Lock()
+	o.buf = append(o.buf, p...)
+	if over := len(o.buf) - shimOutputSize; over > 0 {
+		o.buf = o.buf[over:]
+		o.start += int64(over)
+	}
+	o.cond.Signal()
+	o.Unlock()
+	return len(p), nil
+}
+
+// Close waits for the output to be forwarded to the daemon. The output it
+// can't forward is saved in the log file of the stream for the daemon to
+// read it when it reattaches.
+func (o *shimOutput) Close() error {
+	o.Lock()
+	o.closed = true
+	o.cond.Signal()
+	o.Unlock()
+	<-o.done
+
+	o.Lock()
+	defer o.Unlock()
+	if len(o.buf) == 0 {
+		return nil
+	}
+	return ioutil.WriteFile(o.path+shimLogSuffix, o.buf, 0600)
+}
+
+// next waits for output to forward and returns a copy of it along with its
+// offset in the stream. It returns nil once the stream is closed and all of
+// its output was forwarded.
+func (o *shimOutput) next() ([]byte, int64) {
+	o.Lock()
+	defer o.Unlock()
+	for len(o.buf) == 0 && !o.closed {
+		o.cond.Wait()
+	}
+	n := len(o.buf)
+	if n > 32*1024 {
+		n = 32 * 1024
+	}
+	return append([]byte(nil), o.buf[:n]...), o.start
+}
+
+// forwarded drops the output up to end from the buffer, unless it was
+// dropped already to make room.
+func (o *shimOutput) forwarded(end int64) {
+	o.Lock()
+	if n := end - o.start; n > 0 {
+		o.buf = o.buf[n:]
+		o.start = end
+	}
+	o.Unlock()
+}
+
+func (o *shimOutput) isClosed() bool {
+	o.Lock()
+	defer o.Unlock()
+	return o.closed
+}
+
+// forward writes the output to the FIFO whenever the daemon reads it, until
+// the stream is closed.
+func (o *shimOutput) forward() {
+	defer close(o.done)
+	var fifo *os.File
+	defer func() {
+		if fifo != nil {
+			fifo.Close()
+		}
+	}()
+	for {
+		data, offset := o.next()
+		if len(data) == 0 {
+			return
+		}
+		if fifo == nil {
+			f, err := openFifo(o.path, os.O_WRONLY)
+			if err != nil {
+				// the daemon is down, the output is kept until it comes
+				// back or saved once the container exits
+				if o.isClosed() {
+					return
+				}
+				time.Sleep(shimOutputRetry)
+				continue
+			}
+			fifo = f
+		}
+		n, err := fifo.Write(data)
+		o.forwarded(offset + int64(n))
+		if err != nil {
+			// the daemon went away
+			fifo.Close()
+			fifo = nil
+		}
+	}
+}
+
+// copyStdin copies the stdin FIFO, once the daemon opened it, to w.
+func copyStdin(dir string, w io.Writer) {
+	stdin, err := os.Open(filepath.Join(dir, shimStdin))
+	if err != nil {
+		return
+	}
+	io.Copy(w, stdin)
+	stdin.Close()
+}
+
+// resizeConsole resizes the console to the "height width" lines written by
+// the daemon to resize.
+func resizeConsole(console libcontainer.Console, resize io.Reader) {
+	s := bufio.NewScanner(resize)
+	for s.Scan() {
+		var h, w uint16
+		if _, err := fmt.Sscanf(s.Text(), "%d %d", &h, &w); err != nil {
+			continue
+		}
+		term.SetWinsize(console.Fd(), &term.Winsize{Height: h, Width: w})
+	}
+}
+
+// shimConsole is the terminal of a container started by a shim, which
+// resizes it for the daemon.
+type shimConsole struct {
+	resize *os.File
+}
+
+func (t *shimConsole) Resize(h, w int) error {
+	_, err := fmt.Fprintf(t.resize, "%d %d\n", h, w)
+	return err
+}
+
+func (t *shimConsole) Close() error {
+	return t.resize.Close()
+}
+
+func (d *driver) shimDir(id string) string {
+	return filepath.Join(d.root, "shim", id)
+}
+
+// runShim runs the container c in a shim, see Run.
+func (d *driver) runShim(c *execdriver.Command, pipes *execdriver.Pipes, startCallback execdriver.StartCallback) (execdriver.ExitStatus, error) {
+	container, err := d.createContainer(c)
+	if err != nil {
+		return execdriver.ExitStatus{ExitCode: -1}, err
+	}
+	if err := d.createShapedNetwork(container, c); err != nil {
+		return execdriver.ExitStatus{ExitCode: -1}, err
+	}
+	started := false
+	defer func() {
+		// attachShim releases the network namespace once the shim runs
+		if !started {
+			sandbox.Remove(d.netnsPath(c.ID))
+		}
+	}()
+
+	dir := d.shimDir(c.ID)
+	if err := os.RemoveAll(dir); err != nil {
+		return execdriver.ExitStatus{ExitCode: -1}, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return execdriver.ExitStatus{ExitCode: -1}, err
+	}
+	p := &libcontainer.Process{
+		Args: processArgs(c),
+		Env:  c.ProcessConfig.Env,
+		Cwd:  c.WorkingDir,
+		User: c.ProcessConfig.User,
+	}
+	if err := setupSeccompInit(c, p); err != nil {
+		return execdriver.ExitStatus{ExitCode: -1}, err
+	}
+	data, err := json.Marshal(&shimSpec{
+		ID:           c.ID,
+		Root:         d.root,
+		Config:       container,
+		Args:         p.Args,
+		Env:          p.Env,
+		Cwd:          p.Cwd,
+		User:         p.User,
+		Capabilities: p.Capabilities,
+		Tty:          c.ProcessConfig.Tty,
+		OpenStdin:    pipes.Stdin != nil,
+		Settings:     newSettings(c),
+	})
+	if err != nil {
+		return execdriver.ExitStatus{ExitCode: -1}, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, shimSpecFile), data, 0600); err != nil {
+		return execdriver.ExitStatus{ExitCode: -1}, err
+	}
+	for _, name := range []string{shimStdin, shimStdout, shimStderr, shimResize, shimExit} {
+		if err := syscall.Mkfifo(filepath.Join(dir, name), 0600); err != nil {
+			return execdriver.ExitStatus{ExitCode: -1}, err
+		}
+	}
+
+	// hold the write end of stdin so that the shim doesn't see its end
+	// before the daemon attaches to it
+	var stdin *os.File
+	if pipes.Stdin != nil {
+		if stdin, err = os.OpenFile(filepath.Join(dir, shimStdin), os.O_RDWR, 0); err != nil {
+			return execdriver.ExitStatus{ExitCode: -1}, err
+		}
+	}
+
+	cmd := &exec.Cmd{
+		Path: reexec.Self(),
+		Args: []string{shimName, dir},
+		Dir:  dir,
+		// the shim must survive the daemon
+		SysProcAttr: &syscall.SysProcAttr{Setsid: true},
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return execdriver.ExitStatus{ExitCode: -1}, err
+	}
+	if err := cmd.Start(); err != nil {
+		return execdriver.ExitStatus{ExitCode: -1}, err
+	}
+	msg, _ := ioutil.ReadAll(out)
+	if len(msg) != 0 {
+		cmd.Wait()
+		if stdin != nil {
+			stdin.Close()
+		}
+		os.RemoveAll(dir)
+		return execdriver.ExitStatus{ExitCode: -1}, fmt.Errorf("%s", strings.TrimSpace(string(msg)))
+	}
+	go cmd.Wait()
+	started = true
+
+	status, err := d.attachShim(c, pipes, startCallback)
+	if stdin != nil {
+		stdin.Close()
+	}
+	return status, err
+}
+
+// Reattach attaches to the container c run by a shim while the daemon was
+// down and waits for it like Run. The processes may have exited meanwhile.
+func (d *driver) Reattach(c *execdriver.Command, pipes *execdriver.Pipes, startCallback execdriver.StartCallback) (execdriver.ExitStatus, error) {
+	return d.attachShim(c, pipes, startCallback)
+}
+
+// attachShim connects pipes to the FIFOs of the shim of the container c and
+// waits for it to exit.
+func (d *driver) attachShim(c *execdriver.Command, pipes *execdriver.Pipes, startCallback execdriver.StartCallback) (execdriver.ExitStatus, error) {
+	dir := d.shimDir(c.ID)
+	exit, err := openFifo(filepath.Join(dir, shimExit), os.O_RDONLY)
+	if err != nil {
+		return execdriver.ExitStatus{ExitCode: -1}, err
+	}
+	defer exit.Close()
+	data, err := ioutil.ReadFile(filepath.Join(dir, shimPidFile))
+	if err != nil {
+		return execdriver.ExitStatus{ExitCode: -1}, err
+	}
+	pid, err := strconv.Atoi(string(data))
+	if err != nil {
+		return execdriver.ExitStatus{ExitCode: -1}, err
+	}
+	cont, err := d.factory.Load(c.ID)
+	if err != nil {
+		// the shim destroyed the container if it exited while the daemon
+		// was down
+		status, serr := readShimStatus(dir)
+		if serr != nil {
+			return execdriver.ExitStatus{ExitCode: -1}, err
+		}
+		if startCallback != nil {
+			startCallback(&c.ProcessConfig, pid)
+		}
+		replayShimLogs(dir, pipes)
+		d.cleanContainer(c.ID)
+		return status, nil
+	}
+	d.Lock()
+	d.activeContainers[c.ID] = cont
+	d.Unlock()
+	defer func() {
+		cont.Destroy()
+		d.cleanContainer(c.ID)
+	}()
+
+	// the FIFOs of the output are held open for writing as well, so that
+	// they don't reach their end between the writes of the shim
+	var (
+		copies  sync.WaitGroup
+		holders []io.Closer
+	)
+	closeHolders := func() {
+		for _, h := range holders {
+			h.Close()
+		}
+	}
+	for name, w := range map[string]io.Writer{shimStdout: pipes.Stdout, shimStderr: pipes.Stderr} {
+		r, err := openFifo(filepath.Join(dir, name), os.O_RDONLY)
+		if err != nil {
+			closeHolders()
+			abortShim(cont, exit)
+			return execdriver.ExitStatus{ExitCode: -1}, err
+		}
+		h, err := openFifo(filepath.Join(dir, name), os.O_WRONLY)
+		if err != nil {
+			r.Close()
+			closeHolders()
+			abortShim(cont, exit)
+			return execdriver.ExitStatus{ExitCode: -1}, err
+		}
+		holders = append(holders, h)
+		if w == nil {
+			w = ioutil.Discard
+		}
+		copies.Add(1)
+		go func(w io.Writer) {
+			io.Copy(w, r)
+			r.Close()
+			copies.Done()
+		}(w)
+	}
+	// there is no reader left once the shim got the end of stdin
+	if pipes.Stdin != nil {
+		if w, err := openFifo(filepath.Join(dir, shimStdin), os.O_WRONLY); err == nil {
+			go func() {
+				io.Copy(w, pipes.Stdin)
+				w.Close()
+			}()
+		}
+	}
+	if c.ProcessConfig.Tty {
+		resize, err := os.OpenFile(filepath.Join(dir, shimResize), os.O_WRONLY, 0)
+		if err != nil {
+			closeHolders()
+			abortShim(cont, exit)
+			return execdriver.ExitStatus{ExitCode: -1}, err
+		}
+		c.ProcessConfig.Terminal = &shimConsole{resize: resize}
+	} else {
+		c.ProcessConfig.Terminal = &execdriver.StdConsole{}
+	}
+
+	if startCallback != nil {
+		startCallback(&c.ProcessConfig, pid)
+	}
+
+	oom := notifyOnOOM(cont)
+	io.Copy(ioutil.Discard, exit)
+	// the shim is gone, the output ends with what is left in the FIFOs and
+	// what it couldn't write to them
+	closeHolders()
+	copies.Wait()
+	replayShimLogs(dir, pipes)
+
+	status, err := readShimStatus(dir)
+	if err != nil {
+		// the container runs no more, the shim was killed
+		logrus.Errorf("Error reading the exit status of container %s: %v", c.ID, err)
+		killCgroupProcs(cont)
+		return execdriver.ExitStatus{ExitCode: -1}, nil
+	}
+	cont.Destroy()
+	_, status.OOMKilled = <-oom
+	return status, nil
+}
+
+// replayShimLogs copies the output the shim of a container kept in the log
+// files of the streams to pipes.
+func replayShimLogs(dir string, pipes *execdriver.Pipes) {
+	for name, w := range map[string]io.Writer{shimStdout: pipes.Stdout, shimStderr: pipes.Stderr} {
+		f, err := os.Open(filepath.Join(dir, name+shimLogSuffix))
+		if err != nil {
+			continue
+		}
+		if w != nil {
+			io.Copy(w, f)
+		}
+		f.Close()
+	}
+}
+
+// abortShim kills the container of a shim if it still runs and waits for
+// the shim to exit.
+func abortShim(cont libcontainer.Container, exit io.Reader) {
+	if state, err := cont.State(); err == nil {
+		pid := state.InitProcessPid
+		if startTime, err := system.GetProcessStartTime(pid); err == nil && startTime == state.InitProcessStartTime {
+			syscall.Kill(pid, syscall.SIGKILL)
+		}
+	}
+	io.Copy(ioutil.Discard, exit)
+}
+
+func readShimStatus(dir string) (execdriver.ExitStatus, error) {
+	var status execdriver.ExitStatus
+	data, err := ioutil.ReadFile(filepath.Join(dir, shimStatusFile))
+	if err != nil {
+		return status, err
+	}
+	err = json.Unmarshal(data, &status)
+	return status, err
+}
+
+// openFifo opens the FIFO at path without waiting for its other end. It
+// fails to open a FIFO for writing without reader.
+func openFifo(path string, flag int) (*os.File, error) {
+	fd, err := syscall.Open(path, flag|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	if err := syscall.SetNonblock(fd, false); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	return os.NewFile(uintptr(fd), path), nil
+}
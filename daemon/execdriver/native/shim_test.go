@@ -0,0 +1,87 @@
+// +build linux,cgo
+
+package native
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func newTestFifo(t *testing.T) (string, string) {
+	dir, err := ioutil.TempDir("", "docker-shim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, shimStdout)
+	if err := syscall.Mkfifo(path, 0600); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return dir, path
+}
+
+func TestShimOutputWithoutDaemon(t *testing.T) {
+	dir, path := newTestFifo(t)
+	defer os.RemoveAll(dir)
+
+	o := newShimOutput(path)
+	go o.forward()
+	data := bytes.Repeat([]byte("0123456789abcdef"), shimOutputSize/16+1)
+	if _, err := o.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Close(); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := ioutil.ReadFile(path + shimLogSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(saved, data[len(data)-shimOutputSize:]) {
+		t.Fatalf("Expected the last %d bytes of the output to be saved, got %d bytes", shimOutputSize, len(saved))
+	}
+}
+
+func TestShimOutputWithDaemon(t *testing.T) {
+	dir, path := newTestFifo(t)
+	defer os.RemoveAll(dir)
+
+	// the output written before the daemon reads it is kept for it
+	o := newShimOutput(path)
+	go o.forward()
+	if _, err := o.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := openFifo(path, os.O_RDONLY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	holder, err := openFifo(path, os.O_WRONLY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.Write([]byte(" world")); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Close(); err != nil {
+		t.Fatal(err)
+	}
+	holder.Close()
+
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "hello world" {
+		t.Fatalf("Expected hello world, got %q", out)
+	}
+	if _, err := os.Stat(path + shimLogSuffix); !os.IsNotExist(err) {
+		t.Fatalf("Expected no output to be saved, got %v", err)
+	}
+}
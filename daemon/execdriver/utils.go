@@ -2,9 +2,12 @@ package execdriver
 
 import (
 	"fmt"
+	"io"
+	"strconv"
 	"strings"
 
 	"github.com/docker/docker/pkg/stringutils"
+	"github.com/docker/libcontainer/user"
 	"github.com/syndtr/gocapability/capability"
 )
 
@@ -131,3 +134,50 @@ func TweakCapabilities(basics, adds, drops []string) ([]string, error) {
 
 	return newCaps, nil
 }
+
+// GetAdditionalGroups looks up the gids of the groups given by name or gid in
+// the group reader as a source for /etc/group data, which may be nil. A gid
+// that is not found is used as is, a name that is not found is an error.
+func GetAdditionalGroups(additionalGroups []string, group io.Reader) ([]int, error) {
+	var groups []user.Group
+	if group != nil {
+		var err error
+		groups, err = user.ParseGroupFilter(group, func(g user.Group) bool {
+			for _, ag := range additionalGroups {
+				if g.Name == ag || strconv.Itoa(g.Gid) == ag {
+					return true
+				}
+			}
+			return false
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Unable to find additional groups %v: %v", additionalGroups, err)
+		}
+	}
+
+	seen := make(map[int]bool)
+	gids := []int{}
+	for _, ag := range additionalGroups {
+		gid, found := 0, false
+		for _, g := range groups {
+			if g.Name == ag || strconv.Itoa(g.Gid) == ag {
+				gid, found = g.Gid, true
+				break
+			}
+		}
+		if !found {
+			var err error
+			if gid, err = strconv.Atoi(ag); err != nil {
+				return nil, fmt.Errorf("Unable to find group %s", ag)
+			}
+			if gid < 0 || gid > 1<<31-1 {
+				return nil, user.ErrRange
+			}
+		}
+		if !seen[gid] {
+			seen[gid] = true
+			gids = append(gids, gid)
+		}
+	}
+	return gids, nil
+}
@@ -0,0 +1,56 @@
+package execdriver
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetAdditionalGroups(t *testing.T) {
+	const groupContent = `
+root:x:0:root
+adm:x:43:
+grp:x:1234:root,adm
+adm:x:4343:root,adm-duplicate
+this is just some garbage data
+`
+	tests := []struct {
+		groups   []string
+		expected []int
+		hasError bool
+	}{
+		{groups: []string{}, expected: []int{}},
+		{groups: []string{"adm"}, expected: []int{43}},
+		{groups: []string{"adm", "grp"}, expected: []int{43, 1234}},
+		{groups: []string{"1234", "adm"}, expected: []int{1234, 43}},
+		{groups: []string{"adm", "43"}, expected: []int{43}},
+		{groups: []string{"5000"}, expected: []int{5000}},
+		{groups: []string{"noexist"}, hasError: true},
+		{groups: []string{"-1"}, hasError: true},
+	}
+
+	for _, test := range tests {
+		gids, err := GetAdditionalGroups(test.groups, strings.NewReader(groupContent))
+		if test.hasError {
+			if err == nil {
+				t.Errorf("expected an error for %v", test.groups)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("got unexpected error for %v: %v", test.groups, err)
+			continue
+		}
+		if !reflect.DeepEqual(test.expected, gids) {
+			t.Errorf("got %v for %v, expected %v", gids, test.groups, test.expected)
+		}
+	}
+
+	// without an /etc/group only gids can be added
+	if gids, err := GetAdditionalGroups([]string{"5000"}, nil); err != nil || !reflect.DeepEqual(gids, []int{5000}) {
+		t.Errorf("got %v (%v) without a group file, expected [5000]", gids, err)
+	}
+	if _, err := GetAdditionalGroups([]string{"adm"}, nil); err == nil {
+		t.Error("expected an error for a group name without a group file")
+	}
+}
@@ -0,0 +1,232 @@
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/runconfig"
+)
+
+const (
+	// Health states of a container with a health check
+	HealthStarting  = "starting"
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+
+	// healthNone matches containers without a health check in the
+	// health filter of ps
+	healthNone = "none"
+
+	defaultProbeInterval = 30 * time.Second
+	defaultProbeTimeout  = 30 * time.Second
+	defaultProbeRetries  = 3
+
+	// maxProbeOutput is the number of bytes of output kept for each probe
+	maxProbeOutput = 4096
+
+	// maxProbeLogEntries is the number of probe results kept in the state
+	maxProbeLogEntries = 5
+)
+
+// Health is the health of a container, saved along with its state
+type Health struct {
+	types.Health
+	stop chan struct{} // closed to stop probing the container
+}
+
+// initHealthMonitor starts probing the container if it has a health check,
+// replacing the results of any previous run. It is called each time the
+// process pid of the container starts, and does nothing if that process
+// exited meanwhile.
+func (container *Container) initHealthMonitor(pid int) {
+	container.Lock()
+	defer container.Unlock()
+	if !container.Running || container.Pid != pid {
+		return
+	}
+	container.stopHealthcheck()
+
+	config := container.Config.Healthcheck
+	if config == nil || len(config.Test) == 0 || config.Test[0] == "NONE" {
+		container.Health = nil
+		return
+	}
+
+	h := &Health{stop: make(chan struct{})}
+	h.Status = HealthStarting
+	container.Health = h
+
+	go container.monitorHealth(h.stop, config)
+}
+
+// monitorHealth runs the probe every interval until stop is closed
+func (container *Container) monitorHealth(stop chan struct{}, config *runconfig.HealthConfig) {
+	interval := config.Interval
+	if interval == 0 {
+		interval = defaultProbeInterval
+	}
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = defaultProbeTimeout
+	}
+	retries := config.Retries
+	if retries == 0 {
+		retries = defaultProbeRetries
+	}
+
+	for {
+		select {
+		case <-stop:
+			logrus.Debugf("Stopping health checks of container %s", container.ID)
+			return
+		case <-time.After(interval):
+			if container.IsPaused() {
+				continue
+			}
+			result := container.probe(stop, config.Test, timeout)
+			container.handleProbeResult(stop, result, retries)
+		}
+	}
+}
+
+// probe runs test inside the container and returns its result. A probe
+// running longer than timeout is killed and counts as a failure.
+func (container *Container) probe(stop chan struct{}, test []string, timeout time.Duration) *types.HealthcheckResult {
+	result := &types.HealthcheckResult{
+		Start:    time.Now().UTC(),
+		ExitCode: -1,
+	}
+
+	var args []string
+	switch test[0] {
+	case "CMD":
+		args = test[1:]
+	case "CMD-SHELL":
+		args = []string{"/bin/sh", "-c", strings.Join(test[1:], " ")}
+	}
+	if len(args) == 0 {
+		result.End = result.Start
+		result.Output = fmt.Sprintf("Invalid health check %q", test)
+		return result
+	}
+
+	processConfig := &execdriver.ProcessConfig{
+		Entrypoint: args[0],
+		Arguments:  args[1:],
+		User:       container.Config.User,
+	}
+	processConfig.Env = container.command.ProcessConfig.Env
+	processConfig.Dir = container.command.WorkingDir
+	output := &probeOutput{}
+	pipes := execdriver.NewPipes(nil, output, output, false)
+
+	started := make(chan int, 1)
+	callback := func(_ *execdriver.ProcessConfig, pid int) {
+		started <- pid
+	}
+
+	type execResult struct {
+		exitCode int
+		err      error
+	}
+	done := make(chan execResult, 1)
+	go func() {
+		exitCode, err := container.daemon.execDriver.Exec(container.command, processConfig, pipes, callback)
+		done <- execResult{exitCode, err}
+	}()
+
+	select {
+	case r := <-done:
+		result.ExitCode = r.exitCode
+		if r.err != nil {
+			result.ExitCode = -1
+			fmt.Fprintf(output, "Unable to run health check: %s", r.err)
+		}
+	case <-time.After(timeout):
+		// the probe may not have been started yet, kill it once it is
+		// rather than leaving it running
+		select {
+		case pid := <-started:
+			if p, err := os.FindProcess(pid); err == nil {
+				p.Kill()
+			}
+		case <-done:
+		case <-stop:
+			// the probe goes away with the container
+		}
+		fmt.Fprintf(output, "Health check exceeded timeout (%v)", timeout)
+	}
+
+	result.End = time.Now().UTC()
+	result.Output = output.String()
+	return result
+}
+
+// handleProbeResult records result in the state of the container and
+// updates its health status, unless the probes were stopped meanwhile.
+func (container *Container) handleProbeResult(stop chan struct{}, result *types.HealthcheckResult, retries int) {
+	container.Lock()
+	h := container.Health
+	if h == nil || h.stop != stop {
+		container.Unlock()
+		return
+	}
+
+	h.Log = append(h.Log, result)
+	if len(h.Log) > maxProbeLogEntries {
+		h.Log = h.Log[len(h.Log)-maxProbeLogEntries:]
+	}
+
+	oldStatus := h.Status
+	if result.ExitCode == 0 {
+		h.FailingStreak = 0
+		h.Status = HealthHealthy
+	} else {
+		h.FailingStreak++
+		if h.FailingStreak >= retries {
+			h.Status = HealthUnhealthy
+		}
+	}
+	status := h.Status
+	container.Unlock()
+
+	if status != oldStatus {
+		container.LogEvent("health_status: " + status)
+		if err := container.ToDisk(); err != nil {
+			logrus.Errorf("Error saving the health of container %s: %s", container.ID, err)
+		}
+	}
+}
+
+// probeOutput collects the combined output of a probe, up to
+// maxProbeOutput bytes.
+type probeOutput struct {
+	sync.Mutex
+	buf bytes.Buffer
+}
+
+func (o *probeOutput) Write(p []byte) (int, error) {
+	o.Lock()
+	defer o.Unlock()
+	if room := maxProbeOutput - o.buf.Len(); room > 0 {
+		if len(p) > room {
+			o.buf.Write(p[:room])
+		} else {
+			o.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+func (o *probeOutput) String() string {
+	o.Lock()
+	defer o.Unlock()
+	return o.buf.String()
+}
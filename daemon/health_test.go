@@ -0,0 +1,142 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/daemon/events"
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/runconfig"
+)
+
+func newHealthTestContainer(root string) *Container {
+	return &Container{
+		State:  NewState(),
+		root:   root,
+		ID:     "healthtest",
+		Config: &runconfig.Config{Image: "busybox"},
+		daemon: &Daemon{EventsService: events.New()},
+	}
+}
+
+func TestHandleProbeResult(t *testing.T) {
+	root, err := ioutil.TempDir("", "docker-health-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	c := newHealthTestContainer(root)
+	stop := make(chan struct{})
+	c.Health = &Health{stop: stop}
+	c.Health.Status = HealthStarting
+
+	_, events := c.daemon.EventsService.Subscribe()
+	defer c.daemon.EventsService.Evict(events)
+
+	fail := &types.HealthcheckResult{ExitCode: 1}
+	pass := &types.HealthcheckResult{ExitCode: 0}
+
+	c.handleProbeResult(stop, fail, 2)
+	if c.Health.Status != HealthStarting || c.Health.FailingStreak != 1 {
+		t.Fatalf("Expected to still be starting after one failure, got %+v", c.Health.Health)
+	}
+	c.handleProbeResult(stop, fail, 2)
+	if c.Health.Status != HealthUnhealthy {
+		t.Fatalf("Expected to be unhealthy after two failures, got %+v", c.Health.Health)
+	}
+	c.handleProbeResult(stop, pass, 2)
+	if c.Health.Status != HealthHealthy || c.Health.FailingStreak != 0 {
+		t.Fatalf("Expected to be healthy after a success, got %+v", c.Health.Health)
+	}
+
+	// events are published asynchronously, in any order
+	statuses := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case ev := <-events:
+			statuses[ev.(*jsonmessage.JSONMessage).Status] = true
+		case <-time.After(time.Second):
+			t.Fatalf("Expected two health_status events, got %v", statuses)
+		}
+	}
+	if !statuses["health_status: unhealthy"] || !statuses["health_status: healthy"] {
+		t.Fatalf("Unexpected health_status events %v", statuses)
+	}
+
+	for i := 0; i < 2*maxProbeLogEntries; i++ {
+		c.handleProbeResult(stop, pass, 2)
+	}
+	if len(c.Health.Log) != maxProbeLogEntries {
+		t.Fatalf("Expected %d results in the log, got %d", maxProbeLogEntries, len(c.Health.Log))
+	}
+
+	// results of a stopped monitor are dropped
+	c.stopHealthcheck()
+	c.handleProbeResult(stop, fail, 1)
+	if c.Health.Status != HealthHealthy || c.Health.FailingStreak != 0 {
+		t.Fatalf("Result of a stopped health check was recorded: %+v", c.Health.Health)
+	}
+}
+
+func TestInitHealthMonitor(t *testing.T) {
+	c := newHealthTestContainer("")
+	c.setRunning(42)
+
+	c.initHealthMonitor(42)
+	if c.Health != nil {
+		t.Fatalf("Unexpected health for a container without health check: %+v", c.Health)
+	}
+	if s := c.healthString(); s != healthNone {
+		t.Fatalf("Expected health %q, got %q", healthNone, s)
+	}
+
+	c.Config.Healthcheck = &runconfig.HealthConfig{Test: []string{"NONE"}}
+	c.initHealthMonitor(42)
+	if c.Health != nil {
+		t.Fatalf("Unexpected health for a disabled health check: %+v", c.Health)
+	}
+
+	// the monitor of a process which already exited isn't started
+	c.Config.Healthcheck = &runconfig.HealthConfig{Test: []string{"CMD", "true"}, Interval: time.Hour}
+	c.initHealthMonitor(41)
+	if c.Health != nil {
+		t.Fatalf("Unexpected health for an exited process: %+v", c.Health)
+	}
+
+	c.initHealthMonitor(42)
+	if c.Health == nil || c.Health.Status != HealthStarting {
+		t.Fatalf("Expected the container to be starting, got %+v", c.Health)
+	}
+	stop := c.Health.stop
+
+	if s := c.State.String(); !strings.HasSuffix(s, "(starting)") {
+		t.Fatalf("Expected the status to show the health, got %q", s)
+	}
+
+	c.SetRestarting(&execdriver.ExitStatus{ExitCode: 1})
+	select {
+	case <-stop:
+	default:
+		t.Fatal("Health check not stopped when the container restarted")
+	}
+	if c.Health == nil {
+		t.Fatal("Health results should be kept once the container stops")
+	}
+}
+
+func TestProbeOutput(t *testing.T) {
+	o := &probeOutput{}
+	o.Write([]byte("hello "))
+	if n, err := o.Write([]byte(strings.Repeat("x", maxProbeOutput))); err != nil || n != maxProbeOutput {
+		t.Fatalf("Expected to consume %d bytes, got %d, %v", maxProbeOutput, n, err)
+	}
+	if s := o.String(); len(s) != maxProbeOutput || !strings.HasPrefix(s, "hello x") {
+		t.Fatalf("Unexpected output of %d bytes: %.20q", len(s), s)
+	}
+}
@@ -48,16 +48,26 @@ func (daemon *Daemon) ContainerInspect(name string) (*types.ContainerJSON, error
 	}
 
 	containerState := &types.ContainerState{
-		Running:    container.State.Running,
-		Paused:     container.State.Paused,
-		Restarting: container.State.Restarting,
-		OOMKilled:  container.State.OOMKilled,
-		Dead:       container.State.Dead,
-		Pid:        container.State.Pid,
-		ExitCode:   container.State.ExitCode,
-		Error:      container.State.Error,
-		StartedAt:  container.State.StartedAt,
-		FinishedAt: container.State.FinishedAt,
+		Running:        container.State.Running,
+		Paused:         container.State.Paused,
+		Restarting:     container.State.Restarting,
+		OOMKilled:      container.State.OOMKilled,
+		Dead:           container.State.Dead,
+		Checkpointed:   container.State.Checkpointed,
+		Pid:            container.State.Pid,
+		ExitCode:       container.State.ExitCode,
+		Error:          container.State.Error,
+		StartedAt:      container.State.StartedAt,
+		FinishedAt:     container.State.FinishedAt,
+		CheckpointedAt: container.State.CheckpointedAt,
+		NextRestartAt:  container.State.NextRestartAt,
+	}
+	if h := container.State.Health; h != nil {
+		containerState.Health = &types.Health{
+			Status:        h.Status,
+			FailingStreak: h.FailingStreak,
+			Log:           append([]*types.HealthcheckResult(nil), h.Log...),
+		}
 	}
 
 	contJSON := &types.ContainerJSON{
@@ -83,6 +93,7 @@ func (daemon *Daemon) ContainerInspect(name string) (*types.ContainerJSON, error
 		VolumesRW:       container.VolumesRW,
 		AppArmorProfile: container.AppArmorProfile,
 		ExecIDs:         container.GetExecIDs(),
+		ExecSessions:    container.getExecSessions(),
 		HostConfig:      &hostConfig,
 	}
 
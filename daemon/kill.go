@@ -17,7 +17,9 @@ func (daemon *Daemon) ContainerKill(name string, sig uint64) error {
 
 	// If no signal is passed, or SIGKILL, perform regular Kill (SIGKILL + wait())
 	if sig == 0 || syscall.Signal(sig) == syscall.SIGKILL {
+		container.SetManuallyStopped(true)
 		if err := container.Kill(); err != nil {
+			container.SetManuallyStopped(false)
 			return fmt.Errorf("Cannot kill container %s: %s", name, err)
 		}
 	} else {
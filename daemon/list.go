@@ -53,7 +53,7 @@ func (daemon *Daemon) Containers(config *ContainersConfig) ([]*types.Container,
 
 	if i, ok := psFilters["status"]; ok {
 		for _, value := range i {
-			if value == "exited" {
+			if value == "exited" || value == "checkpointed" {
 				all = true
 			}
 		}
@@ -128,6 +128,10 @@ func (daemon *Daemon) Containers(config *ContainersConfig) ([]*types.Container,
 		if !psFilters.Match("status", container.State.StateString()) {
 			return nil
 		}
+
+		if !psFilters.Match("health", container.State.healthString()) {
+			return nil
+		}
 		displayed++
 		newC := &types.Container{
 			ID:    container.ID,
@@ -12,7 +12,15 @@ import (
 	"github.com/docker/docker/runconfig"
 )
 
-const defaultTimeIncrement = 100
+const (
+	// defaultRestartDelay is the delay before restarting a container which ran
+	// for longer than the reset window, doubled for each exit within it
+	defaultRestartDelay = 100 * time.Millisecond
+
+	// defaultRestartResetWindow is how long a container has to run for the
+	// delay between restarts to be reset
+	defaultRestartResetWindow = 10 * time.Second
+)
 
 // containerMonitor monitors the execution of a container's main process.
 // If a restart policy is specified for the container the monitor will ensure that the
@@ -40,16 +48,23 @@ type containerMonitor struct {
 	startSignal chan struct{}
 
 	// stopChan is used to signal to the monitor whenever there is a wait for the
-	// next restart so that the timeIncrement is not honored and the user is not
+	// next restart so that the restartDelay is not honored and the user is not
 	// left waiting for nothing to happen during this time
 	stopChan chan struct{}
 
-	// timeIncrement is the amount of time to wait between restarts
-	// this is in milliseconds
-	timeIncrement int
+	// restartDelay is the amount of time to wait before the next restart
+	restartDelay time.Duration
 
 	// lastStartTime is the time which the monitor last exec'd the container's process
 	lastStartTime time.Time
+
+	// restoreOpts are set when the container's process is restored from a
+	// checkpoint rather than started
+	restoreOpts *execdriver.CheckpointOpts
+
+	// reattach is set when the container's process kept running while the
+	// daemon was down and is monitored again rather than started
+	reattach bool
 }
 
 // newContainerMonitor returns an initialized containerMonitor for the provided container
@@ -58,7 +73,6 @@ func newContainerMonitor(container *Container, policy runconfig.RestartPolicy) *
 	return &containerMonitor{
 		container:     container,
 		restartPolicy: policy,
-		timeIncrement: defaultTimeIncrement,
 		stopChan:      make(chan struct{}),
 		startSignal:   make(chan struct{}),
 	}
@@ -79,6 +93,14 @@ func (m *containerMonitor) ExitOnNext() {
 	m.mux.Unlock()
 }
 
+// setRestartPolicy changes the restart policy applied the next time the
+// container exits
+func (m *containerMonitor) setRestartPolicy(policy runconfig.RestartPolicy) {
+	m.mux.Lock()
+	m.restartPolicy = policy
+	m.mux.Unlock()
+}
+
 // Close closes the container's resources such as networking allocations and
 // unmounts the contatiner's root filesystem
 func (m *containerMonitor) Close() error {
@@ -115,6 +137,10 @@ func (m *containerMonitor) Start() error {
 			defer m.container.Unlock()
 		}
 		m.Close()
+
+		if m.container.hostConfig.AutoRemove {
+			go m.container.daemon.autoRemove(m.container)
+		}
 	}()
 
 	// reset the restart count
@@ -131,11 +157,21 @@ func (m *containerMonitor) Start() error {
 
 		pipes := execdriver.NewPipes(m.container.stdin, m.container.stdout, m.container.stderr, m.container.Config.OpenStdin)
 
-		m.container.LogEvent("start")
-
 		m.lastStartTime = time.Now()
 
-		if exitStatus, err = m.container.daemon.Run(m.container, pipes, m.callback); err != nil {
+		if m.restoreOpts != nil {
+			m.container.LogEvent("restore")
+			exitStatus, err = m.container.daemon.Restore(m.container, pipes, m.callback, m.restoreOpts)
+			// a restart runs the container again from scratch
+			m.restoreOpts = nil
+		} else if m.reattach {
+			exitStatus, err = m.container.daemon.Reattach(m.container, pipes, m.callback)
+			m.reattach = false
+		} else {
+			m.container.LogEvent("start")
+			exitStatus, err = m.container.daemon.Run(m.container, pipes, m.callback)
+		}
+		if err != nil {
 			// if we receive an internal error from the initial start of a container then lets
 			// return it instead of entering the restart loop
 			if m.container.RestartCount == 0 {
@@ -155,6 +191,7 @@ func (m *containerMonitor) Start() error {
 
 		if m.shouldRestart(exitStatus.ExitCode) {
 			m.container.SetRestarting(&exitStatus)
+			m.container.SetNextRestart(time.Now().UTC().Add(m.restartDelay))
 			if exitStatus.OOMKilled {
 				m.container.LogEvent("oom")
 			}
@@ -183,16 +220,24 @@ func (m *containerMonitor) Start() error {
 
 // resetMonitor resets the stateful fields on the containerMonitor based on the
 // previous runs success or failure.  Regardless of success, if the container had
-// an execution time of more than 10s then reset the timer back to the default
+// an execution time of more than the reset window then reset the delay back to
+// the initial one
 func (m *containerMonitor) resetMonitor(successful bool) {
-	executionTime := time.Now().Sub(m.lastStartTime).Seconds()
+	delay, maxDelay, resetWindow := m.restartDelays()
 
-	if executionTime > 10 {
-		m.timeIncrement = defaultTimeIncrement
+	// a container failing right away waits twice the initial delay already
+	if m.restartDelay == 0 {
+		m.restartDelay = delay
+	}
+	if time.Now().Sub(m.lastStartTime) > resetWindow {
+		m.restartDelay = delay
 	} else {
 		// otherwise we need to increment the amount of time we wait before restarting
-		// the process.  We will build up by multiplying the increment by 2
-		m.timeIncrement *= 2
+		// the process.  We will build up by multiplying the delay by 2
+		m.restartDelay *= 2
+		if maxDelay != 0 && m.restartDelay > maxDelay {
+			m.restartDelay = maxDelay
+		}
 	}
 
 	// the container exited successfully so we need to reset the failure counter
@@ -203,11 +248,32 @@ func (m *containerMonitor) resetMonitor(successful bool) {
 	}
 }
 
-// waitForNextRestart waits with the default time increment to restart the container unless
+// restartDelays returns the initial and maximum delays between restarts and the
+// reset window of the restart policy, or their defaults. A maximum delay of 0
+// leaves the delay uncapped.
+func (m *containerMonitor) restartDelays() (delay, maxDelay, resetWindow time.Duration) {
+	m.mux.Lock()
+	policy := m.restartPolicy
+	m.mux.Unlock()
+
+	delay, maxDelay, resetWindow = policy.Delay, policy.MaxDelay, policy.ResetWindow
+	if delay == 0 {
+		delay = defaultRestartDelay
+	}
+	if maxDelay != 0 && maxDelay < delay {
+		maxDelay = delay
+	}
+	if resetWindow == 0 {
+		resetWindow = defaultRestartResetWindow
+	}
+	return delay, maxDelay, resetWindow
+}
+
+// waitForNextRestart waits for the restart delay to restart the container unless
 // a user or docker asks for the container to be stopped
 func (m *containerMonitor) waitForNextRestart() {
 	select {
-	case <-time.After(time.Duration(m.timeIncrement) * time.Millisecond):
+	case <-time.After(m.restartDelay):
 	case <-m.stopChan:
 	}
 }
@@ -223,8 +289,13 @@ func (m *containerMonitor) shouldRestart(exitCode int) bool {
 		return false
 	}
 
+	// the container was stopped to be restored from its checkpoint
+	if m.container.IsCheckpointed() {
+		return false
+	}
+
 	switch m.restartPolicy.Name {
-	case "always":
+	case "always", "unless-stopped":
 		return true
 	case "on-failure":
 		// the default value of 0 for MaximumRetryCount means that we will not enforce a maximum count
@@ -252,7 +323,17 @@ func (m *containerMonitor) callback(processConfig *execdriver.ProcessConfig, pid
 		}
 	}
 
-	m.container.setRunning(pid)
+	if m.reattach {
+		// the process was started and maybe paused before the daemon stopped
+		startedAt, paused := m.container.StartedAt, m.container.Paused
+		m.container.setRunning(pid)
+		m.container.StartedAt, m.container.Paused = startedAt, paused
+	} else {
+		m.container.setRunning(pid)
+	}
+	// the container is locked while it is started by a user, the health
+	// monitor waits for the lock to be released
+	go m.container.initHealthMonitor(pid)
 
 	// signal that the process has started
 	// close channel only if not closed
@@ -0,0 +1,71 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/docker/runconfig"
+)
+
+func TestMonitorRestartDelay(t *testing.T) {
+	m := newContainerMonitor(&Container{}, runconfig.RestartPolicy{
+		Name:     "always",
+		Delay:    time.Second,
+		MaxDelay: 3 * time.Second,
+	})
+
+	m.lastStartTime = time.Now()
+	for _, expected := range []time.Duration{2 * time.Second, 3 * time.Second, 3 * time.Second, 3 * time.Second} {
+		m.resetMonitor(false)
+		if m.restartDelay != expected {
+			t.Fatalf("Expected a restart delay of %s, got %s", expected, m.restartDelay)
+		}
+	}
+
+	// a container that ran for longer than the reset window starts over
+	m.lastStartTime = time.Now().Add(-defaultRestartResetWindow - time.Second)
+	m.resetMonitor(false)
+	if m.restartDelay != time.Second {
+		t.Fatalf("Expected the restart delay to be reset, got %s", m.restartDelay)
+	}
+	if m.failureCount != 5 {
+		t.Fatalf("Expected 5 failures, got %d", m.failureCount)
+	}
+}
+
+func TestMonitorDefaultRestartDelays(t *testing.T) {
+	m := newContainerMonitor(&Container{}, runconfig.RestartPolicy{Name: "always", Delay: 2 * time.Minute, MaxDelay: time.Minute})
+	delay, maxDelay, resetWindow := m.restartDelays()
+	if delay != 2*time.Minute || maxDelay != 2*time.Minute || resetWindow != defaultRestartResetWindow {
+		t.Fatalf("Unexpected restart delays %s, %s, %s", delay, maxDelay, resetWindow)
+	}
+
+	// without a policy the delays grow like they always did, from 200ms
+	// after a container failing right away and without a maximum
+	m = newContainerMonitor(&Container{}, runconfig.RestartPolicy{Name: "always"})
+	m.lastStartTime = time.Now()
+	expected := 200 * time.Millisecond
+	for i := 0; i < 12; i++ {
+		m.resetMonitor(false)
+		if m.restartDelay != expected {
+			t.Fatalf("Expected a restart delay of %s, got %s", expected, m.restartDelay)
+		}
+		expected *= 2
+	}
+	m.lastStartTime = time.Now().Add(-defaultRestartResetWindow - time.Second)
+	m.resetMonitor(false)
+	if m.restartDelay != 100*time.Millisecond {
+		t.Fatalf("Expected the restart delay to be reset to 100ms, got %s", m.restartDelay)
+	}
+}
+
+func TestMonitorShouldRestartUnlessStopped(t *testing.T) {
+	m := newContainerMonitor(&Container{State: NewState()}, runconfig.RestartPolicy{Name: "unless-stopped"})
+	if !m.shouldRestart(0) {
+		t.Fatal("Expected an unless-stopped container to be restarted")
+	}
+	m.ExitOnNext()
+	if m.shouldRestart(1) {
+		t.Fatal("Expected a stopped container not to be restarted")
+	}
+}
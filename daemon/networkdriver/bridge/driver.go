@@ -108,6 +108,7 @@ type Config struct {
 	DefaultGatewayIPv4          string
 	DefaultGatewayIPv6          string
 	InterContainerCommunication bool
+	EnableUserlandProxy         bool
 }
 
 func InitDriver(config *Config) error {
@@ -230,14 +231,29 @@ func InitDriver(config *Config) error {
 		iptables.FirewalldInit()
 	}
 
+	// Without the userland proxy published ports are reached through
+	// hairpin NAT. Loopback traffic can only be NATed onto the bridge when
+	// the kernel allows routing 127.0.0.0/8 there, otherwise the proxy is
+	// kept around to serve loopback connections.
+	hairpinMode := !config.EnableUserlandProxy
+	loopbackNat := false
+	if config.EnableIptables && hairpinMode {
+		if err := setupLoopbackRouting(); err != nil {
+			logrus.Warnf("Unable to route loopback traffic to %s, falling back to the userland proxy for loopback access: %s", bridgeIface, err)
+		} else {
+			loopbackNat = true
+		}
+	}
+	portMapper.SetUserlandProxy(!loopbackNat)
+
 	// Configure iptables for link support
 	if config.EnableIptables {
-		if err := setupIPTables(addrv4, config.InterContainerCommunication, config.EnableIpMasq); err != nil {
+		if err := setupIPTables(addrv4, config.InterContainerCommunication, config.EnableIpMasq, loopbackNat); err != nil {
 			logrus.Errorf("Error configuring iptables: %s", err)
 			return err
 		}
 		// call this on Firewalld reload
-		iptables.OnReloaded(func() { setupIPTables(addrv4, config.InterContainerCommunication, config.EnableIpMasq) })
+		iptables.OnReloaded(func() { setupIPTables(addrv4, config.InterContainerCommunication, config.EnableIpMasq, loopbackNat) })
 	}
 
 	if config.EnableIpForward {
@@ -263,21 +279,27 @@ func InitDriver(config *Config) error {
 	}
 
 	if config.EnableIptables {
-		_, err := iptables.NewChain("DOCKER", bridgeIface, iptables.Nat)
+		_, err := iptables.NewChain("DOCKER", bridgeIface, iptables.Nat, loopbackNat)
 		if err != nil {
 			return err
 		}
 		// call this on Firewalld reload
-		iptables.OnReloaded(func() { iptables.NewChain("DOCKER", bridgeIface, iptables.Nat) })
+		iptables.OnReloaded(func() { iptables.NewChain("DOCKER", bridgeIface, iptables.Nat, loopbackNat) })
 
-		chain, err := iptables.NewChain("DOCKER", bridgeIface, iptables.Filter)
+		chain, err := iptables.NewChain("DOCKER", bridgeIface, iptables.Filter, hairpinMode)
 		if err != nil {
 			return err
 		}
 		// call this on Firewalld reload
-		iptables.OnReloaded(func() { iptables.NewChain("DOCKER", bridgeIface, iptables.Filter) })
+		iptables.OnReloaded(func() { iptables.NewChain("DOCKER", bridgeIface, iptables.Filter, hairpinMode) })
 
 		portMapper.SetIptablesChain(chain)
+
+		if config.EnableIPv6 {
+			if err := setupIP6Tables(hairpinMode); err != nil {
+				logrus.Warnf("Unable to set up ip6tables, IPv6 ports will be published by the userland proxy: %s", err)
+			}
+		}
 	}
 
 	bridgeIPv4Network = networkv4
@@ -328,9 +350,49 @@ func InitDriver(config *Config) error {
 	return nil
 }
 
-func setupIPTables(addr net.Addr, icc, ipmasq bool) error {
+// setupIP6Tables creates the DOCKER chains forwarding IPv6 host ports to
+// containers with a global IPv6 address. Loopback traffic is never NATed,
+// the kernel does not route ::1 off the host.
+func setupIP6Tables(hairpinMode bool) error {
+	if err := iptables.RemoveExistingChain6("DOCKER", iptables.Nat); err != nil {
+		return err
+	}
+	if _, err := iptables.NewChain6("DOCKER", bridgeIface, iptables.Nat, false); err != nil {
+		return err
+	}
+	// call this on Firewalld reload
+	iptables.OnReloaded(func() { iptables.NewChain6("DOCKER", bridgeIface, iptables.Nat, false) })
+
+	chain, err := iptables.NewChain6("DOCKER", bridgeIface, iptables.Filter, hairpinMode)
+	if err != nil {
+		return err
+	}
+	// call this on Firewalld reload
+	iptables.OnReloaded(func() { iptables.NewChain6("DOCKER", bridgeIface, iptables.Filter, hairpinMode) })
+
+	portMapper.SetIp6tablesChain(chain)
+	return nil
+}
+
+func setupIPTables(addr net.Addr, icc, ipmasq, hairpin bool) error {
 	// Enable NAT
 
+	// Masquerade loopback traffic that is NATed to the containers, their
+	// replies could not be routed back to 127.0.0.0/8 otherwise
+	hairpinArgs := []string{"-m", "addrtype", "--src-type", "LOCAL", "-o", bridgeIface, "-j", "MASQUERADE"}
+	if hairpin {
+		if !iptables.Exists(iptables.Nat, "POSTROUTING", hairpinArgs...) {
+			if output, err := iptables.Raw(append([]string{
+				"-t", string(iptables.Nat), "-I", "POSTROUTING"}, hairpinArgs...)...); err != nil {
+				return fmt.Errorf("Unable to enable hairpin NAT: %s", err)
+			} else if len(output) != 0 {
+				return iptables.ChainError{Chain: "POSTROUTING", Output: output}
+			}
+		}
+	} else {
+		iptables.Raw(append([]string{"-t", string(iptables.Nat), "-D", "POSTROUTING"}, hairpinArgs...)...)
+	}
+
 	if ipmasq {
 		natArgs := []string{"-s", addr.String(), "!", "-o", bridgeIface, "-j", "MASQUERADE"}
 
@@ -397,6 +459,17 @@ func setupIPTables(addr net.Addr, icc, ipmasq bool) error {
 	return nil
 }
 
+// setupLoopbackRouting allows packets with a loopback source or destination
+// address to be routed through the bridge, so that connections to published
+// ports on 127.0.0.1 can be NATed to the containers.
+func setupLoopbackRouting() error {
+	procFile := "/proc/sys/net/ipv4/conf/" + bridgeIface + "/route_localnet"
+	if _, err := os.Stat(procFile); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(procFile, []byte{'1', '\n'}, 0644)
+}
+
 func RequestPort(ip net.IP, proto string, port int) (int, error) {
 	initPortMapper()
 	return portMapper.Allocator.RequestPort(ip, proto, port)
@@ -531,31 +604,6 @@ func createBridgeIface(name string) error {
 	return netlink.CreateBridge(name, setBridgeMacAddr)
 }
 
-// Generate a IEEE802 compliant MAC address from the given IP address.
-//
-// The generator is guaranteed to be consistent: the same IP will always yield the same
-// MAC address. This is to avoid ARP cache issues.
-func generateMacAddr(ip net.IP) net.HardwareAddr {
-	hw := make(net.HardwareAddr, 6)
-
-	// The first byte of the MAC address has to comply with these rules:
-	// 1. Unicast: Set the least-significant bit to 0.
-	// 2. Address is locally administered: Set the second-least-significant bit (U/L) to 1.
-	// 3. As "small" as possible: The veth address has to be "smaller" than the bridge address.
-	hw[0] = 0x02
-
-	// The first 24 bits of the MAC represent the Organizationally Unique Identifier (OUI).
-	// Since this address is locally administered, we can do whatever we want as long as
-	// it doesn't conflict with other addresses.
-	hw[1] = 0x42
-
-	// Insert the IP address into the last 32 bits of the MAC address.
-	// This is a simple way to guarantee the address will be consistent and unique.
-	copy(hw[2:], ip.To4())
-
-	return hw
-}
-
 func linkLocalIPv6FromMac(mac string) (string, error) {
 	hx := strings.Replace(mac, ":", "", -1)
 	hw, err := hex.DecodeString(hx)
@@ -586,7 +634,7 @@ func Allocate(id, requestedMac, requestedIP, requestedIPv6 string) (*network.Set
 
 	// If no explicit mac address was given, generate a random one.
 	if mac, err = net.ParseMAC(requestedMac); err != nil {
-		mac = generateMacAddr(ip)
+		mac = networkdriver.GenerateMacAddr(ip)
 	}
 
 	if globalIPv6Network != nil {
@@ -695,13 +743,20 @@ func AllocatePort(id string, port nat.Port, binding nat.PortBinding) (nat.PortBi
 		}
 	}
 
+	// IPv6 host ports go to the global IPv6 address of the container when
+	// it has one, the userland proxy bridges them to IPv4 otherwise
+	containerIP := network.IP
+	if ip.To4() == nil && network.IPv6 != nil {
+		containerIP = network.IPv6
+	}
+
 	// host ip, proto, and host port
 	var container net.Addr
 	switch proto {
 	case "tcp":
-		container = &net.TCPAddr{IP: network.IP, Port: containerPort}
+		container = &net.TCPAddr{IP: containerIP, Port: containerPort}
 	case "udp":
-		container = &net.UDPAddr{IP: network.IP, Port: containerPort}
+		container = &net.UDPAddr{IP: containerIP, Port: containerPort}
 	default:
 		return nat.PortBinding{}, fmt.Errorf("unsupported address type %s", proto)
 	}
@@ -0,0 +1,269 @@
+package macvlan
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/daemon/network"
+	"github.com/docker/docker/daemon/networkdriver"
+	"github.com/docker/docker/daemon/networkdriver/ipallocator"
+	"github.com/docker/docker/daemon/networkdriver/sandbox"
+	"github.com/docker/libcontainer/netlink"
+	"github.com/docker/libcontainer/utils"
+)
+
+const macvlanMode = "bridge"
+
+var ErrNotConfigured = errors.New("macvlan networking is not configured, start the daemon with --macvlan-parent and --macvlan-subnet")
+
+// Network interface represents the macvlan subinterface of a container
+type networkInterface struct {
+	IP      net.IP
+	Sandbox string
+}
+
+// Driver gives containers a macvlan subinterface of the parent, addressed
+// from the subnet of the network the parent is on.
+type Driver struct {
+	parent      string
+	subnet      *net.IPNet
+	gateway     net.IP
+	interfaces  map[string]*networkInterface
+	ipAllocator *ipallocator.IPAllocator
+	sync.Mutex
+}
+
+type Config struct {
+	Parent  string
+	Subnet  string
+	Gateway string
+}
+
+// New validates the parent interface, creating it first if it names a
+// missing 802.1q VLAN of an existing interface (e.g. eth0.10), and returns
+// a driver allocating addresses from the configured subnet.
+func New(config *Config) (*Driver, error) {
+	if config.Subnet == "" {
+		return nil, fmt.Errorf("A subnet is required for macvlan networking on %s", config.Parent)
+	}
+	_, n, err := net.ParseCIDR(config.Subnet)
+	if err != nil {
+		return nil, err
+	}
+	if n.IP.To4() == nil {
+		return nil, fmt.Errorf("Macvlan subnet %s is not an IPv4 subnet", config.Subnet)
+	}
+
+	if err := setupParent(config.Parent); err != nil {
+		return nil, err
+	}
+
+	d := &Driver{
+		parent:      config.Parent,
+		subnet:      n,
+		interfaces:  make(map[string]*networkInterface),
+		ipAllocator: ipallocator.New(),
+	}
+	if d.gateway, err = d.requestGateway(config.Gateway); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// parseVlanParent splits a VLAN interface name of the form <master>.<vlan id>.
+func parseVlanParent(name string) (string, uint16, error) {
+	i := strings.LastIndex(name, ".")
+	if i <= 0 || i == len(name)-1 {
+		return "", 0, fmt.Errorf("%s is not a VLAN interface name", name)
+	}
+	vid, err := strconv.ParseUint(name[i+1:], 10, 16)
+	if err != nil || vid < 1 || vid > 4094 {
+		return "", 0, fmt.Errorf("Invalid VLAN id in interface name %s", name)
+	}
+	return name[:i], uint16(vid), nil
+}
+
+func setupParent(name string) error {
+	if name == "" {
+		return errors.New("A parent interface is required for macvlan networking")
+	}
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		master, vid, perr := parseVlanParent(name)
+		if perr != nil {
+			return fmt.Errorf("Macvlan parent interface %s not found: %v", name, err)
+		}
+		logrus.Infof("Creating VLAN interface %s with id %d on %s", name, vid, master)
+		if err := netlink.NetworkLinkAddVlan(master, name, vid); err != nil {
+			return fmt.Errorf("Unable to create VLAN interface %s: %v", name, err)
+		}
+		if iface, err = net.InterfaceByName(name); err != nil {
+			return err
+		}
+	}
+	if iface.Flags&net.FlagUp == 0 {
+		if err := netlink.NetworkLinkUp(iface); err != nil {
+			return fmt.Errorf("Unable to bring up macvlan parent %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func (d *Driver) requestGateway(requestedGateway string) (net.IP, error) {
+	if requestedGateway == "" {
+		// the allocator hands out the first address of the subnet
+		return d.ipAllocator.RequestIP(d.subnet, nil)
+	}
+	gw := net.ParseIP(requestedGateway)
+	if gw == nil {
+		return nil, fmt.Errorf("Bad parameter: invalid gateway ip %s", requestedGateway)
+	}
+	if !d.subnet.Contains(gw) {
+		return nil, fmt.Errorf("Gateway ip %s must be part of the network %s", requestedGateway, d.subnet.String())
+	}
+	return d.ipAllocator.RequestIP(d.subnet, gw)
+}
+
+// Allocate assigns an address to the container and creates its network
+// namespace holding a macvlan subinterface of the parent as eth0.
+func (d *Driver) Allocate(id, requestedMac, requestedIP string) (*network.Settings, error) {
+	d.Lock()
+	defer d.Unlock()
+
+	ip, err := d.ipAllocator.RequestIP(d.subnet, net.ParseIP(requestedIP))
+	if err != nil {
+		return nil, err
+	}
+
+	mac, err := net.ParseMAC(requestedMac)
+	if err != nil {
+		mac = networkdriver.GenerateMacAddr(ip)
+	}
+
+	path := sandbox.Path(id)
+	if err := d.createInterface(path, mac, ip); err != nil {
+		d.ipAllocator.ReleaseIP(d.subnet, ip)
+		return nil, err
+	}
+
+	d.interfaces[id] = &networkInterface{
+		IP:      ip,
+		Sandbox: path,
+	}
+
+	maskSize, _ := d.subnet.Mask.Size()
+	return &network.Settings{
+		IPAddress:   ip.String(),
+		IPPrefixLen: maskSize,
+		Gateway:     d.gateway.String(),
+		MacAddress:  mac.String(),
+	}, nil
+}
+
+// Reattach takes over the network namespace of a container which kept
+// running while the daemon was down, and reserves its address again.
+func (d *Driver) Reattach(id, requestedIP string) error {
+	d.Lock()
+	defer d.Unlock()
+
+	ip := net.ParseIP(requestedIP)
+	if ip == nil {
+		return fmt.Errorf("Invalid address %q to reattach container %s", requestedIP, id)
+	}
+
+	path := sandbox.Path(id)
+	if err := sandbox.Check(path); err != nil {
+		return err
+	}
+	if _, err := d.ipAllocator.RequestIP(d.subnet, ip); err != nil {
+		return err
+	}
+
+	d.interfaces[id] = &networkInterface{
+		IP:      ip,
+		Sandbox: path,
+	}
+	return nil
+}
+
+func (d *Driver) createInterface(path string, mac net.HardwareAddr, ip net.IP) (err error) {
+	if err := sandbox.Create(path); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			sandbox.Remove(path)
+		}
+	}()
+
+	name, err := utils.GenerateRandomName("mv", 7)
+	if err != nil {
+		return err
+	}
+	if err := netlink.NetworkLinkAddMacVlan(d.parent, name, macvlanMode); err != nil {
+		return fmt.Errorf("Unable to create macvlan interface on %s: %v", d.parent, err)
+	}
+	if err := sandbox.MoveInterface(name, path); err != nil {
+		netlink.NetworkLinkDel(name)
+		return err
+	}
+
+	return sandbox.Do(path, func() error {
+		iface, err := net.InterfaceByName(name)
+		if err != nil {
+			return err
+		}
+		if err := netlink.NetworkChangeName(iface, "eth0"); err != nil {
+			return err
+		}
+		if iface, err = net.InterfaceByName("eth0"); err != nil {
+			return err
+		}
+		if err := netlink.NetworkSetMacAddress(iface, mac.String()); err != nil {
+			return err
+		}
+		if err := netlink.NetworkLinkAddIp(iface, ip, &net.IPNet{IP: ip, Mask: d.subnet.Mask}); err != nil {
+			return err
+		}
+		if err := netlink.NetworkLinkUp(iface); err != nil {
+			return err
+		}
+		return netlink.AddDefaultGw(d.gateway.String(), "eth0")
+	})
+}
+
+// NamespacePath returns the network namespace allocated to the container,
+// or an empty string if there is none.
+func (d *Driver) NamespacePath(id string) string {
+	d.Lock()
+	defer d.Unlock()
+	if i, exists := d.interfaces[id]; exists {
+		return i.Sandbox
+	}
+	return ""
+}
+
+// Release removes the container's network namespace and frees its address
+func (d *Driver) Release(id string) {
+	d.Lock()
+	defer d.Unlock()
+
+	containerInterface, exists := d.interfaces[id]
+	if !exists {
+		logrus.Warnf("No network information to release for %s", id)
+		return
+	}
+	delete(d.interfaces, id)
+
+	if err := sandbox.Remove(containerInterface.Sandbox); err != nil {
+		logrus.Infof("Unable to remove network namespace %s: %s", containerInterface.Sandbox, err)
+	}
+	if err := d.ipAllocator.ReleaseIP(d.subnet, containerInterface.IP); err != nil {
+		logrus.Infof("Unable to release IPv4 %s", err)
+	}
+}
@@ -0,0 +1,143 @@
+package macvlan
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/daemon/networkdriver/sandbox"
+	"github.com/docker/libcontainer/netlink"
+)
+
+func TestParseVlanParent(t *testing.T) {
+	valid := map[string]struct {
+		master string
+		vid    uint16
+	}{
+		"eth0.10":     {"eth0", 10},
+		"eth0.1":      {"eth0", 1},
+		"bond0.4094":  {"bond0", 4094},
+		"en.p1s0.200": {"en.p1s0", 200},
+	}
+	for name, expected := range valid {
+		master, vid, err := parseVlanParent(name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if master != expected.master || vid != expected.vid {
+			t.Fatalf("%s: expected %s/%d, got %s/%d", name, expected.master, expected.vid, master, vid)
+		}
+	}
+
+	invalid := []string{"eth0", "eth0.", ".10", "eth0.0", "eth0.4095", "eth0.foo"}
+	for _, name := range invalid {
+		if _, _, err := parseVlanParent(name); err == nil {
+			t.Fatalf("Expected an error parsing %s", name)
+		}
+	}
+}
+
+func TestNewRequiresSubnet(t *testing.T) {
+	if _, err := New(&Config{Parent: "lo"}); err == nil {
+		t.Fatal("Expected an error without a subnet")
+	}
+	if _, err := New(&Config{Parent: "lo", Subnet: "2001:db8::/64"}); err == nil {
+		t.Fatal("Expected an error with an IPv6 subnet")
+	}
+}
+
+// TestAllocateOnDummyParent runs the driver inside a scratch network
+// namespace with a dummy interface standing in for the physical parent.
+func TestAllocateOnDummyParent(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "docker-macvlan-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	sandbox.Root = filepath.Join(tmp, "netns")
+
+	host := filepath.Join(tmp, "host")
+	if err := sandbox.Create(host); err != nil {
+		t.Skipf("Unable to create a network namespace: %v", err)
+	}
+	defer sandbox.Remove(host)
+
+	if err := sandbox.Do(host, func() error {
+		return netlink.NetworkLinkAdd("dummy0", "dummy")
+	}); err != nil {
+		t.Skipf("Unable to create a dummy interface: %v", err)
+	}
+
+	err = sandbox.Do(host, func() error {
+		d, err := New(&Config{Parent: "dummy0.10", Subnet: "192.168.10.0/24"})
+		if err != nil {
+			return err
+		}
+		if _, err := net.InterfaceByName("dummy0.10"); err != nil {
+			return fmt.Errorf("VLAN parent was not created: %v", err)
+		}
+		if d.gateway.String() != "192.168.10.1" {
+			return fmt.Errorf("Expected default gateway 192.168.10.1, got %s", d.gateway)
+		}
+
+		settings, err := d.Allocate("container_id", "", "")
+		if err != nil {
+			return err
+		}
+		defer d.Release("container_id")
+
+		if settings.IPAddress != "192.168.10.2" || settings.IPPrefixLen != 24 {
+			return fmt.Errorf("Unexpected address %s/%d", settings.IPAddress, settings.IPPrefixLen)
+		}
+		if settings.Gateway != "192.168.10.1" {
+			return fmt.Errorf("Unexpected gateway %s", settings.Gateway)
+		}
+
+		path := d.NamespacePath("container_id")
+		if path == "" {
+			return fmt.Errorf("No network namespace for the container")
+		}
+
+		// a restarted daemon takes over the namespace of a running container
+		delete(d.interfaces, "container_id")
+		d.ipAllocator.ReleaseIP(d.subnet, net.ParseIP(settings.IPAddress))
+		if err := d.Reattach("container_id", settings.IPAddress); err != nil {
+			return fmt.Errorf("Unable to reattach the container: %v", err)
+		}
+		if d.NamespacePath("container_id") != path {
+			return fmt.Errorf("Expected the namespace %s to be taken over, got %q", path, d.NamespacePath("container_id"))
+		}
+		if _, err := d.Allocate("other_id", "", settings.IPAddress); err == nil {
+			return fmt.Errorf("Allocated %s twice after reattaching", settings.IPAddress)
+		}
+		if err := d.Reattach("missing_id", "192.168.10.3"); err == nil {
+			return fmt.Errorf("Expected reattaching a container without a namespace to fail")
+		}
+
+		return sandbox.Do(path, func() error {
+			iface, err := net.InterfaceByName("eth0")
+			if err != nil {
+				return err
+			}
+			if iface.HardwareAddr.String() != settings.MacAddress {
+				return fmt.Errorf("Expected MAC %s, got %s", settings.MacAddress, iface.HardwareAddr)
+			}
+			addrs, err := iface.Addrs()
+			if err != nil {
+				return err
+			}
+			for _, addr := range addrs {
+				if addr.String() == "192.168.10.2/24" {
+					return nil
+				}
+			}
+			return fmt.Errorf("Address not found on eth0: %v", addrs)
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
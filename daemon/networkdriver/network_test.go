@@ -173,3 +173,19 @@ func TestNetworkRange(t *testing.T) {
 		t.Error(last.String())
 	}
 }
+
+func TestMacAddrGeneration(t *testing.T) {
+	ip := net.ParseIP("192.168.0.1")
+	mac := GenerateMacAddr(ip).String()
+
+	// Should be consistent.
+	if GenerateMacAddr(ip).String() != mac {
+		t.Fatal("Inconsistent MAC address")
+	}
+
+	// Should be unique.
+	ip2 := net.ParseIP("192.168.0.2")
+	if GenerateMacAddr(ip2).String() == mac {
+		t.Fatal("Non-unique MAC address")
+	}
+}
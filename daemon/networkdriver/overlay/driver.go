@@ -0,0 +1,661 @@
+package overlay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/daemon/network"
+	"github.com/docker/docker/daemon/networkdriver"
+	"github.com/docker/docker/daemon/networkdriver/ipallocator"
+	"github.com/docker/docker/daemon/networkdriver/sandbox"
+	"github.com/docker/libcontainer/netlink"
+	"github.com/docker/libcontainer/utils"
+)
+
+const (
+	// VXLAN adds 50 bytes of headers to every frame
+	defaultMtu   = 1450
+	syncInterval = 5 * time.Second
+)
+
+var ErrNoSuchNetwork = errors.New("no such overlay network")
+
+type Config struct {
+	Store       string
+	BindAddress string
+	Networks    []string
+}
+
+// endpointRecord is what the store knows about a container attached to an
+// overlay network.
+type endpointRecord struct {
+	ID   string `json:"id"`
+	IP   string `json:"ip"`
+	Mac  string `json:"mac"`
+	Host string `json:"host"`
+}
+
+// endpoint is a container of this host attached to an overlay network
+type endpoint struct {
+	network *overlayNetwork
+	ip      net.IP
+	sandbox string
+}
+
+// Driver attaches containers to VXLAN overlay networks spanning all the
+// hosts sharing its store.
+type Driver struct {
+	store     Store
+	bindIP    net.IP
+	networks  map[string]*overlayNetwork
+	endpoints map[string]*endpoint
+	stop      chan struct{}
+	sync.Mutex
+}
+
+// New creates the driver and the overlay networks described by config.
+func New(config *Config) (*Driver, error) {
+	store, err := NewStore(config.Store)
+	if err != nil {
+		return nil, err
+	}
+	bindIP := net.ParseIP(config.BindAddress)
+	if bindIP == nil || bindIP.To4() == nil {
+		return nil, fmt.Errorf("A valid IPv4 bind address is required for overlay networks, got %q", config.BindAddress)
+	}
+	for _, name := range []string{"ip", "bridge"} {
+		if _, err := exec.LookPath(name); err != nil {
+			return nil, fmt.Errorf("Overlay networks require the %s command of iproute2: %v", name, err)
+		}
+	}
+
+	d := NewDriver(store, bindIP)
+	for _, spec := range config.Networks {
+		name, vni, subnet, err := parseNetworkSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		if err := d.AddNetwork(name, vni, subnet); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// NewDriver returns a driver without any network, sending VXLAN traffic
+// from bindIP.
+func NewDriver(store Store, bindIP net.IP) *Driver {
+	return &Driver{
+		store:     store,
+		bindIP:    bindIP,
+		networks:  make(map[string]*overlayNetwork),
+		endpoints: make(map[string]*endpoint),
+	}
+}
+
+// parseNetworkSpec parses an overlay network given as name:vni:subnet
+func parseNetworkSpec(spec string) (string, uint32, *net.IPNet, error) {
+	parts := strings.SplitN(spec, ":", 3)
+	if len(parts) != 3 || parts[0] == "" {
+		return "", 0, nil, fmt.Errorf("Invalid overlay network %s, expected name:vni:subnet", spec)
+	}
+	vni, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil || vni < 1 || vni > 1<<24-1 {
+		return "", 0, nil, fmt.Errorf("Invalid VXLAN id %s for overlay network %s", parts[1], parts[0])
+	}
+	_, subnet, err := net.ParseCIDR(parts[2])
+	if err != nil {
+		return "", 0, nil, err
+	}
+	if subnet.IP.To4() == nil {
+		return "", 0, nil, fmt.Errorf("Overlay network %s: %s is not an IPv4 subnet", parts[0], parts[2])
+	}
+	return parts[0], uint32(vni), subnet, nil
+}
+
+// AddNetwork sets up the bridge and VXLAN interface of an overlay network
+// on this host and announces the host in the store.
+func (d *Driver) AddNetwork(name string, vni uint32, subnet *net.IPNet) error {
+	d.Lock()
+	defer d.Unlock()
+
+	if _, exists := d.networks[name]; exists {
+		return fmt.Errorf("Overlay network %s already exists", name)
+	}
+	for _, n := range d.networks {
+		if n.vni == vni {
+			return fmt.Errorf("VXLAN id %d is already used by overlay network %s", vni, n.name)
+		}
+	}
+
+	n := newOverlayNetwork(name, vni, subnet)
+	if err := n.setup(d.bindIP); err != nil {
+		return err
+	}
+
+	host, err := json.Marshal(d.bindIP.String())
+	if err != nil {
+		return err
+	}
+	if err := d.store.Put(n.hostKey(d.bindIP.String()), host); err != nil {
+		return err
+	}
+	d.networks[name] = n
+	return nil
+}
+
+// Allocate reserves an address on the named network and creates the
+// container's network namespace with a veth interface on the network's
+// bridge as eth0.
+func (d *Driver) Allocate(id, name, requestedMac, requestedIP string) (*network.Settings, error) {
+	d.Lock()
+	defer d.Unlock()
+
+	n, exists := d.networks[name]
+	if !exists {
+		return nil, ErrNoSuchNetwork
+	}
+
+	ip, err := d.reserveIP(n, net.ParseIP(requestedIP))
+	if err != nil {
+		return nil, err
+	}
+
+	mac, err := net.ParseMAC(requestedMac)
+	if err != nil {
+		mac = networkdriver.GenerateMacAddr(ip)
+	}
+
+	record, err := json.Marshal(&endpointRecord{
+		ID:   id,
+		IP:   ip.String(),
+		Mac:  mac.String(),
+		Host: d.bindIP.String(),
+	})
+	if err == nil {
+		err = d.store.Put(n.endpointKey(ip), record)
+	}
+	if err == nil {
+		err = n.createInterface(sandbox.Path(id), mac, ip)
+	}
+	if err != nil {
+		d.store.Delete(n.endpointKey(ip))
+		n.ipAllocator.ReleaseIP(n.subnet, ip)
+		return nil, err
+	}
+
+	d.endpoints[id] = &endpoint{
+		network: n,
+		ip:      ip,
+		sandbox: sandbox.Path(id),
+	}
+
+	maskSize, _ := n.subnet.Mask.Size()
+	return &network.Settings{
+		IPAddress:   ip.String(),
+		IPPrefixLen: maskSize,
+		MacAddress:  mac.String(),
+		Bridge:      n.bridge,
+	}, nil
+}
+
+// Reattach takes over the endpoint of a container which kept running while
+// the daemon was down: its address, still claimed in the store, and the
+// network namespace holding its interface.
+func (d *Driver) Reattach(id, name, mac, requestedIP string) error {
+	d.Lock()
+	defer d.Unlock()
+
+	n, exists := d.networks[name]
+	if !exists {
+		return ErrNoSuchNetwork
+	}
+	ip := net.ParseIP(requestedIP)
+	if ip == nil {
+		return fmt.Errorf("Invalid address %q to reattach container %s", requestedIP, id)
+	}
+
+	// the endpoint is left in the store when the daemon stops, the address
+	// must not have been claimed by another container since
+	value, err := d.store.Get(n.endpointKey(ip))
+	switch err {
+	case nil:
+		var record endpointRecord
+		if err := json.Unmarshal(value, &record); err != nil {
+			return err
+		}
+		if record.ID != id {
+			return ipallocator.ErrIPAlreadyAllocated
+		}
+	case ErrKeyNotFound:
+	default:
+		return err
+	}
+
+	path := sandbox.Path(id)
+	if err := sandbox.Check(path); err != nil {
+		return err
+	}
+
+	record, err := json.Marshal(&endpointRecord{
+		ID:   id,
+		IP:   ip.String(),
+		Mac:  mac,
+		Host: d.bindIP.String(),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := n.ipAllocator.RequestIP(n.subnet, ip); err != nil {
+		return err
+	}
+	if err := d.store.Put(n.endpointKey(ip), record); err != nil {
+		n.ipAllocator.ReleaseIP(n.subnet, ip)
+		return err
+	}
+
+	d.endpoints[id] = &endpoint{
+		network: n,
+		ip:      ip,
+		sandbox: path,
+	}
+	return nil
+}
+
+// Purge removes the endpoints this host left in the store for containers it
+// didn't reattach to, which the daemon couldn't release when it went down.
+func (d *Driver) Purge() {
+	d.Lock()
+	defer d.Unlock()
+
+	for _, n := range d.networks {
+		values, err := d.store.List(n.key("endpoints"))
+		if err != nil {
+			logrus.Errorf("Unable to list the endpoints of overlay network %s: %s", n.name, err)
+			continue
+		}
+		for _, value := range values {
+			var record endpointRecord
+			if err := json.Unmarshal(value, &record); err != nil || record.Host != d.bindIP.String() {
+				continue
+			}
+			if ep, exists := d.endpoints[record.ID]; exists && ep.network == n {
+				continue
+			}
+			if err := d.store.Delete(n.endpointKey(net.ParseIP(record.IP))); err != nil {
+				logrus.Warnf("Unable to remove stale overlay endpoint %s: %s", record.IP, err)
+			}
+		}
+	}
+}
+
+// reserveIP picks an address which no host of the network has claimed in
+// the store yet.
+func (d *Driver) reserveIP(n *overlayNetwork, requestedIP net.IP) (net.IP, error) {
+	for {
+		ip, err := n.ipAllocator.RequestIP(n.subnet, requestedIP)
+		if err != nil {
+			return nil, err
+		}
+		// claim the address with a placeholder, the endpoint is
+		// filled in once its interface exists
+		err = d.store.Create(n.endpointKey(ip), []byte("{}"))
+		if err == nil {
+			return ip, nil
+		}
+		if err != ErrKeyExists {
+			n.ipAllocator.ReleaseIP(n.subnet, ip)
+			return nil, err
+		}
+		// taken by another host, keep it marked as allocated here
+		if requestedIP != nil {
+			return nil, ipallocator.ErrIPAlreadyAllocated
+		}
+	}
+}
+
+// NamespacePath returns the network namespace allocated to the container,
+// or an empty string if there is none.
+func (d *Driver) NamespacePath(id string) string {
+	d.Lock()
+	defer d.Unlock()
+	if ep, exists := d.endpoints[id]; exists {
+		return ep.sandbox
+	}
+	return ""
+}
+
+// Release removes the container from its overlay network
+func (d *Driver) Release(id string) {
+	d.Lock()
+	defer d.Unlock()
+
+	ep, exists := d.endpoints[id]
+	if !exists {
+		logrus.Warnf("No overlay network information to release for %s", id)
+		return
+	}
+	delete(d.endpoints, id)
+
+	if err := d.store.Delete(ep.network.endpointKey(ep.ip)); err != nil {
+		logrus.Infof("Unable to remove overlay endpoint %s from the store: %s", ep.ip, err)
+	}
+	if err := sandbox.Remove(ep.sandbox); err != nil {
+		logrus.Infof("Unable to remove network namespace %s: %s", ep.sandbox, err)
+	}
+	ep.network.ipAllocator.ReleaseIP(ep.network.subnet, ep.ip)
+}
+
+// Start periodically programs the endpoints and hosts found in the store.
+func (d *Driver) Start() {
+	d.stop = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(syncInterval)
+		defer ticker.Stop()
+		for {
+			if err := d.sync(); err != nil {
+				logrus.Errorf("Error synchronizing overlay networks: %s", err)
+			}
+			select {
+			case <-ticker.C:
+			case <-d.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the synchronization and withdraws this host from the store.
+func (d *Driver) Stop() {
+	if d.stop != nil {
+		close(d.stop)
+	}
+	d.Lock()
+	defer d.Unlock()
+	for _, n := range d.networks {
+		d.store.Delete(n.hostKey(d.bindIP.String()))
+	}
+}
+
+// sync brings the forwarding database and neighbour entries of every
+// network in line with the endpoints and hosts in the store.
+func (d *Driver) sync() error {
+	d.Lock()
+	defer d.Unlock()
+
+	for _, n := range d.networks {
+		hosts, err := d.remoteHosts(n)
+		if err != nil {
+			return err
+		}
+		peers, err := d.remoteEndpoints(n)
+		if err != nil {
+			return err
+		}
+		if err := n.program(hosts, peers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Driver) remoteHosts(n *overlayNetwork) (map[string]bool, error) {
+	values, err := d.store.List(n.key("hosts"))
+	if err != nil {
+		return nil, err
+	}
+	hosts := make(map[string]bool)
+	for host := range values {
+		if host != d.bindIP.String() {
+			hosts[host] = true
+		}
+	}
+	return hosts, nil
+}
+
+func (d *Driver) remoteEndpoints(n *overlayNetwork) (map[string]*endpointRecord, error) {
+	values, err := d.store.List(n.key("endpoints"))
+	if err != nil {
+		return nil, err
+	}
+	peers := make(map[string]*endpointRecord)
+	for _, value := range values {
+		var record endpointRecord
+		if err := json.Unmarshal(value, &record); err != nil {
+			logrus.Warnf("Ignoring invalid overlay endpoint in %s: %s", n.name, err)
+			continue
+		}
+		// skip our own endpoints and addresses still being set up
+		if record.Host == "" || record.Host == d.bindIP.String() {
+			continue
+		}
+		peers[record.IP] = &record
+	}
+	return peers, nil
+}
+
+// overlayNetwork is the local part of an overlay network: a bridge for the
+// containers of this host and a VXLAN interface on it towards the others.
+type overlayNetwork struct {
+	name        string
+	vni         uint32
+	subnet      *net.IPNet
+	bridge      string
+	vxlan       string
+	ipAllocator *ipallocator.IPAllocator
+
+	// remote hosts and endpoints programmed on the VXLAN interface
+	hosts map[string]bool
+	peers map[string]*endpointRecord
+}
+
+func newOverlayNetwork(name string, vni uint32, subnet *net.IPNet) *overlayNetwork {
+	return &overlayNetwork{
+		name:        name,
+		vni:         vni,
+		subnet:      subnet,
+		bridge:      fmt.Sprintf("ov-%d", vni),
+		vxlan:       fmt.Sprintf("vx-%d", vni),
+		ipAllocator: ipallocator.New(),
+		hosts:       make(map[string]bool),
+		peers:       make(map[string]*endpointRecord),
+	}
+}
+
+func (n *overlayNetwork) key(parts ...string) string {
+	return strings.Join(append([]string{"overlay", n.name}, parts...), "/")
+}
+
+func (n *overlayNetwork) hostKey(host string) string {
+	return n.key("hosts", host)
+}
+
+func (n *overlayNetwork) endpointKey(ip net.IP) string {
+	return n.key("endpoints", ip.String())
+}
+
+func (n *overlayNetwork) setup(bindIP net.IP) error {
+	// a VXLAN interface left over by a previous run has stale entries, while
+	// the bridge is kept for the containers that kept running
+	if _, err := net.InterfaceByName(n.vxlan); err == nil {
+		if err := netlink.NetworkLinkDel(n.vxlan); err != nil {
+			return err
+		}
+	}
+
+	created := false
+	if _, err := net.InterfaceByName(n.bridge); err != nil {
+		if err := netlink.CreateBridge(n.bridge, true); err != nil {
+			return fmt.Errorf("Unable to create bridge %s: %v", n.bridge, err)
+		}
+		created = true
+	}
+	// learning is left to the store, ARP requests are answered from the
+	// neighbour entries of the VXLAN interface
+	if err := runCommand("ip", "link", "add", n.vxlan, "type", "vxlan",
+		"id", strconv.FormatUint(uint64(n.vni), 10),
+		"local", bindIP.String(),
+		"dstport", "4789",
+		"nolearning", "proxy"); err != nil {
+		if created {
+			netlink.NetworkLinkDel(n.bridge)
+		}
+		return err
+	}
+
+	bridge, err := net.InterfaceByName(n.bridge)
+	if err != nil {
+		return err
+	}
+	vxlan, err := net.InterfaceByName(n.vxlan)
+	if err != nil {
+		return err
+	}
+	if err := netlink.NetworkSetMaster(vxlan, bridge); err != nil {
+		return err
+	}
+	if err := netlink.NetworkSetMTU(vxlan, defaultMtu); err != nil {
+		return err
+	}
+	if err := netlink.NetworkLinkUp(vxlan); err != nil {
+		return err
+	}
+	return netlink.NetworkLinkUp(bridge)
+}
+
+func (n *overlayNetwork) createInterface(path string, mac net.HardwareAddr, ip net.IP) (err error) {
+	if err := sandbox.Create(path); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			sandbox.Remove(path)
+		}
+	}()
+
+	hostName, err := utils.GenerateRandomName("veth", 7)
+	if err != nil {
+		return err
+	}
+	peerName, err := utils.GenerateRandomName("veth", 7)
+	if err != nil {
+		return err
+	}
+	if err := netlink.NetworkCreateVethPair(hostName, peerName, 0); err != nil {
+		return err
+	}
+	host, err := net.InterfaceByName(hostName)
+	if err != nil {
+		return err
+	}
+	bridge, err := net.InterfaceByName(n.bridge)
+	if err != nil {
+		return err
+	}
+	if err := netlink.NetworkSetMTU(host, defaultMtu); err != nil {
+		return err
+	}
+	if err := netlink.NetworkSetMaster(host, bridge); err != nil {
+		return err
+	}
+	if err := netlink.NetworkLinkUp(host); err != nil {
+		return err
+	}
+	if err := sandbox.MoveInterface(peerName, path); err != nil {
+		netlink.NetworkLinkDel(hostName)
+		return err
+	}
+
+	return sandbox.Do(path, func() error {
+		iface, err := net.InterfaceByName(peerName)
+		if err != nil {
+			return err
+		}
+		if err := netlink.NetworkChangeName(iface, "eth0"); err != nil {
+			return err
+		}
+		if iface, err = net.InterfaceByName("eth0"); err != nil {
+			return err
+		}
+		if err := netlink.NetworkSetMacAddress(iface, mac.String()); err != nil {
+			return err
+		}
+		if err := netlink.NetworkSetMTU(iface, defaultMtu); err != nil {
+			return err
+		}
+		if err := netlink.NetworkLinkAddIp(iface, ip, &net.IPNet{IP: ip, Mask: n.subnet.Mask}); err != nil {
+			return err
+		}
+		return netlink.NetworkLinkUp(iface)
+	})
+}
+
+// program adds and removes the forwarding entries of the VXLAN interface.
+// Broadcasts are flooded to every remote host, while unicast frames and
+// ARP replies for remote endpoints are served from static entries.
+func (n *overlayNetwork) program(hosts map[string]bool, peers map[string]*endpointRecord) error {
+	for host := range hosts {
+		if n.hosts[host] {
+			continue
+		}
+		if err := runCommand("bridge", "fdb", "append", "00:00:00:00:00:00", "dev", n.vxlan, "dst", host); err != nil {
+			return err
+		}
+		n.hosts[host] = true
+	}
+	for host := range n.hosts {
+		if hosts[host] {
+			continue
+		}
+		if err := runCommand("bridge", "fdb", "del", "00:00:00:00:00:00", "dev", n.vxlan, "dst", host); err != nil {
+			logrus.Warnf("Unable to remove overlay host %s: %s", host, err)
+		}
+		delete(n.hosts, host)
+	}
+
+	for ip, peer := range peers {
+		if old, exists := n.peers[ip]; exists && *old == *peer {
+			continue
+		}
+		if err := runCommand("bridge", "fdb", "replace", peer.Mac, "dev", n.vxlan, "dst", peer.Host, "self", "permanent"); err != nil {
+			return err
+		}
+		if err := runCommand("ip", "neigh", "replace", peer.IP, "lladdr", peer.Mac, "dev", n.vxlan, "nud", "permanent"); err != nil {
+			return err
+		}
+		// the address is in use on another host
+		n.ipAllocator.RequestIP(n.subnet, net.ParseIP(peer.IP))
+		n.peers[ip] = peer
+	}
+	for ip, peer := range n.peers {
+		if _, exists := peers[ip]; exists {
+			continue
+		}
+		if err := runCommand("bridge", "fdb", "del", peer.Mac, "dev", n.vxlan, "self"); err != nil {
+			logrus.Warnf("Unable to remove overlay endpoint %s: %s", ip, err)
+		}
+		if err := runCommand("ip", "neigh", "del", peer.IP, "dev", n.vxlan); err != nil {
+			logrus.Warnf("Unable to remove overlay endpoint %s: %s", ip, err)
+		}
+		n.ipAllocator.ReleaseIP(n.subnet, net.ParseIP(peer.IP))
+		delete(n.peers, ip)
+	}
+	return nil
+}
+
+// runCommand runs one of the iproute2 tools, which know how to set up
+// VXLAN interfaces and their forwarding entries.
+func runCommand(name string, args ...string) error {
+	if output, err := exec.Command(name, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s %s failed: %s (%s)", name, strings.Join(args, " "), strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package overlay
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/daemon/networkdriver/sandbox"
+	"github.com/docker/libcontainer/netlink"
+)
+
+func TestParseNetworkSpec(t *testing.T) {
+	name, vni, subnet, err := parseNetworkSpec("blue:256:10.10.0.0/16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "blue" || vni != 256 || subnet.String() != "10.10.0.0/16" {
+		t.Fatalf("Unexpected network %s:%d:%s", name, vni, subnet)
+	}
+
+	invalid := []string{
+		"blue",
+		"blue:256",
+		":256:10.10.0.0/16",
+		"blue:0:10.10.0.0/16",
+		"blue:16777216:10.10.0.0/16",
+		"blue:256:10.10.0.0",
+		"blue:256:2001:db8::/64",
+	}
+	for _, spec := range invalid {
+		if _, _, _, err := parseNetworkSpec(spec); err == nil {
+			t.Fatalf("Expected an error parsing %s", spec)
+		}
+	}
+}
+
+func TestNewRequiresBindAddress(t *testing.T) {
+	if _, err := New(&Config{Networks: []string{"blue:256:10.10.0.0/16"}}); err == nil {
+		t.Fatal("Expected an error without a bind address")
+	}
+}
+
+func TestPurge(t *testing.T) {
+	store := NewMemoryStore()
+	d := NewDriver(store, net.ParseIP("192.168.99.1"))
+	_, subnet, _ := net.ParseCIDR("10.10.0.0/24")
+	n := newOverlayNetwork("blue", 256, subnet)
+	d.networks["blue"] = n
+
+	records := []endpointRecord{
+		{ID: "reattached", IP: "10.10.0.2", Host: "192.168.99.1"},
+		{ID: "stale", IP: "10.10.0.3", Host: "192.168.99.1"},
+		{ID: "remote", IP: "10.10.0.4", Host: "192.168.99.2"},
+	}
+	for _, r := range records {
+		value, err := json.Marshal(&r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Put(n.endpointKey(net.ParseIP(r.IP)), value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d.endpoints["reattached"] = &endpoint{network: n, ip: net.ParseIP("10.10.0.2")}
+
+	d.Purge()
+
+	for _, r := range records {
+		_, err := store.Get(n.endpointKey(net.ParseIP(r.IP)))
+		if r.ID == "stale" && err != ErrKeyNotFound {
+			t.Fatalf("Expected the endpoint of %s to be purged, got %v", r.ID, err)
+		}
+		if r.ID != "stale" && err != nil {
+			t.Fatalf("Expected the endpoint of %s to be kept, got %v", r.ID, err)
+		}
+	}
+}
+
+// testHost is a network namespace standing in for a docker host
+type testHost struct {
+	path   string
+	driver *Driver
+}
+
+func (h *testHost) do(t *testing.T, fn func() error) {
+	if err := sandbox.Do(h.path, fn); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// setupHosts creates two namespaces connected by a veth pair, addressed
+// 192.168.99.1 and 192.168.99.2, each with a driver on the same store.
+func setupHosts(t *testing.T, tmp string) (*testHost, *testHost) {
+	sandbox.Root = filepath.Join(tmp, "netns")
+	store := NewMemoryStore()
+
+	hosts := []*testHost{
+		{path: filepath.Join(tmp, "host1")},
+		{path: filepath.Join(tmp, "host2")},
+	}
+	for _, h := range hosts {
+		if err := sandbox.Create(h.path); err != nil {
+			t.Skipf("Unable to create a network namespace: %v", err)
+		}
+	}
+
+	hosts[0].do(t, func() error {
+		if err := netlink.NetworkCreateVethPair("wire1", "wire2", 0); err != nil {
+			return err
+		}
+		return sandbox.MoveInterface("wire2", hosts[1].path)
+	})
+	for i, h := range hosts {
+		name := []string{"wire1", "wire2"}[i]
+		ip := net.IPv4(192, 168, 99, byte(i+1))
+		h.driver = NewDriver(store, ip)
+		h.do(t, func() error {
+			iface, err := net.InterfaceByName(name)
+			if err != nil {
+				return err
+			}
+			if err := netlink.NetworkLinkAddIp(iface, ip, &net.IPNet{IP: ip, Mask: net.CIDRMask(24, 32)}); err != nil {
+				return err
+			}
+			return netlink.NetworkLinkUp(iface)
+		})
+	}
+	return hosts[0], hosts[1]
+}
+
+func TestOverlayAcrossHosts(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "docker-overlay-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	host1, host2 := setupHosts(t, tmp)
+	defer sandbox.Remove(host1.path)
+	defer sandbox.Remove(host2.path)
+
+	_, subnet, _ := net.ParseCIDR("10.10.0.0/24")
+	for _, h := range []*testHost{host1, host2} {
+		driver := h.driver
+		if err := sandbox.Do(h.path, func() error {
+			return driver.AddNetwork("blue", 256, subnet)
+		}); err != nil {
+			t.Skipf("Unable to set up the overlay network: %v", err)
+		}
+	}
+
+	var ip1, ip2 string
+	host1.do(t, func() error {
+		settings, err := host1.driver.Allocate("container1", "blue", "", "")
+		if err != nil {
+			return err
+		}
+		ip1 = settings.IPAddress
+		return nil
+	})
+	host2.do(t, func() error {
+		settings, err := host2.driver.Allocate("container2", "blue", "", "")
+		if err != nil {
+			return err
+		}
+		ip2 = settings.IPAddress
+		return nil
+	})
+	if ip1 == ip2 {
+		t.Fatalf("Both containers got %s", ip1)
+	}
+
+	if _, err := host1.driver.Allocate("container3", "blue", "", ip2); err == nil {
+		t.Fatalf("Allocated %s twice", ip2)
+	}
+	if _, err := host1.driver.Allocate("container3", "red", "", ""); err != ErrNoSuchNetwork {
+		t.Fatalf("Expected %v, got %v", ErrNoSuchNetwork, err)
+	}
+
+	host1.do(t, host1.driver.sync)
+	host2.do(t, host2.driver.sync)
+
+	if peer := host1.driver.networks["blue"].peers[ip2]; peer == nil || peer.Host != "192.168.99.2" {
+		t.Fatalf("Endpoint %s of host2 not programmed on host1: %v", ip2, peer)
+	}
+
+	// connect from the container on host1 to the one on host2
+	var l net.Listener
+	if err := sandbox.Do(host2.driver.NamespacePath("container2"), func() error {
+		var err error
+		l, err = net.Listen("tcp", net.JoinHostPort(ip2, "0"))
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		if c, err := l.Accept(); err == nil {
+			c.Write([]byte("ok"))
+			c.Close()
+		}
+	}()
+	if err := sandbox.Do(host1.driver.NamespacePath("container1"), func() error {
+		c, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		buf, err := ioutil.ReadAll(c)
+		if err != nil {
+			return err
+		}
+		if string(buf) != "ok" {
+			return fmt.Errorf("Unexpected answer %q", buf)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a restarted daemon takes over the endpoint of a running container,
+	// while the address of another container can't be claimed
+	host2.do(t, func() error {
+		driver := host2.driver
+		ep := driver.endpoints["container2"]
+		delete(driver.endpoints, "container2")
+		ep.network.ipAllocator.ReleaseIP(ep.network.subnet, ep.ip)
+		if err := driver.Reattach("container3", "blue", "", ip2); err == nil {
+			return fmt.Errorf("Reattached container3 to the address %s of container2", ip2)
+		}
+		if err := driver.Reattach("container2", "blue", "", ip2); err != nil {
+			return err
+		}
+		if driver.NamespacePath("container2") != ep.sandbox {
+			return fmt.Errorf("Expected the namespace %s to be taken over, got %q", ep.sandbox, driver.NamespacePath("container2"))
+		}
+		return nil
+	})
+
+	host2.do(t, func() error {
+		host2.driver.Release("container2")
+		return nil
+	})
+	host1.do(t, host1.driver.sync)
+	if peer := host1.driver.networks["blue"].peers[ip2]; peer != nil {
+		t.Fatalf("Endpoint %s still programmed on host1 after release", ip2)
+	}
+	host1.do(t, func() error {
+		host1.driver.Release("container1")
+		return nil
+	})
+}
@@ -0,0 +1,241 @@
+package overlay
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrKeyExists   = errors.New("key already exists")
+	ErrKeyNotFound = errors.New("key not found")
+)
+
+// Store is the key-value store shared by all the hosts taking part in the
+// overlay networks. Keys are slash separated paths.
+type Store interface {
+	// Put sets the value of key, creating it if needed.
+	Put(key string, value []byte) error
+	// Create sets the value of key only if it does not exist yet and
+	// returns ErrKeyExists otherwise.
+	Create(key string, value []byte) error
+	// Get returns the value of key or ErrKeyNotFound.
+	Get(key string) ([]byte, error)
+	// Delete removes key or returns ErrKeyNotFound.
+	Delete(key string) error
+	// List returns the values of the keys directly under prefix, indexed
+	// by the last element of their key.
+	List(prefix string) (map[string][]byte, error)
+}
+
+// StoreFactory creates a store from the address part of a store URL.
+type StoreFactory func(addr string) (Store, error)
+
+var (
+	storesMu sync.Mutex
+	stores   = map[string]StoreFactory{
+		"memory": func(string) (Store, error) { return NewMemoryStore(), nil },
+		"file":   func(addr string) (Store, error) { return NewFileStore(addr) },
+	}
+)
+
+// RegisterStore makes a store implementation available for the given URL
+// scheme.
+func RegisterStore(scheme string, factory StoreFactory) error {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	if _, exists := stores[scheme]; exists {
+		return fmt.Errorf("Store %s is already registered", scheme)
+	}
+	stores[scheme] = factory
+	return nil
+}
+
+// NewStore returns the store for an URL of the form scheme://address. An
+// empty URL gives an in-process store, which only works for a single host.
+func NewStore(url string) (Store, error) {
+	if url == "" {
+		return NewMemoryStore(), nil
+	}
+	parts := strings.SplitN(url, "://", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("Invalid store URL %s, expected scheme://address", url)
+	}
+	storesMu.Lock()
+	factory, exists := stores[parts[0]]
+	storesMu.Unlock()
+	if !exists {
+		return nil, fmt.Errorf("Unknown store %s", parts[0])
+	}
+	return factory(parts[1])
+}
+
+type memoryStore struct {
+	data map[string][]byte
+	sync.Mutex
+}
+
+// NewMemoryStore returns a store that only lives in the current process.
+func NewMemoryStore() Store {
+	return &memoryStore{data: make(map[string][]byte)}
+}
+
+func (s *memoryStore) Put(key string, value []byte) error {
+	s.Lock()
+	s.data[key] = value
+	s.Unlock()
+	return nil
+}
+
+func (s *memoryStore) Create(key string, value []byte) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, exists := s.data[key]; exists {
+		return ErrKeyExists
+	}
+	s.data[key] = value
+	return nil
+}
+
+func (s *memoryStore) Get(key string) ([]byte, error) {
+	s.Lock()
+	defer s.Unlock()
+	value, exists := s.data[key]
+	if !exists {
+		return nil, ErrKeyNotFound
+	}
+	return value, nil
+}
+
+func (s *memoryStore) Delete(key string) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, exists := s.data[key]; !exists {
+		return ErrKeyNotFound
+	}
+	delete(s.data, key)
+	return nil
+}
+
+func (s *memoryStore) List(prefix string) (map[string][]byte, error) {
+	s.Lock()
+	defer s.Unlock()
+	prefix = strings.TrimSuffix(prefix, "/") + "/"
+	res := make(map[string][]byte)
+	for key, value := range s.data {
+		if name := strings.TrimPrefix(key, prefix); name != key && !strings.Contains(name, "/") {
+			res[name] = value
+		}
+	}
+	return res, nil
+}
+
+// fileStore keeps every key in its own file below root, so that a shared
+// filesystem can serve as the store for several hosts.
+type fileStore struct {
+	root string
+}
+
+// NewFileStore returns a store keeping its keys as files below root.
+func NewFileStore(root string) (Store, error) {
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return nil, err
+	}
+	return &fileStore{root: root}, nil
+}
+
+func (s *fileStore) path(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(filepath.Clean("/"+key)))
+}
+
+// writeTemp writes value to a hidden temporary file next to the key.
+func (s *fileStore) writeTemp(key string, value []byte) (string, error) {
+	dir := filepath.Dir(s.path(key))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	f, err := ioutil.TempFile(dir, ".tmp-")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := f.Write(value); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+func (s *fileStore) Put(key string, value []byte) error {
+	tmp, err := s.writeTemp(key, value)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path(key)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func (s *fileStore) Create(key string, value []byte) error {
+	tmp, err := s.writeTemp(key, value)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	// link(2) fails if the target exists, which makes the creation atomic
+	if err := os.Link(tmp, s.path(key)); err != nil {
+		if os.IsExist(err) {
+			return ErrKeyExists
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *fileStore) Get(key string) ([]byte, error) {
+	value, err := ioutil.ReadFile(s.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrKeyNotFound
+	}
+	return value, err
+}
+
+func (s *fileStore) Delete(key string) error {
+	err := os.Remove(s.path(key))
+	if os.IsNotExist(err) {
+		return ErrKeyNotFound
+	}
+	return err
+}
+
+func (s *fileStore) List(prefix string) (map[string][]byte, error) {
+	res := make(map[string][]byte)
+	dir := s.path(prefix)
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return res, nil
+		}
+		return nil, err
+	}
+	for _, fi := range fis {
+		if fi.IsDir() || strings.HasPrefix(fi.Name(), ".") {
+			continue
+		}
+		value, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		res[fi.Name()] = value
+	}
+	return res, nil
+}
@@ -0,0 +1,80 @@
+package overlay
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func testStore(t *testing.T, s Store) {
+	if _, err := s.Get("net/endpoints/10.0.0.2"); err != ErrKeyNotFound {
+		t.Fatalf("Expected %v, got %v", ErrKeyNotFound, err)
+	}
+
+	if err := s.Create("net/endpoints/10.0.0.2", []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Create("net/endpoints/10.0.0.2", []byte("b")); err != ErrKeyExists {
+		t.Fatalf("Expected %v, got %v", ErrKeyExists, err)
+	}
+	if value, err := s.Get("net/endpoints/10.0.0.2"); err != nil || string(value) != "a" {
+		t.Fatalf("Expected a, got %q (%v)", value, err)
+	}
+
+	if err := s.Put("net/endpoints/10.0.0.3", []byte("c")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put("net/endpoints/10.0.0.3", []byte("d")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put("net/hosts/192.168.0.1", []byte("h")); err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := s.List("net/endpoints")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 2 || string(values["10.0.0.2"]) != "a" || string(values["10.0.0.3"]) != "d" {
+		t.Fatalf("Unexpected listing %v", values)
+	}
+
+	if err := s.Delete("net/endpoints/10.0.0.2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("net/endpoints/10.0.0.2"); err != ErrKeyNotFound {
+		t.Fatalf("Expected %v, got %v", ErrKeyNotFound, err)
+	}
+	if values, err := s.List("net/endpoints/"); err != nil || len(values) != 1 {
+		t.Fatalf("Unexpected listing %v (%v)", values, err)
+	}
+	if values, err := s.List("missing"); err != nil || len(values) != 0 {
+		t.Fatalf("Unexpected listing %v (%v)", values, err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "docker-overlay-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	s, err := NewStore("file://" + tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, s)
+}
+
+func TestNewStoreInvalid(t *testing.T) {
+	for _, url := range []string{"/var/lib/docker/overlay", "etcd://127.0.0.1:4001"} {
+		if _, err := NewStore(url); err == nil {
+			t.Fatalf("Expected an error for %s", url)
+		}
+	}
+}
@@ -16,10 +16,16 @@ type mapping struct {
 	userlandProxy UserlandProxy
 	host          net.Addr
 	container     net.Addr
+	// chain holds the iptables rules of the mapping, if any
+	chain *iptables.Chain
 }
 
 var NewProxy = NewProxyCommand
 
+// iptablesForward adds and deletes the iptables rules of the mappings,
+// override it to mock out iptables
+var iptablesForward = forward
+
 var (
 	ErrUnknownBackendAddressType = errors.New("unknown container address type not supported")
 	ErrPortMappedForIP           = errors.New("port is already mapped to ip")
@@ -27,7 +33,12 @@ var (
 )
 
 type PortMapper struct {
-	chain *iptables.Chain
+	chain  *iptables.Chain
+	chain6 *iptables.Chain
+
+	// disableProxy skips the userland proxy and relies on the iptables
+	// rules alone, the host port is only held open to reserve it.
+	disableProxy bool
 
 	// udp:ip:port
 	currentMappings map[string]*mapping
@@ -51,6 +62,18 @@ func (pm *PortMapper) SetIptablesChain(c *iptables.Chain) {
 	pm.chain = c
 }
 
+// SetIp6tablesChain sets the chain forwarding IPv6 host ports. Without it
+// IPv6 host ports are always served by the userland proxy.
+func (pm *PortMapper) SetIp6tablesChain(c *iptables.Chain) {
+	pm.chain6 = c
+}
+
+// SetUserlandProxy sets whether new mappings start a userland proxy
+// process for the host port.
+func (pm *PortMapper) SetUserlandProxy(enabled bool) {
+	pm.disableProxy = !enabled
+}
+
 func (pm *PortMapper) Map(container net.Addr, hostIP net.IP, hostPort int) (host net.Addr, err error) {
 	pm.lock.Lock()
 	defer pm.lock.Unlock()
@@ -74,8 +97,6 @@ func (pm *PortMapper) Map(container net.Addr, hostIP net.IP, hostPort int) (host
 			host:      &net.TCPAddr{IP: hostIP, Port: allocatedHostPort},
 			container: container,
 		}
-
-		proxy = NewProxy(proto, hostIP, allocatedHostPort, container.(*net.TCPAddr).IP, container.(*net.TCPAddr).Port)
 	case *net.UDPAddr:
 		proto = "udp"
 		if allocatedHostPort, err = pm.Allocator.RequestPort(hostIP, proto, hostPort); err != nil {
@@ -87,8 +108,6 @@ func (pm *PortMapper) Map(container net.Addr, hostIP net.IP, hostPort int) (host
 			host:      &net.UDPAddr{IP: hostIP, Port: allocatedHostPort},
 			container: container,
 		}
-
-		proxy = NewProxy(proto, hostIP, allocatedHostPort, container.(*net.UDPAddr).IP, container.(*net.UDPAddr).Port)
 	default:
 		return nil, ErrUnknownBackendAddressType
 	}
@@ -106,14 +125,24 @@ func (pm *PortMapper) Map(container net.Addr, hostIP net.IP, hostPort int) (host
 	}
 
 	containerIP, containerPort := getIPAndPort(m.container)
-	if err := pm.forward(iptables.Append, m.proto, hostIP, allocatedHostPort, containerIP.String(), containerPort); err != nil {
+	chain, forwarded := pm.chainFor(hostIP, containerIP)
+	// the IPv6 chain doesn't DNAT the connections from the host to ::1, the
+	// userland proxy has to serve them
+	if pm.disableProxy && forwarded && hostIP.To4() != nil {
+		proxy = newDummyProxy(proto, hostIP, allocatedHostPort)
+	} else {
+		proxy = NewProxy(proto, hostIP, allocatedHostPort, containerIP, containerPort)
+	}
+	m.chain = chain
+
+	if err := iptablesForward(m.chain, iptables.Append, m.proto, hostIP, allocatedHostPort, containerIP.String(), containerPort); err != nil {
 		return nil, err
 	}
 
 	cleanup := func() error {
 		// need to undo the iptables rules before we return
 		proxy.Stop()
-		pm.forward(iptables.Delete, m.proto, hostIP, allocatedHostPort, containerIP.String(), containerPort)
+		iptablesForward(m.chain, iptables.Delete, m.proto, hostIP, allocatedHostPort, containerIP.String(), containerPort)
 		if err := pm.Allocator.ReleasePort(hostIP, m.proto, allocatedHostPort); err != nil {
 			return err
 		}
@@ -138,7 +167,7 @@ func (pm *PortMapper) ReMapAll() {
 	for _, data := range pm.currentMappings {
 		containerIP, containerPort := getIPAndPort(data.container)
 		hostIP, hostPort := getIPAndPort(data.host)
-		if err := pm.forward(iptables.Append, data.proto, hostIP, hostPort, containerIP.String(), containerPort); err != nil {
+		if err := iptablesForward(data.chain, iptables.Append, data.proto, hostIP, hostPort, containerIP.String(), containerPort); err != nil {
 			logrus.Errorf("Error on iptables add: %s", err)
 		}
 	}
@@ -160,7 +189,7 @@ func (pm *PortMapper) Unmap(host net.Addr) error {
 
 	containerIP, containerPort := getIPAndPort(data.container)
 	hostIP, hostPort := getIPAndPort(data.host)
-	if err := pm.forward(iptables.Delete, data.proto, hostIP, hostPort, containerIP.String(), containerPort); err != nil {
+	if err := iptablesForward(data.chain, iptables.Delete, data.proto, hostIP, hostPort, containerIP.String(), containerPort); err != nil {
 		logrus.Errorf("Error on iptables delete: %s", err)
 	}
 
@@ -193,9 +222,25 @@ func getIPAndPort(a net.Addr) (net.IP, int) {
 	return nil, 0
 }
 
-func (pm *PortMapper) forward(action iptables.Action, proto string, sourceIP net.IP, sourcePort int, containerIP string, containerPort int) error {
-	if pm.chain == nil {
+// chainFor returns the chain forwarding hostIP to containerIP and whether
+// iptables can forward between them at all. IPv6 host ports are only
+// forwarded to IPv6 containers, and only when ip6tables supports NAT.
+func (pm *PortMapper) chainFor(hostIP, containerIP net.IP) (*iptables.Chain, bool) {
+	if hostIP.To4() != nil {
+		if containerIP.To4() == nil {
+			return nil, false
+		}
+		return pm.chain, true
+	}
+	if containerIP.To4() != nil || pm.chain6 == nil {
+		return nil, false
+	}
+	return pm.chain6, true
+}
+
+func forward(chain *iptables.Chain, action iptables.Action, proto string, sourceIP net.IP, sourcePort int, containerIP string, containerPort int) error {
+	if chain == nil {
 		return nil
 	}
-	return pm.chain.Forward(action, sourceIP, sourcePort, proto, containerIP, containerPort)
+	return chain.Forward(action, sourceIP, sourcePort, proto, containerIP, containerPort)
 }
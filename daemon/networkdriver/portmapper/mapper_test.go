@@ -147,3 +147,125 @@ func TestMapAllPortsSingleInterface(t *testing.T) {
 		hosts = []net.Addr{}
 	}
 }
+
+func TestMapPortsWithoutUserlandProxy(t *testing.T) {
+	pm := New()
+	pm.SetUserlandProxy(false)
+
+	hostIP := net.ParseIP("127.0.0.1")
+	srcAddr := &net.TCPAddr{Port: 80, IP: net.ParseIP("172.16.0.1")}
+
+	host, err := pm.Map(srcAddr, hostIP, 0)
+	if err != nil {
+		t.Fatalf("Failed to allocate port: %s", err)
+	}
+
+	// the host port should be held while mapped
+	if l, err := net.Listen("tcp", host.String()); err == nil {
+		l.Close()
+		t.Fatalf("Port %s should be reserved", host)
+	}
+
+	if err := pm.Unmap(host); err != nil {
+		t.Fatalf("Failed to release port: %s", err)
+	}
+
+	l, err := net.Listen("tcp", host.String())
+	if err != nil {
+		t.Fatalf("Port %s should be released: %s", host, err)
+	}
+	l.Close()
+}
+
+func TestChainFor(t *testing.T) {
+	pm := New()
+	c := &iptables.Chain{Name: "TEST"}
+	c6 := &iptables.Chain{Name: "TEST", IPVersion: iptables.Ip6tables}
+	pm.SetIptablesChain(c)
+
+	v4 := net.ParseIP("172.16.0.1")
+	v6 := net.ParseIP("2001:db8::1")
+
+	if chain, ok := pm.chainFor(net.ParseIP("0.0.0.0"), v4); !ok || chain != c {
+		t.Fatalf("Expected IPv4 ports to be forwarded by %v, got %v", c, chain)
+	}
+	if _, ok := pm.chainFor(net.ParseIP("::"), v6); ok {
+		t.Fatal("IPv6 ports should not be forwarded without an ip6tables chain")
+	}
+
+	pm.SetIp6tablesChain(c6)
+	if chain, ok := pm.chainFor(net.ParseIP("::"), v6); !ok || chain != c6 {
+		t.Fatalf("Expected IPv6 ports to be forwarded by %v, got %v", c6, chain)
+	}
+	if _, ok := pm.chainFor(net.ParseIP("::"), v4); ok {
+		t.Fatal("IPv6 ports should not be forwarded to an IPv4 container")
+	}
+	if _, ok := pm.chainFor(net.ParseIP("0.0.0.0"), v6); ok {
+		t.Fatal("IPv4 ports should not be forwarded to an IPv6 container")
+	}
+}
+
+func TestMapIPv6PortsWithoutUserlandProxy(t *testing.T) {
+	pm := New()
+	pm.SetUserlandProxy(false)
+
+	proxied := false
+	NewProxy = func(proto string, hostIP net.IP, hostPort int, containerIP net.IP, containerPort int) UserlandProxy {
+		proxied = true
+		return NewMockProxyCommand(proto, hostIP, hostPort, containerIP, containerPort)
+	}
+	defer func() { NewProxy = NewMockProxyCommand }()
+
+	// without ip6tables the userland proxy is the only way to reach the
+	// container, even when it is disabled
+	srcAddr := &net.TCPAddr{Port: 80, IP: net.ParseIP("172.16.0.1")}
+	host, err := pm.Map(srcAddr, net.ParseIP("::1"), 0)
+	if err != nil {
+		t.Fatalf("Failed to allocate port: %s", err)
+	}
+	if !proxied {
+		t.Fatal("Expected the userland proxy to serve the IPv6 port")
+	}
+	if err := pm.Unmap(host); err != nil {
+		t.Fatalf("Failed to release port: %s", err)
+	}
+}
+
+func TestMapIPv6PortsWithIp6tablesWithoutUserlandProxy(t *testing.T) {
+	pm := New()
+	pm.SetUserlandProxy(false)
+	c6 := &iptables.Chain{Name: "TEST", IPVersion: iptables.Ip6tables}
+	pm.SetIp6tablesChain(c6)
+
+	proxied := false
+	NewProxy = func(proto string, hostIP net.IP, hostPort int, containerIP net.IP, containerPort int) UserlandProxy {
+		proxied = true
+		return NewMockProxyCommand(proto, hostIP, hostPort, containerIP, containerPort)
+	}
+	var chains []*iptables.Chain
+	iptablesForward = func(chain *iptables.Chain, action iptables.Action, proto string, sourceIP net.IP, sourcePort int, containerIP string, containerPort int) error {
+		chains = append(chains, chain)
+		return nil
+	}
+	defer func() {
+		NewProxy = NewMockProxyCommand
+		iptablesForward = forward
+	}()
+
+	// ip6tables forwards the port, but connections from the host to ::1
+	// still need the userland proxy
+	srcAddr := &net.TCPAddr{Port: 80, IP: net.ParseIP("2001:db8::2")}
+	host, err := pm.Map(srcAddr, net.ParseIP("::"), 0)
+	if err != nil {
+		t.Fatalf("Failed to allocate port: %s", err)
+	}
+	if !proxied {
+		t.Fatal("Expected the userland proxy to serve the IPv6 port")
+	}
+	if err := pm.Unmap(host); err != nil {
+		t.Fatalf("Failed to release port: %s", err)
+	}
+	if len(chains) != 2 || chains[0] != c6 || chains[1] != c6 {
+		t.Fatalf("Expected the rules of the port to be added to and deleted from %v, got %v", c6, chains)
+	}
+}
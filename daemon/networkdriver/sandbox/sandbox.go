@@ -0,0 +1,156 @@
+package sandbox
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+
+	"github.com/docker/libcontainer/netlink"
+	"github.com/docker/libcontainer/system"
+)
+
+// Root is the directory holding the bind mounts that keep the network
+// namespaces alive while no process is running inside them.
+var Root = "/var/run/docker/netns"
+
+// Path returns the location of the network namespace for the given id.
+func Path(id string) string {
+	return filepath.Join(Root, id)
+}
+
+// Create creates a new network namespace and bind mounts it at path.
+// The loopback interface of the new namespace is brought up.
+func Create(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// a namespace still at path is stale, the daemon died before releasing
+	// it when the container stopped
+	if err := Remove(path); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	f.Close()
+
+	if err := createNamespace(path); err != nil {
+		Remove(path)
+		return err
+	}
+
+	return Do(path, func() error {
+		lo, err := net.InterfaceByName("lo")
+		if err != nil {
+			return err
+		}
+		return netlink.NetworkLinkUp(lo)
+	})
+}
+
+// createNamespace creates a network namespace and bind mounts it at path.
+// The thread stays locked if it can't switch back to its own namespace.
+func createNamespace(path string) error {
+	runtime.LockOSThread()
+	stuck := false
+	defer func() {
+		if !stuck {
+			runtime.UnlockOSThread()
+		}
+	}()
+
+	origns, err := os.Open(threadNamespacePath())
+	if err != nil {
+		return err
+	}
+	defer origns.Close()
+
+	if err := syscall.Unshare(syscall.CLONE_NEWNET); err != nil {
+		return fmt.Errorf("Unable to create network namespace: %v", err)
+	}
+	err = syscall.Mount(threadNamespacePath(), path, "bind", syscall.MS_BIND, "")
+	if serr := system.Setns(origns.Fd(), syscall.CLONE_NEWNET); serr != nil {
+		stuck = true
+		return fmt.Errorf("Unable to leave network namespace %s: %v", path, serr)
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to bind mount network namespace to %s: %v", path, err)
+	}
+	return nil
+}
+
+// Remove releases the network namespace bind mounted at path. The namespace
+// and the interfaces inside it are destroyed once no process uses it anymore.
+func Remove(path string) error {
+	if err := syscall.Unmount(path, syscall.MNT_DETACH); err != nil && err != syscall.EINVAL && err != syscall.ENOENT {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Do runs fn with the calling thread switched into the network namespace
+// bind mounted at path. The thread stays locked if it can't switch back, so
+// that no other goroutine runs in the namespace.
+func Do(path string, fn func() error) error {
+	runtime.LockOSThread()
+	stuck := false
+	defer func() {
+		if !stuck {
+			runtime.UnlockOSThread()
+		}
+	}()
+
+	origns, err := os.Open(threadNamespacePath())
+	if err != nil {
+		return err
+	}
+	defer origns.Close()
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := system.Setns(f.Fd(), syscall.CLONE_NEWNET); err != nil {
+		return fmt.Errorf("Unable to enter network namespace %s: %v", path, err)
+	}
+	err = fn()
+	if serr := system.Setns(origns.Fd(), syscall.CLONE_NEWNET); serr != nil {
+		stuck = true
+		return fmt.Errorf("Unable to leave network namespace %s: %v", path, serr)
+	}
+	return err
+}
+
+// Check returns an error unless a network namespace is bind mounted at path,
+// as it is for a container whose processes kept running.
+func Check(path string) error {
+	return Do(path, func() error { return nil })
+}
+
+// MoveInterface moves the named interface of the current namespace into
+// the network namespace bind mounted at path.
+func MoveInterface(name, path string) error {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return netlink.NetworkSetNsFd(iface, int(f.Fd()))
+}
+
+func threadNamespacePath() string {
+	return fmt.Sprintf("/proc/%d/task/%d/ns/net", os.Getpid(), syscall.Gettid())
+}
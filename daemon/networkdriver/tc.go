@@ -0,0 +1,59 @@
+package networkdriver
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// minBurst is the smallest bucket given to tc, enough for a few full
+// sized frames even at low rates.
+const minBurst = 16 * 1024
+
+// SetRateLimits limits the bandwidth of the container behind iface, the
+// host side of its veth pair. Traffic to the container leaves through
+// iface and is shaped by a token bucket filter, traffic from the container
+// enters through iface and is policed on its ingress qdisc. Rates are in
+// bytes per second, 0 leaves the direction unlimited.
+func SetRateLimits(iface string, ingress, egress int64) error {
+	if ingress < 0 || egress < 0 {
+		return fmt.Errorf("Invalid rate limits for %s: %d/%d", iface, ingress, egress)
+	}
+	if ingress > 0 {
+		rate, burst := tcRate(ingress)
+		if err := tc("qdisc", "replace", "dev", iface, "root", "tbf",
+			"rate", rate, "burst", burst, "latency", "50ms"); err != nil {
+			return err
+		}
+	}
+	if egress > 0 {
+		rate, burst := tcRate(egress)
+		if err := tc("qdisc", "replace", "dev", iface, "handle", "ffff:", "ingress"); err != nil {
+			return err
+		}
+		if err := tc("filter", "add", "dev", iface, "parent", "ffff:", "protocol", "all",
+			"prio", "1", "u32", "match", "u32", "0", "0",
+			"police", "rate", rate, "burst", burst, "drop", "flowid", ":1"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tcRate returns the tc rate and burst arguments for a rate in bytes per
+// second, the burst holding 10ms worth of traffic.
+func tcRate(rate int64) (string, string) {
+	burst := rate / 100
+	if burst < minBurst {
+		burst = minBurst
+	}
+	return strconv.FormatInt(rate, 10) + "bps", strconv.FormatInt(burst, 10)
+}
+
+func tc(args ...string) error {
+	if output, err := exec.Command("tc", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("tc %s failed: %s (%v)", strings.Join(args, " "), strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package networkdriver
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/daemon/networkdriver/sandbox"
+	"github.com/docker/libcontainer/netlink"
+)
+
+func TestTcRate(t *testing.T) {
+	if rate, burst := tcRate(1000); rate != "1000bps" || burst != "16384" {
+		t.Fatalf("Unexpected rate %s burst %s", rate, burst)
+	}
+	if rate, burst := tcRate(10000000); rate != "10000000bps" || burst != "100000" {
+		t.Fatalf("Unexpected rate %s burst %s", rate, burst)
+	}
+}
+
+func TestSetRateLimitsInvalid(t *testing.T) {
+	if err := SetRateLimits("veth0", -1, 0); err == nil {
+		t.Fatal("Expected an error for a negative rate")
+	}
+}
+
+func TestSetRateLimits(t *testing.T) {
+	if _, err := exec.LookPath("tc"); err != nil {
+		t.Skip("tc is not available")
+	}
+	tmp, err := ioutil.TempDir("", "docker-tc-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	path := filepath.Join(tmp, "netns")
+	if err := sandbox.Create(path); err != nil {
+		t.Skipf("Unable to create a network namespace: %v", err)
+	}
+	defer sandbox.Remove(path)
+
+	err = sandbox.Do(path, func() error {
+		if err := netlink.NetworkCreateVethPair("vethhost", "vethcont", 0); err != nil {
+			return err
+		}
+		if err := SetRateLimits("vethhost", 0, 0); err != nil {
+			return err
+		}
+		if err := SetRateLimits("vethhost", 1000000, 0); err != nil {
+			return err
+		}
+		output, err := exec.Command("tc", "qdisc", "show", "dev", "vethhost").CombinedOutput()
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(string(output), "tbf") || !strings.Contains(string(output), "8Mbit") {
+			t.Fatalf("No tbf qdisc limiting to 8Mbit: %s", output)
+		}
+
+		if err := SetRateLimits("vethhost", 0, 1000000); err != nil {
+			// the police action is a module that not every kernel has
+			t.Skipf("Unable to police the ingress: %v", err)
+		}
+		output, err = exec.Command("tc", "filter", "show", "dev", "vethhost", "parent", "ffff:").CombinedOutput()
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(string(output), "police") {
+			t.Fatalf("No police filter: %s", output)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
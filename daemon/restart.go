@@ -2,11 +2,15 @@ package daemon
 
 import "fmt"
 
+// ContainerRestart stops the container like ContainerStop and starts it again.
 func (daemon *Daemon) ContainerRestart(name string, seconds int) error {
 	container, err := daemon.Get(name)
 	if err != nil {
 		return err
 	}
+	if seconds < 0 {
+		seconds = container.stopTimeout(defaultStopTimeout)
+	}
 	if err := container.Restart(seconds); err != nil {
 		return fmt.Errorf("Cannot restart container %s: %s\n", name, err)
 	}
@@ -17,17 +17,40 @@ type State struct {
 	OOMKilled         bool
 	removalInProgress bool // Not need for this to be persistent on disk.
 	Dead              bool
+	Checkpointed      bool // the processes were stopped by a checkpoint
+	ManuallyStopped   bool // a user stopped the container, "unless-stopped" doesn't start it with the daemon
 	Pid               int
 	ExitCode          int
 	Error             string // contains last known error when starting the container
 	StartedAt         time.Time
 	FinishedAt        time.Time
+	CheckpointedAt    time.Time // time of the checkpoint to restore, zero if there is none
+	NextRestartAt     time.Time // time of the next restart while the container is restarting
+	Health            *Health   `json:",omitempty"` // only set for containers with a health check
 	waitChan          chan struct{}
+	exitChan          chan struct{} // closed when the container exits
+	removedChan       chan struct{} // closed once the container was removed
 }
 
+// WaitCondition is a state of a container to wait for
+type WaitCondition string
+
+const (
+	// WaitConditionNotRunning is met once the container is not running, at
+	// once if it is stopped already
+	WaitConditionNotRunning WaitCondition = "not-running"
+	// WaitConditionNextExit is met the next time the container exits, even
+	// if it is not started yet
+	WaitConditionNextExit WaitCondition = "next-exit"
+	// WaitConditionRemoved is met once the container was removed
+	WaitConditionRemoved WaitCondition = "removed"
+)
+
 func NewState() *State {
 	return &State{
-		waitChan: make(chan struct{}),
+		waitChan:    make(chan struct{}),
+		exitChan:    make(chan struct{}),
+		removedChan: make(chan struct{}),
 	}
 }
 
@@ -41,6 +64,9 @@ func (s *State) String() string {
 			return fmt.Sprintf("Restarting (%d) %s ago", s.ExitCode, units.HumanDuration(time.Now().UTC().Sub(s.FinishedAt)))
 		}
 
+		if s.Health != nil {
+			return fmt.Sprintf("Up %s (%s)", units.HumanDuration(time.Now().UTC().Sub(s.StartedAt)), s.healthString())
+		}
 		return fmt.Sprintf("Up %s", units.HumanDuration(time.Now().UTC().Sub(s.StartedAt)))
 	}
 
@@ -52,6 +78,10 @@ func (s *State) String() string {
 		return "Dead"
 	}
 
+	if s.Checkpointed {
+		return fmt.Sprintf("Checkpointed %s ago", units.HumanDuration(time.Now().UTC().Sub(s.CheckpointedAt)))
+	}
+
 	if s.FinishedAt.IsZero() {
 		return ""
 	}
@@ -75,9 +105,22 @@ func (s *State) StateString() string {
 		return "dead"
 	}
 
+	if s.Checkpointed {
+		return "checkpointed"
+	}
+
 	return "exited"
 }
 
+// healthString returns the health status of the container, or "none" if it
+// has no health check
+func (s *State) healthString() string {
+	if s.Health == nil {
+		return healthNone
+	}
+	return s.Health.Status
+}
+
 func wait(waitChan <-chan struct{}, timeout time.Duration) error {
 	if timeout < 0 {
 		<-waitChan
@@ -127,6 +170,52 @@ func (s *State) WaitStop(timeout time.Duration) (int, error) {
 	return s.GetExitCode(), nil
 }
 
+// WaitNextStop waits until the container exits the next time, whether it is
+// running already or not. If you want wait forever you must supply negative
+// timeout. Returns exit code, that was passed to SetStopped
+func (s *State) WaitNextStop(timeout time.Duration) (int, error) {
+	s.Lock()
+	exitChan := s.exitChan
+	s.Unlock()
+	if err := wait(exitChan, timeout); err != nil {
+		return -1, err
+	}
+	return s.GetExitCode(), nil
+}
+
+// WaitRemoved waits until the container was removed. If you want wait forever
+// you must supply negative timeout. Returns the exit code of its last run
+func (s *State) WaitRemoved(timeout time.Duration) (int, error) {
+	if err := wait(s.removedChan, timeout); err != nil {
+		return -1, err
+	}
+	return s.GetExitCode(), nil
+}
+
+// Wait waits until the condition is met. If you want wait forever you must
+// supply negative timeout. Returns the exit code and the error message of the
+// container
+func (s *State) Wait(condition WaitCondition, timeout time.Duration) (int, string, error) {
+	var (
+		exitCode int
+		err      error
+	)
+	switch condition {
+	case WaitConditionNotRunning:
+		exitCode, err = s.WaitStop(timeout)
+	case WaitConditionNextExit:
+		exitCode, err = s.WaitNextStop(timeout)
+	case WaitConditionRemoved:
+		exitCode, err = s.WaitRemoved(timeout)
+	default:
+		return -1, "", fmt.Errorf("Bad parameter: unknown wait condition %q", condition)
+	}
+	if err != nil {
+		return -1, "", err
+	}
+	return exitCode, s.GetError(), nil
+}
+
 func (s *State) IsRunning() bool {
 	s.Lock()
 	res := s.Running
@@ -148,6 +237,13 @@ func (s *State) GetExitCode() int {
 	return res
 }
 
+func (s *State) GetError() string {
+	s.Lock()
+	res := s.Error
+	s.Unlock()
+	return res
+}
+
 func (s *State) SetRunning(pid int) {
 	s.Lock()
 	s.setRunning(pid)
@@ -159,6 +255,8 @@ func (s *State) setRunning(pid int) {
 	s.Running = true
 	s.Paused = false
 	s.Restarting = false
+	s.Checkpointed = false
+	s.NextRestartAt = time.Time{}
 	s.ExitCode = 0
 	s.Pid = pid
 	s.StartedAt = time.Now().UTC()
@@ -173,14 +271,18 @@ func (s *State) SetStopped(exitStatus *execdriver.ExitStatus) {
 }
 
 func (s *State) setStopped(exitStatus *execdriver.ExitStatus) {
+	s.stopHealthcheck()
 	s.Running = false
 	s.Restarting = false
+	s.NextRestartAt = time.Time{}
 	s.Pid = 0
 	s.FinishedAt = time.Now().UTC()
 	s.ExitCode = exitStatus.ExitCode
 	s.OOMKilled = exitStatus.OOMKilled
 	close(s.waitChan) // fire waiters for stop
 	s.waitChan = make(chan struct{})
+	close(s.exitChan) // fire waiters for exit
+	s.exitChan = make(chan struct{})
 }
 
 // SetRestarting is when docker handles the auto restart of containers when they are
@@ -189,6 +291,7 @@ func (s *State) SetRestarting(exitStatus *execdriver.ExitStatus) {
 	s.Lock()
 	// we should consider the container running when it is restarting because of
 	// all the checks in docker around rm/stop/etc
+	s.stopHealthcheck()
 	s.Running = true
 	s.Restarting = true
 	s.Pid = 0
@@ -197,9 +300,35 @@ func (s *State) SetRestarting(exitStatus *execdriver.ExitStatus) {
 	s.OOMKilled = exitStatus.OOMKilled
 	close(s.waitChan) // fire waiters for stop
 	s.waitChan = make(chan struct{})
+	close(s.exitChan) // fire waiters for exit
+	s.exitChan = make(chan struct{})
 	s.Unlock()
 }
 
+// SetNextRestart records when the restarting container is started again.
+func (s *State) SetNextRestart(at time.Time) {
+	s.Lock()
+	s.NextRestartAt = at
+	s.Unlock()
+}
+
+// SetManuallyStopped records that a user stopped the container, or that it
+// was started again when stopped is false.
+func (s *State) SetManuallyStopped(stopped bool) {
+	s.Lock()
+	s.ManuallyStopped = stopped
+	s.Unlock()
+}
+
+// stopHealthcheck stops probing the container, the results of the last
+// probes are kept for inspection
+func (s *State) stopHealthcheck() {
+	if s.Health != nil && s.Health.stop != nil {
+		close(s.Health.stop)
+		s.Health.stop = nil
+	}
+}
+
 // setError sets the container's error state. This is useful when we want to
 // know the error that occurred when container transits to another state
 // when inspecting it
@@ -249,6 +378,40 @@ func (s *State) ResetRemovalInProgress() {
 	s.Unlock()
 }
 
+// SetCheckpointed marks the container as stopped by a checkpoint, so that it
+// is not restarted when its processes exit.
+func (s *State) SetCheckpointed(checkpointed bool) {
+	s.Lock()
+	s.Checkpointed = checkpointed
+	s.Unlock()
+}
+
+func (s *State) IsCheckpointed() bool {
+	s.Lock()
+	res := s.Checkpointed
+	s.Unlock()
+	return res
+}
+
+// HasCheckpoint returns whether the container has a checkpoint to restore.
+func (s *State) HasCheckpoint() bool {
+	s.Lock()
+	res := !s.CheckpointedAt.IsZero()
+	s.Unlock()
+	return res
+}
+
+// SetRemoved fires the waiters for the removal of the container
+func (s *State) SetRemoved() {
+	s.Lock()
+	select {
+	case <-s.removedChan:
+	default:
+		close(s.removedChan)
+	}
+	s.Unlock()
+}
+
 func (s *State) SetDead() {
 	s.Lock()
 	s.Dead = true
@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -102,3 +103,81 @@ func TestStateTimeoutWait(t *testing.T) {
 	}
 
 }
+
+func TestStateCheckpointed(t *testing.T) {
+	s := NewState()
+	s.SetRunning(42)
+	s.SetCheckpointed(true)
+	s.CheckpointedAt = time.Now().UTC()
+	s.SetStopped(&execdriver.ExitStatus{ExitCode: 137})
+	if !s.IsCheckpointed() || !s.HasCheckpoint() {
+		t.Fatal("Checkpoint is lost when the container stops")
+	}
+	if s.StateString() != "checkpointed" {
+		t.Fatalf("StateString() = %q, expected checkpointed", s.StateString())
+	}
+	if !strings.HasPrefix(s.String(), "Checkpointed") {
+		t.Fatalf("String() = %q, expected the checkpoint time", s.String())
+	}
+	s.SetRunning(43)
+	if s.IsCheckpointed() {
+		t.Fatal("Container is still checkpointed after it was restored")
+	}
+	if !s.HasCheckpoint() {
+		t.Fatal("Checkpoint is lost when the container is restored")
+	}
+}
+
+func TestStateWaitConditions(t *testing.T) {
+	s := NewState()
+	s.SetStopped(&execdriver.ExitStatus{ExitCode: 2})
+
+	if exitCode, _, err := s.Wait(WaitConditionNotRunning, 100*time.Millisecond); err != nil || exitCode != 2 {
+		t.Fatalf("Wait not-running returned exitCode: %v, err: %v, expected exitCode: 2", exitCode, err)
+	}
+	if _, _, err := s.Wait(WaitConditionNextExit, 100*time.Millisecond); err == nil {
+		t.Fatal("Wait next-exit should time out on a stopped container")
+	}
+	if _, _, err := s.Wait("unknown", 100*time.Millisecond); err == nil {
+		t.Fatal("Wait should fail for an unknown condition")
+	}
+
+	exited := make(chan int)
+	go func() {
+		exitCode, _, _ := s.Wait(WaitConditionNextExit, -1*time.Second)
+		exited <- exitCode
+	}()
+	removed := make(chan int)
+	go func() {
+		exitCode, _, _ := s.Wait(WaitConditionRemoved, -1*time.Second)
+		removed <- exitCode
+	}()
+
+	// give the waiters the time to start waiting before the exit
+	time.Sleep(100 * time.Millisecond)
+	s.SetRunning(100)
+	s.SetStopped(&execdriver.ExitStatus{ExitCode: 3})
+	select {
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("Wait next-exit doesn't return in 100 milliseconds")
+	case exitCode := <-exited:
+		if exitCode != 3 {
+			t.Fatalf("ExitCode %v, expected 3", exitCode)
+		}
+	}
+
+	select {
+	case <-removed:
+		t.Fatal("Wait removed returned before the removal")
+	default:
+	}
+	s.SetRemoved()
+	select {
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("Wait removed doesn't return in 100 milliseconds")
+	case exitCode := <-removed:
+		if exitCode != 3 {
+			t.Fatalf("ExitCode %v, expected 3", exitCode)
+		}
+	}
+}
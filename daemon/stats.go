@@ -10,16 +10,28 @@ import (
 	"github.com/docker/libcontainer/cgroups"
 )
 
-func (daemon *Daemon) ContainerStats(name string, out io.Writer) error {
+type ContainerStatsConfig struct {
+	// AggregateNetwork sums the statistics of all the interfaces in
+	// Network, as API versions older than 1.19 expect, instead of
+	// reporting each of them in Networks.
+	AggregateNetwork bool
+	OutStream        io.Writer
+}
+
+func (daemon *Daemon) ContainerStats(name string, config *ContainerStatsConfig) error {
 	updates, err := daemon.SubscribeToContainerStats(name)
 	if err != nil {
 		return err
 	}
-	enc := json.NewEncoder(out)
+	enc := json.NewEncoder(config.OutStream)
 	for v := range updates {
 		update := v.(*execdriver.ResourceStats)
-		ss := convertToAPITypes(update.Stats)
+		ss := convertToAPITypes(update.Stats, config.AggregateNetwork)
 		ss.MemoryStats.Limit = uint64(update.MemoryLimit)
+		ss.PidsStats = types.PidsStats{
+			Current: update.PidsStats.Current,
+			Limit:   update.PidsStats.Limit,
+		}
 		ss.Read = update.Read
 		ss.CpuStats.SystemUsage = update.SystemUsage
 		if err := enc.Encode(ss); err != nil {
@@ -33,19 +45,36 @@ func (daemon *Daemon) ContainerStats(name string, out io.Writer) error {
 
 // convertToAPITypes converts the libcontainer.Stats to the api specific
 // structs.  This is done to preserve API compatibility and versioning.
-func convertToAPITypes(ls *libcontainer.Stats) *types.Stats {
+func convertToAPITypes(ls *libcontainer.Stats, aggregateNetwork bool) *types.Stats {
 	s := &types.Stats{}
 	if ls.Interfaces != nil {
-		s.Network = types.Network{}
+		if aggregateNetwork {
+			s.Network = &types.Network{}
+		} else {
+			s.Networks = make(map[string]types.Network, len(ls.Interfaces))
+		}
 		for _, iface := range ls.Interfaces {
-			s.Network.RxBytes += iface.RxBytes
-			s.Network.RxPackets += iface.RxPackets
-			s.Network.RxErrors += iface.RxErrors
-			s.Network.RxDropped += iface.RxDropped
-			s.Network.TxBytes += iface.TxBytes
-			s.Network.TxPackets += iface.TxPackets
-			s.Network.TxErrors += iface.TxErrors
-			s.Network.TxDropped += iface.TxDropped
+			if aggregateNetwork {
+				s.Network.RxBytes += iface.RxBytes
+				s.Network.RxPackets += iface.RxPackets
+				s.Network.RxErrors += iface.RxErrors
+				s.Network.RxDropped += iface.RxDropped
+				s.Network.TxBytes += iface.TxBytes
+				s.Network.TxPackets += iface.TxPackets
+				s.Network.TxErrors += iface.TxErrors
+				s.Network.TxDropped += iface.TxDropped
+				continue
+			}
+			s.Networks[iface.Name] = types.Network{
+				RxBytes:   iface.RxBytes,
+				RxPackets: iface.RxPackets,
+				RxErrors:  iface.RxErrors,
+				RxDropped: iface.RxDropped,
+				TxBytes:   iface.TxBytes,
+				TxPackets: iface.TxPackets,
+				TxErrors:  iface.TxErrors,
+				TxDropped: iface.TxDropped,
+			}
 		}
 	}
 	cs := ls.CgroupStats
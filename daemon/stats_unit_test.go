@@ -0,0 +1,38 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/docker/libcontainer"
+)
+
+func TestConvertToAPITypesNetworks(t *testing.T) {
+	ls := &libcontainer.Stats{
+		Interfaces: []*libcontainer.NetworkInterface{
+			{Name: "eth0", RxBytes: 10, TxBytes: 20, RxPackets: 1},
+			{Name: "eth1", RxBytes: 1, TxBytes: 2, TxDropped: 3},
+		},
+	}
+
+	s := convertToAPITypes(ls, false)
+	if s.Network != nil {
+		t.Fatalf("Unexpected aggregate network %+v", s.Network)
+	}
+	if len(s.Networks) != 2 {
+		t.Fatalf("Expected 2 interfaces, got %+v", s.Networks)
+	}
+	if eth0 := s.Networks["eth0"]; eth0.RxBytes != 10 || eth0.TxBytes != 20 || eth0.RxPackets != 1 {
+		t.Fatalf("Unexpected eth0 statistics %+v", eth0)
+	}
+	if eth1 := s.Networks["eth1"]; eth1.RxBytes != 1 || eth1.TxBytes != 2 || eth1.TxDropped != 3 {
+		t.Fatalf("Unexpected eth1 statistics %+v", eth1)
+	}
+
+	s = convertToAPITypes(ls, true)
+	if s.Networks != nil {
+		t.Fatalf("Unexpected networks %+v", s.Networks)
+	}
+	if s.Network == nil || s.Network.RxBytes != 11 || s.Network.TxBytes != 22 || s.Network.TxDropped != 3 {
+		t.Fatalf("Unexpected aggregate network %+v", s.Network)
+	}
+}
@@ -2,6 +2,12 @@ package daemon
 
 import "fmt"
 
+// defaultStopTimeout is the number of seconds docker stop waits for a
+// container without a stop timeout of its own
+const defaultStopTimeout = 10
+
+// ContainerStop stops the container, killing it if it is still running after
+// seconds, or after its own stop timeout if seconds is negative.
 func (daemon *Daemon) ContainerStop(name string, seconds int) error {
 	container, err := daemon.Get(name)
 	if err != nil {
@@ -10,7 +16,14 @@ func (daemon *Daemon) ContainerStop(name string, seconds int) error {
 	if !container.IsRunning() {
 		return fmt.Errorf("Container already stopped")
 	}
+	if seconds < 0 {
+		seconds = container.stopTimeout(defaultStopTimeout)
+	}
+	// set before stopping, so that it is saved with the state of the
+	// stopped container
+	container.SetManuallyStopped(true)
 	if err := container.Stop(seconds); err != nil {
+		container.SetManuallyStopped(false)
 		return fmt.Errorf("Cannot stop container %s: %s\n", name, err)
 	}
 	container.LogEvent("stop")
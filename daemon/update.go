@@ -0,0 +1,120 @@
+package daemon
+
+import (
+	"fmt"
+
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/runconfig"
+)
+
+// ContainerUpdate changes the resource limits and restart policy of a
+// container. The new limits are applied right away if the container is
+// running, and saved so that they survive a restart of the container.
+// Limits set to 0 are removed.
+func (daemon *Daemon) ContainerUpdate(name string, updateConfig *runconfig.UpdateConfig) ([]string, error) {
+	container, err := daemon.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, limit := range []*int64{updateConfig.CpuShares, updateConfig.CpuQuota, updateConfig.Memory} {
+		if limit != nil && *limit < 0 {
+			return nil, fmt.Errorf("Resource limits can't be negative")
+		}
+	}
+	if updateConfig.MemorySwap != nil && *updateConfig.MemorySwap < -1 {
+		return nil, fmt.Errorf("Resource limits can't be negative")
+	}
+	if updateConfig.RestartPolicy.MaximumRetryCount < 0 {
+		return nil, fmt.Errorf("Maximum restart count can't be negative")
+	}
+
+	container.Lock()
+	defer container.Unlock()
+
+	hostConfig := *container.hostConfig
+	if updateConfig.CpuShares != nil {
+		hostConfig.CpuShares = *updateConfig.CpuShares
+	}
+	if updateConfig.CpuQuota != nil {
+		hostConfig.CpuQuota = *updateConfig.CpuQuota
+	}
+	if updateConfig.CpusetCpus != "" {
+		hostConfig.CpusetCpus = updateConfig.CpusetCpus
+	}
+	if updateConfig.CpusetMems != "" {
+		hostConfig.CpusetMems = updateConfig.CpusetMems
+	}
+	if updateConfig.Memory != nil {
+		hostConfig.Memory = *updateConfig.Memory
+		if hostConfig.Memory == 0 && updateConfig.MemorySwap == nil {
+			// the memory+swap limit can't outlive the memory limit
+			hostConfig.MemorySwap = 0
+		}
+	}
+	if updateConfig.MemorySwap != nil {
+		hostConfig.MemorySwap = *updateConfig.MemorySwap
+	}
+	if updateConfig.RestartPolicy.Name != "" {
+		// the restart delays can't be updated, keep them
+		policy := updateConfig.RestartPolicy
+		policy.Delay = hostConfig.RestartPolicy.Delay
+		policy.MaxDelay = hostConfig.RestartPolicy.MaxDelay
+		policy.ResetWindow = hostConfig.RestartPolicy.ResetWindow
+		hostConfig.RestartPolicy = policy
+	}
+
+	warnings, err := daemon.verifyHostConfig(&hostConfig)
+	if err != nil {
+		return warnings, err
+	}
+
+	if container.command != nil {
+		resources := *container.command.Resources
+		resources.Memory = hostConfig.Memory
+		resources.MemorySwap = hostConfig.MemorySwap
+		resources.CpuShares = hostConfig.CpuShares
+		resources.CpusetCpus = hostConfig.CpusetCpus
+		resources.CpusetMems = hostConfig.CpusetMems
+		resources.CpuQuota = hostConfig.CpuQuota
+
+		if container.Running {
+			if err := daemon.checkMemoryUsage(container, resources.Memory); err != nil {
+				return warnings, err
+			}
+			command := *container.command
+			command.Resources = &resources
+			if err := daemon.execDriver.Update(&command); err != nil && err != execdriver.ErrNotRunning {
+				return warnings, fmt.Errorf("Cannot update container %s: %s", container.ID, err)
+			}
+		}
+		container.command.Resources = &resources
+	}
+	if container.monitor != nil {
+		container.monitor.setRestartPolicy(hostConfig.RestartPolicy)
+	}
+
+	container.hostConfig = &hostConfig
+	if err := container.WriteHostConfig(); err != nil {
+		return warnings, err
+	}
+	container.LogEvent("update")
+	return warnings, nil
+}
+
+// checkMemoryUsage refuses a memory limit below the memory the running
+// container already uses, which the kernel can't enforce.
+func (daemon *Daemon) checkMemoryUsage(container *Container, limit int64) error {
+	if limit == 0 {
+		return nil
+	}
+	stats, err := daemon.execDriver.Stats(container.ID)
+	if err != nil || stats.CgroupStats == nil {
+		// the driver will report the failure to set the limit
+		return nil
+	}
+	if usage := stats.CgroupStats.MemoryStats.Usage; uint64(limit) < usage {
+		return fmt.Errorf("Cannot set the memory limit of container %s to %d bytes, below its current usage of %d bytes", container.ID, limit, usage)
+	}
+	return nil
+}
@@ -78,6 +78,7 @@ func init() {
 		for _, command := range [][]string{
 			{"attach", "Attach to a running container"},
 			{"build", "Build an image from a Dockerfile"},
+			{"checkpoint", "Checkpoint one or more running containers"},
 			{"commit", "Create a new image from a container's changes"},
 			{"cp", "Copy files/folders from a container's filesystem to the host path"},
 			{"create", "Create a new container"},
@@ -102,6 +103,7 @@ func init() {
 			{"push", "Push an image or a repository to a Docker registry server"},
 			{"rename", "Rename an existing container"},
 			{"restart", "Restart a running container"},
+			{"restore", "Restore one or more checkpointed containers"},
 			{"rm", "Remove one or more containers"},
 			{"rmi", "Remove one or more images"},
 			{"run", "Run a command in a new container"},
@@ -113,6 +115,7 @@ func init() {
 			{"tag", "Tag an image into a repository"},
 			{"top", "Lookup the running processes of a container"},
 			{"unpause", "Unpause a paused container"},
+			{"update", "Update the resource limits of one or more containers"},
 			{"version", "Show the Docker version information"},
 			{"wait", "Block until a container stops, then print its exit code"},
 		} {
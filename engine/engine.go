@@ -58,7 +58,8 @@ type Engine struct {
 	l            sync.RWMutex // lock for shutdown
 	shutdownWait sync.WaitGroup
 	shutdown     bool
-	onShutdown   []func() // shutdown handlers
+	onShutdown   []func()             // shutdown handlers
+	waitTimeout  func() time.Duration // how long Shutdown waits for the handlers, 10 seconds if nil
 }
 
 func (eng *Engine) Register(name string, handler Handler) error {
@@ -150,11 +151,20 @@ func (eng *Engine) OnShutdown(h func()) {
 	eng.l.Unlock()
 }
 
+// SetShutdownTimeout sets how long Shutdown waits for the shutdown handlers to
+// complete. timeout is called right before the handlers are started, so that
+// it can account for the work left to them.
+func (eng *Engine) SetShutdownTimeout(timeout func() time.Duration) {
+	eng.l.Lock()
+	eng.waitTimeout = timeout
+	eng.l.Unlock()
+}
+
 // Shutdown permanently shuts down eng as follows:
 // - It refuses all new jobs, permanently.
 // - It waits for all active jobs to complete (with no timeout)
 // - It calls all shutdown handlers concurrently (if any)
-// - It returns when all handlers complete, or after 15 seconds,
+// - It returns when all handlers complete, or after the shutdown timeout,
 //	whichever happens first.
 func (eng *Engine) Shutdown() {
 	eng.l.Lock()
@@ -164,6 +174,7 @@ func (eng *Engine) Shutdown() {
 		return
 	}
 	eng.shutdown = true
+	waitTimeout := eng.waitTimeout
 	eng.l.Unlock()
 	// We don't need to protect the rest with a lock, to allow
 	// for other calls to immediately fail with "shutdown" instead
@@ -184,7 +195,11 @@ func (eng *Engine) Shutdown() {
 	}
 
 	// Call shutdown handlers, if any.
-	// Timeout after 10 seconds.
+	// Timeout after 10 seconds, unless set otherwise.
+	handlersTimeout := 10 * time.Second
+	if waitTimeout != nil {
+		handlersTimeout = waitTimeout()
+	}
 	for _, h := range eng.onShutdown {
 		go func(h func()) {
 			h()
@@ -197,7 +212,7 @@ func (eng *Engine) Shutdown() {
 		close(done)
 	}()
 	select {
-	case <-time.After(time.Second * 10):
+	case <-time.After(handlersTimeout):
 	case <-done:
 	}
 	return
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/docker/docker/pkg/ioutils"
 )
@@ -74,6 +75,25 @@ func TestEngineShutdown(t *testing.T) {
 	}
 }
 
+func TestEngineShutdownTimeout(t *testing.T) {
+	eng := New()
+	block := make(chan struct{})
+	defer close(block)
+	eng.OnShutdown(func() { <-block })
+	eng.SetShutdownTimeout(func() time.Duration { return 100 * time.Millisecond })
+
+	done := make(chan struct{})
+	go func() {
+		eng.Shutdown()
+		close(done)
+	}()
+	select {
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shutdown didn't return after its timeout")
+	case <-done:
+	}
+}
+
 func TestEngineCommands(t *testing.T) {
 	eng := New()
 	handler := func(job *Job) error { return nil }
@@ -20,6 +20,7 @@ import (
 	"github.com/docker/docker/daemon/graphdriver"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/progressreader"
 	"github.com/docker/docker/pkg/streamformatter"
 	"github.com/docker/docker/pkg/stringid"
@@ -272,8 +273,9 @@ func bufferToFile(f *os.File, src io.Reader) (int64, digest.Digest, error) {
 // empty file at /.dockerinit
 //
 // This extra layer is used by all containers as the top-most ro layer. It protects
-// the container from unwanted side-effects on the rw layer.
-func SetupInitLayer(initLayer string) error {
+// the container from unwanted side-effects on the rw layer. The entries are
+// owned by rootUID:rootGID, the host ids of root in the container.
+func SetupInitLayer(initLayer string, rootUID, rootGID int) error {
 	for pth, typ := range map[string]string{
 		"/dev/pts":         "dir",
 		"/dev/shm":         "dir",
@@ -296,12 +298,12 @@ func SetupInitLayer(initLayer string) error {
 
 		if _, err := os.Stat(path.Join(initLayer, pth)); err != nil {
 			if os.IsNotExist(err) {
-				if err := os.MkdirAll(path.Join(initLayer, path.Dir(pth)), 0755); err != nil {
+				if err := idtools.MkdirAllAs(path.Join(initLayer, path.Dir(pth)), 0755, rootUID, rootGID); err != nil {
 					return err
 				}
 				switch typ {
 				case "dir":
-					if err := os.MkdirAll(path.Join(initLayer, pth), 0755); err != nil {
+					if err := idtools.MkdirAllAs(path.Join(initLayer, pth), 0755, rootUID, rootGID); err != nil {
 						return err
 					}
 				case "file":
@@ -309,11 +311,18 @@ func SetupInitLayer(initLayer string) error {
 					if err != nil {
 						return err
 					}
+					err = f.Chown(rootUID, rootGID)
 					f.Close()
+					if err != nil {
+						return err
+					}
 				default:
 					if err := os.Symlink(typ, path.Join(initLayer, pth)); err != nil {
 						return err
 					}
+					if err := os.Lchown(path.Join(initLayer, pth), rootUID, rootGID); err != nil {
+						return err
+					}
 				}
 			} else {
 				return err
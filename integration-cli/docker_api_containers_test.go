@@ -293,6 +293,9 @@ func (s *DockerSuite) TestGetContainerStats(c *check.C) {
 		if err := dec.Decode(&s); err != nil {
 			c.Fatal(err)
 		}
+		if _, ok := s.Networks["eth0"]; !ok || s.Network != nil {
+			c.Fatalf("Expected the statistics of eth0 only, got %v and %v", s.Networks, s.Network)
+		}
 	}
 }
 
@@ -2157,6 +2157,24 @@ func (s *DockerSuite) TestBuildWorkdirWithEnvVariables(c *check.C) {
 	}
 }
 
+func (s *DockerSuite) TestBuildStopSignal(c *check.C) {
+	name := "test_build_stop_signal"
+	_, err := buildImage(name,
+		`FROM busybox
+		 STOPSIGNAL SIGKILL`,
+		true)
+	if err != nil {
+		c.Fatal(err)
+	}
+	res, err := inspectFieldJSON(name, "Config.StopSignal")
+	if err != nil {
+		c.Fatal(err)
+	}
+	if res != `"SIGKILL"` {
+		c.Fatalf("Signal %s, expected SIGKILL", res)
+	}
+}
+
 func (s *DockerSuite) TestBuildRelativeCopy(c *check.C) {
 	name := "testbuildrelativecopy"
 	dockerfile := `
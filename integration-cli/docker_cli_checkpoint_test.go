@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+
+	"github.com/go-check/check"
+)
+
+func (s *DockerSuite) TestCheckpointAndRestore(c *check.C) {
+	testRequires(c, SameHostDaemon, NativeExecDriver, Criu)
+
+	name := "test-checkpoint-and-restore"
+	dockerCmd(c, "run", "-d", "--name", name, "busybox", "sh", "-c", "i=0; while true; do i=$((i+1)); echo $i > /tmp/counter; sleep 1; done")
+	defer dockerCmd(c, "rm", "-f", name)
+	ip, err := inspectField(name, "NetworkSettings.IPAddress")
+	if err != nil {
+		c.Fatal(err)
+	}
+
+	dockerCmd(c, "checkpoint", name)
+	if res, err := inspectField(name, "State.Running"); err != nil || res != "false" {
+		c.Fatalf("Expected the container to be stopped by the checkpoint, got %s (%v)", res, err)
+	}
+	if res, err := inspectField(name, "State.Checkpointed"); err != nil || res != "true" {
+		c.Fatalf("Expected the container to be checkpointed, got %s (%v)", res, err)
+	}
+	out, _ := dockerCmd(c, "ps", "-a", "-q", "--no-trunc", "--filter", "status=checkpointed")
+	id, err := inspectField(name, "Id")
+	if err != nil {
+		c.Fatal(err)
+	}
+	if !strings.Contains(out, id) {
+		c.Fatalf("Expected the container in the checkpointed containers, got %s", out)
+	}
+
+	dockerCmd(c, "restore", name)
+	if res, err := inspectField(name, "State.Running"); err != nil || res != "true" {
+		c.Fatalf("Expected the container to be restored, got %s (%v)", res, err)
+	}
+	if res, err := inspectField(name, "NetworkSettings.IPAddress"); err != nil || res != ip {
+		c.Fatalf("Expected the container to be restored with IP %s, got %s (%v)", ip, res, err)
+	}
+	// the processes go on from where they were dumped
+	out, _ = dockerCmd(c, "exec", name, "cat", "/tmp/counter")
+	if strings.TrimSpace(out) == "1" {
+		c.Fatalf("Expected the counter to keep its value, got %s", out)
+	}
+}
+
+func (s *DockerSuite) TestRestoreWithoutCheckpoint(c *check.C) {
+	name := "test-restore-without-checkpoint"
+	dockerCmd(c, "create", "--name", name, "busybox", "true")
+	defer dockerCmd(c, "rm", "-f", name)
+
+	out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "restore", name))
+	if err == nil || !strings.Contains(out, "has no checkpoint to restore") {
+		c.Fatalf("Expected the restore to fail, got %s (%v)", out, err)
+	}
+}
+
+func (s *DockerSuite) TestCheckpointStoppedContainer(c *check.C) {
+	name := "test-checkpoint-stopped-container"
+	dockerCmd(c, "create", "--name", name, "busybox", "true")
+	defer dockerCmd(c, "rm", "-f", name)
+
+	out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "checkpoint", name))
+	if err == nil || !strings.Contains(out, "not running") {
+		c.Fatalf("Expected the checkpoint to fail, got %s (%v)", out, err)
+	}
+}
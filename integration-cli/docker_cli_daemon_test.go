@@ -59,6 +59,186 @@ func (s *DockerSuite) TestDaemonRestartWithRunningContainersPorts(c *check.C) {
 
 }
 
+func (s *DockerSuite) TestDaemonRestartUnlessStopped(c *check.C) {
+	d := NewDaemon(c)
+	if err := d.StartWithBusybox(); err != nil {
+		c.Fatalf("Could not start daemon with busybox: %v", err)
+	}
+	defer d.Stop()
+
+	for _, name := range []string{"running", "stopped"} {
+		if out, err := d.Cmd("run", "-d", "--name", name, "--restart", "unless-stopped", "busybox:latest", "top"); err != nil {
+			c.Fatalf("Could not run %s: err=%v\n%s", name, err, out)
+		}
+	}
+	if out, err := d.Cmd("stop", "stopped"); err != nil {
+		c.Fatalf("Could not stop container: err=%v\n%s", err, out)
+	}
+
+	if err := d.Restart(); err != nil {
+		c.Fatalf("Could not restart daemon: %v", err)
+	}
+
+	out, err := d.Cmd("ps")
+	if err != nil {
+		c.Fatalf("Could not run ps: err=%v\n%q", err, out)
+	}
+	if !strings.Contains(out, "running") {
+		c.Fatalf("Container running before the daemon restart is not running: %s", out)
+	}
+	if strings.Contains(out, "stopped") {
+		c.Fatalf("Container stopped by the user was started with the daemon: %s", out)
+	}
+}
+
+func (s *DockerSuite) TestDaemonLiveRestore(c *check.C) {
+	d := NewDaemon(c)
+	if err := d.StartWithBusybox("--live-restore"); err != nil {
+		c.Fatalf("Could not start daemon with busybox: %v", err)
+	}
+	defer d.Stop()
+
+	if out, err := d.Cmd("run", "-d", "--name", "top", "busybox:latest", "top"); err != nil {
+		c.Fatalf("Could not run top: err=%v\n%s", err, out)
+	}
+	pid, err := d.Cmd("inspect", "-f", "{{.State.Pid}}", "top")
+	if err != nil {
+		c.Fatalf("Could not inspect top: err=%v\n%s", err, pid)
+	}
+
+	if err := d.Restart("--live-restore"); err != nil {
+		c.Fatalf("Could not restart daemon: %v", err)
+	}
+
+	out, err := d.Cmd("inspect", "-f", "{{.State.Pid}}", "top")
+	if err != nil {
+		c.Fatalf("Could not inspect top: err=%v\n%s", err, out)
+	}
+	if out != pid {
+		c.Fatalf("Container should have kept running with pid %s, has pid %s", pid, out)
+	}
+
+	// the container is monitored again
+	if out, err := d.Cmd("stop", "top"); err != nil {
+		c.Fatalf("Could not stop top: err=%v\n%s", err, out)
+	}
+	out, err = d.Cmd("inspect", "-f", "{{.State.Running}}", "top")
+	if err != nil {
+		c.Fatalf("Could not inspect top: err=%v\n%s", err, out)
+	}
+	if strings.TrimSpace(out) != "false" {
+		c.Fatalf("Container should have stopped: %s", out)
+	}
+}
+
+// testLiveRestoreNetwork checks that a container on the network given with
+// --net keeps running with its network when a daemon started with args is
+// restarted.
+func testLiveRestoreNetwork(c *check.C, net string, args ...string) {
+	args = append([]string{"--live-restore"}, args...)
+	d := NewDaemon(c)
+	if err := d.StartWithBusybox(args...); err != nil {
+		c.Fatalf("Could not start daemon with busybox: %v", err)
+	}
+	defer d.Stop()
+
+	if out, err := d.Cmd("run", "-d", "--name", "top", "--net", net, "busybox:latest", "top"); err != nil {
+		c.Fatalf("Could not run top: err=%v\n%s", err, out)
+	}
+	before, err := d.Cmd("inspect", "-f", "{{.State.Pid}} {{.NetworkSettings.IPAddress}}", "top")
+	if err != nil {
+		c.Fatalf("Could not inspect top: err=%v\n%s", err, before)
+	}
+
+	if err := d.Restart(args...); err != nil {
+		c.Fatalf("Could not restart daemon: %v", err)
+	}
+
+	after, err := d.Cmd("inspect", "-f", "{{.State.Pid}} {{.NetworkSettings.IPAddress}}", "top")
+	if err != nil {
+		c.Fatalf("Could not inspect top: err=%v\n%s", err, after)
+	}
+	if after != before {
+		c.Fatalf("Container should have kept running with the same pid and address %s, has %s", before, after)
+	}
+	ip := strings.Fields(before)[1]
+	out, err := d.Cmd("exec", "top", "ip", "-4", "addr", "show", "eth0")
+	if err != nil || !strings.Contains(out, ip) {
+		c.Fatalf("Container should have kept its address %s on eth0: err=%v\n%s", ip, err, out)
+	}
+
+	// the network is released when the container stops
+	if out, err := d.Cmd("stop", "top"); err != nil {
+		c.Fatalf("Could not stop top: err=%v\n%s", err, out)
+	}
+	if out, err := d.Cmd("run", "--rm", "--net", net, "busybox:latest", "true"); err != nil {
+		c.Fatalf("Could not run a container on %s after the restore: err=%v\n%s", net, err, out)
+	}
+}
+
+func (s *DockerSuite) TestDaemonLiveRestoreMacvlan(c *check.C) {
+	if out, err := exec.Command("ip", "link", "add", "dockermv0", "type", "dummy").CombinedOutput(); err != nil {
+		c.Skip(fmt.Sprintf("Unable to create a dummy interface: %v %s", err, out))
+	}
+	defer exec.Command("ip", "link", "del", "dockermv0").Run()
+
+	testLiveRestoreNetwork(c, "macvlan", "--macvlan-parent=dockermv0", "--macvlan-subnet=192.168.97.0/24")
+}
+
+func (s *DockerSuite) TestDaemonLiveRestoreOverlay(c *check.C) {
+	testLiveRestoreNetwork(c, "overlay:blue", "--overlay-bind=127.0.0.1", "--overlay-network=blue:4097:10.97.0.0/24")
+}
+
+func (s *DockerSuite) TestDaemonShutdownTimeout(c *check.C) {
+	d := NewDaemon(c)
+	if err := d.StartWithBusybox("--shutdown-timeout=1"); err != nil {
+		c.Fatalf("Could not start daemon with busybox: %v", err)
+	}
+	defer d.Stop()
+
+	// sleep ignores SIGTERM as the init of the container, the daemon has to
+	// kill it after the shutdown timeout
+	if out, err := d.Cmd("run", "-d", "--name", "sleeper", "busybox:latest", "sleep", "100"); err != nil {
+		c.Fatalf("Could not run sleeper: err=%v\n%s", err, out)
+	}
+	// the link parent is stopped before the container it links to
+	if out, err := d.Cmd("run", "-d", "--name", "parent", "--link", "sleeper:sleeper", "busybox:latest", "top"); err != nil {
+		c.Fatalf("Could not run parent: err=%v\n%s", err, out)
+	}
+
+	start := time.Now()
+	if err := d.Restart("--shutdown-timeout=1"); err != nil {
+		c.Fatalf("Could not restart daemon: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 15*time.Second {
+		c.Fatalf("Expected the daemon to stop its containers in time, it took %v", elapsed)
+	}
+
+	out, err := d.Cmd("inspect", "-f", "{{.State.ExitCode}} {{.State.FinishedAt.UnixNano}}", "sleeper")
+	if err != nil {
+		c.Fatalf("Could not inspect sleeper: err=%v\n%s", err, out)
+	}
+	var sleeperExit, sleeperFinished int64
+	if _, err := fmt.Sscan(out, &sleeperExit, &sleeperFinished); err != nil {
+		c.Fatalf("Could not parse %q: %v", out, err)
+	}
+	if sleeperExit != 137 {
+		c.Fatalf("Expected sleeper to be killed, it exited with %d", sleeperExit)
+	}
+
+	out, err = d.Cmd("inspect", "-f", "{{.State.FinishedAt.UnixNano}}", "parent")
+	if err != nil {
+		c.Fatalf("Could not inspect parent: err=%v\n%s", err, out)
+	}
+	var parentFinished int64
+	if _, err := fmt.Sscan(out, &parentFinished); err != nil {
+		c.Fatalf("Could not parse %q: %v", out, err)
+	}
+	if parentFinished > sleeperFinished {
+		c.Fatalf("Expected parent to be stopped before sleeper")
+	}
+}
+
 func (s *DockerSuite) TestDaemonRestartWithVolumesRefs(c *check.C) {
 	d := NewDaemon(c)
 	if err := d.StartWithBusybox(); err != nil {
@@ -4,12 +4,15 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -647,3 +650,57 @@ func (s *DockerSuite) TestExecWithPrivileged(c *check.C) {
 	}
 
 }
+
+func (s *DockerSuite) TestExecWithEnvAndWorkdir(c *check.C) {
+	runCmd := exec.Command(dockerBinary, "run", "-d", "--name", "exec_env", "-e", "FOO=container", "-e", "BAR=container", "-w", "/root", "busybox", "top")
+	if out, _, err := runCommandWithOutput(runCmd); err != nil {
+		c.Fatal(out, err)
+	}
+
+	out, _ := dockerCmd(c, "exec", "exec_env", "sh", "-c", "echo $FOO $BAR; pwd")
+	if actual := strings.TrimSpace(out); actual != "container container\n/root" {
+		c.Fatalf("exec should run with the environment and working directory of the container, got %q", out)
+	}
+
+	out, _ = dockerCmd(c, "exec", "-e", "FOO=exec", "-w", "/tmp", "exec_env", "sh", "-c", "echo $FOO $BAR; pwd")
+	if actual := strings.TrimSpace(out); actual != "exec container\n/tmp" {
+		c.Fatalf("exec should override the environment and working directory, got %q", out)
+	}
+}
+
+func (s *DockerSuite) TestExecInspectSessions(c *check.C) {
+	dockerCmd(c, "run", "-d", "--name", "exec_sessions", "busybox", "top")
+	dockerCmd(c, "exec", "-d", "exec_sessions", "sleep", "100")
+
+	out, err := inspectFieldJSON("exec_sessions", "ExecSessions")
+	if err != nil {
+		c.Fatal(err)
+	}
+	var sessions []struct {
+		ID      string
+		Cmd     []string
+		Running bool
+		Pid     int
+	}
+	if err := json.Unmarshal([]byte(out), &sessions); err != nil {
+		c.Fatal(err)
+	}
+	if len(sessions) != 1 || !reflect.DeepEqual(sessions[0].Cmd, []string{"sleep", "100"}) {
+		c.Fatalf("Expected the sleep command in the exec sessions, got %s", out)
+	}
+	if !sessions[0].Running || sessions[0].Pid == 0 {
+		c.Fatalf("Expected the exec session to be running with a pid, got %s", out)
+	}
+
+	status, _, err := sockRequest("POST", "/exec/"+sessions[0].ID+"/kill?signal=TERM", nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(status, check.Equals, http.StatusNoContent)
+
+	if err := waitInspect("exec_sessions", "{{len .ExecSessions}}", "0", 5); err != nil {
+		c.Fatal(err)
+	}
+	out, _ = dockerCmd(c, "events", "--since=0", "--until="+strconv.FormatInt(daemonTime(c).Unix(), 10))
+	if !strings.Contains(out, "exec_die: sleep 100 (exit code 143)") {
+		c.Fatalf("Expected an exec_die event with the exit code, got %s", out)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/go-check/check"
+)
+
+func waitForHealthStatus(c *check.C, name, prev, expected string) {
+	for {
+		status, err := inspectField(name, "State.Health.Status")
+		if err != nil {
+			c.Fatal(err)
+		}
+		if status == expected {
+			return
+		}
+		if status != prev {
+			c.Fatalf("Expected health status %q of %s to become %q, got %q", prev, name, expected, status)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func (s *DockerSuite) TestHealth(c *check.C) {
+	imageName := "testhealth"
+	defer deleteImages(imageName)
+	_, err := buildImage(imageName,
+		`FROM busybox
+		RUN echo OK > /status
+		CMD ["/bin/sleep", "120"]
+		HEALTHCHECK --interval=1s --timeout=30s \
+		  CMD cat /status`,
+		true)
+	if err != nil {
+		c.Fatal(err)
+	}
+
+	// no health check until the container is started
+	name := "test_health"
+	out, _ := dockerCmd(c, "create", "--name", name, imageName)
+	id := strings.TrimSpace(out)
+	if out, _ := dockerCmd(c, "ps", "-a", "-q", "--no-trunc", "--filter=health=none"); !strings.Contains(out, id) {
+		c.Fatalf("Expected %s to have no health before it starts, got %q", name, out)
+	}
+
+	// the check passes once the container is running
+	dockerCmd(c, "start", name)
+	waitForHealthStatus(c, name, "starting", "healthy")
+
+	// and fails when the status file goes bad
+	dockerCmd(c, "exec", name, "rm", "/status")
+	waitForHealthStatus(c, name, "healthy", "unhealthy")
+
+	if out, _ := dockerCmd(c, "ps", "-q", "--no-trunc", "--filter=health=unhealthy"); strings.TrimSpace(out) != id {
+		c.Fatalf("Expected %s to be listed as unhealthy, got %q", name, out)
+	}
+
+	var health types.Health
+	out, err = inspectFieldJSON(name, "State.Health")
+	if err != nil {
+		c.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(out), &health); err != nil {
+		c.Fatal(err)
+	}
+	last := health.Log[len(health.Log)-1]
+	if last.ExitCode != 1 || !strings.Contains(last.Output, "No such file") {
+		c.Fatalf("Unexpected result of the last check: %+v", last)
+	}
+	dockerCmd(c, "rm", "-f", name)
+
+	// the check of the image can be disabled
+	out, _ = dockerCmd(c, "run", "-d", "--no-healthcheck", imageName)
+	id = strings.TrimSpace(out)
+	if out, _ := inspectField(id, "State.Health"); out != "<nil>" {
+		c.Fatalf("Expected no health check, got %s", out)
+	}
+	dockerCmd(c, "rm", "-f", id)
+
+	// or replaced
+	out, _ = dockerCmd(c, "run", "-d", "--health-cmd=exit 0", "--health-interval=500ms", imageName)
+	id = strings.TrimSpace(out)
+	waitForHealthStatus(c, id, "starting", "healthy")
+	dockerCmd(c, "rm", "-f", id)
+}
+
+func (s *DockerSuite) TestHealthEvents(c *check.C) {
+	since := daemonTime(c).Unix()
+	out, _ := dockerCmd(c, "run", "-d", "--health-cmd=exit 1", "--health-interval=500ms", "--health-retries=1", "busybox", "sleep", "120")
+	id := strings.TrimSpace(out)
+	defer dockerCmd(c, "rm", "-f", id)
+	waitForHealthStatus(c, id, "starting", "unhealthy")
+
+	out, _ = dockerCmd(c, "events", fmt.Sprintf("--since=%d", since), fmt.Sprintf("--until=%d", daemonTime(c).Unix()+1))
+	if !strings.Contains(out, id+": (from busybox) health_status: unhealthy") {
+		c.Fatalf("Missing health_status event in %s", out)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"os/exec"
 	"strings"
 	"time"
@@ -241,3 +242,34 @@ func (s *DockerSuite) TestContainerRestartwithGoodContainer(c *check.C) {
 	}
 
 }
+
+func (s *DockerSuite) TestContainerRestartDelay(c *check.C) {
+	// the delay is doubled for a container failing right away
+	out, err := exec.Command(dockerBinary, "run", "-d", "--restart=always", "--restart-delay=5s", "busybox", "false").CombinedOutput()
+	if err != nil {
+		c.Fatal(string(out), err)
+	}
+	id := strings.TrimSpace(string(out))
+	if err := waitInspect(id, "{{ .State.Restarting }}", "true", 5); err != nil {
+		c.Fatal(err)
+	}
+
+	out, err = exec.Command(dockerBinary, "inspect", "-f", "{{ .State.FinishedAt.Unix }} {{ .State.NextRestartAt.Unix }}", id).CombinedOutput()
+	if err != nil {
+		c.Fatal(string(out), err)
+	}
+	var finished, next int64
+	if _, err := fmt.Sscan(string(out), &finished, &next); err != nil {
+		c.Fatal(string(out), err)
+	}
+	if delay := next - finished; delay < 9 || delay > 11 {
+		c.Fatalf("Expected the next restart in 10 seconds, got %d seconds", delay)
+	}
+	count, err := inspectField(id, "RestartCount")
+	if err != nil {
+		c.Fatal(err)
+	}
+	if count != "0" {
+		c.Fatalf("Container was restarted %s times before the delay, expected 0", count)
+	}
+}
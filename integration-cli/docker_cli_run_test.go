@@ -2989,6 +2989,28 @@ func (s *DockerSuite) TestRunContainerWithRmFlagExitCodeNotEqualToZero(c *check.
 	}
 }
 
+// an attached container with --rm is removed by the daemon, and docker run
+// still exits with its exit code
+func (s *DockerSuite) TestRunAttachedWithRmFlagRemovedByDaemon(c *check.C) {
+	name := "petals"
+	runCmd := exec.Command(dockerBinary, "run", "--name", name, "--rm", "busybox", "sh", "-c", "exit 3")
+	out, exitCode, err := runCommandWithOutput(runCmd)
+	if err == nil || exitCode != 3 {
+		c.Fatalf("Expected docker run to exit with 3, got %d: %s (%v)", exitCode, out, err)
+	}
+
+	if out, err := getAllContainers(); err != nil || out != "" {
+		c.Fatal("Expected not to have containers", out, err)
+	}
+
+	out, _ = dockerCmd(c, "events", "--since=0", fmt.Sprintf("--until=%d", daemonTime(c).Unix()))
+	events := strings.Split(strings.TrimSpace(out), "\n")
+	destroyEvent := strings.Fields(events[len(events)-1])
+	if destroyEvent[len(destroyEvent)-1] != "destroy" {
+		c.Fatalf("event should be destroy, not %#v", destroyEvent)
+	}
+}
+
 func (s *DockerSuite) TestRunContainerWithRmFlagCannotStartContainer(c *check.C) {
 	name := "sparkles"
 	runCmd := exec.Command(dockerBinary, "run", "--name", name, "--rm", "busybox", "commandNotFound")
@@ -3007,6 +3029,31 @@ func (s *DockerSuite) TestRunContainerWithRmFlagCannotStartContainer(c *check.C)
 	}
 }
 
+// run a detached container with --rm should remove the container when it exits
+func (s *DockerSuite) TestRunDetachedWithRmFlag(c *check.C) {
+	name := "bubbles"
+	dockerCmd(c, "run", "-d", "--name", name, "--rm", "busybox", "true")
+
+	removed := false
+	for i := 0; i < 50; i++ {
+		if err := exec.Command(dockerBinary, "inspect", name).Run(); err != nil {
+			removed = true
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if !removed {
+		c.Fatal("Expected the container to be removed when it exited")
+	}
+
+	out, _ := dockerCmd(c, "events", "--since=0", fmt.Sprintf("--until=%d", daemonTime(c).Unix()))
+	events := strings.Split(strings.TrimSpace(out), "\n")
+	destroyEvent := strings.Fields(events[len(events)-1])
+	if destroyEvent[len(destroyEvent)-1] != "destroy" {
+		c.Fatalf("event should be destroy, not %#v", destroyEvent)
+	}
+}
+
 func (s *DockerSuite) TestRunPidHostWithChildIsKillable(c *check.C) {
 	name := "ibuildthecloud"
 	if out, err := exec.Command(dockerBinary, "run", "-d", "--pid=host", "--name", name, "busybox", "sh", "-c", "sleep 30; echo hi").CombinedOutput(); err != nil {
@@ -3029,3 +3076,191 @@ func (s *DockerSuite) TestRunPidHostWithChildIsKillable(c *check.C) {
 		c.Fatal("Kill container timed out")
 	}
 }
+
+func (s *DockerSuite) TestRunStopSignal(c *check.C) {
+	out, _ := dockerCmd(c, "run", "-d", "--stop-signal=SIGUSR1", "busybox",
+		"sh", "-c", "trap 'exit 42' USR1; while true; do sleep 1; done")
+	id := strings.TrimSpace(out)
+	defer dockerCmd(c, "rm", "-f", id)
+
+	if res, err := inspectField(id, "Config.StopSignal"); err != nil || res != "SIGUSR1" {
+		c.Fatalf("Expected stop signal SIGUSR1, got %q (%v)", res, err)
+	}
+
+	// the process exits on its own when it gets SIGUSR1, before being killed
+	dockerCmd(c, "stop", "-t", "30", id)
+	if res, err := inspectField(id, "State.ExitCode"); err != nil || res != "42" {
+		c.Fatalf("Expected the container to exit with 42, got %q (%v)", res, err)
+	}
+
+	if out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "run", "--stop-signal=SIGNOPE", "busybox", "true")); err == nil {
+		c.Fatalf("Expected an error for an invalid stop signal, got %s", out)
+	}
+}
+
+func (s *DockerSuite) TestRunStopTimeout(c *check.C) {
+	// sleep ignores SIGTERM as the init of the container
+	out, _ := dockerCmd(c, "run", "-d", "--stop-timeout=1", "busybox", "sleep", "100")
+	id := strings.TrimSpace(out)
+	defer dockerCmd(c, "rm", "-f", id)
+
+	if res, err := inspectField(id, "Config.StopTimeout"); err != nil || res != "1" {
+		c.Fatalf("Expected stop timeout 1, got %q (%v)", res, err)
+	}
+
+	// without -t docker stop kills the container after its own timeout
+	start := time.Now()
+	dockerCmd(c, "stop", id)
+	if elapsed := time.Since(start); elapsed > 8*time.Second {
+		c.Fatalf("Expected the container to be killed after 1 second, it took %v", elapsed)
+	}
+
+	if out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "run", "--stop-timeout=-1", "busybox", "true")); err == nil {
+		c.Fatalf("Expected an error for a negative stop timeout, got %s", out)
+	}
+}
+
+func (s *DockerSuite) TestRunSysctls(c *check.C) {
+	testRequires(c, NativeExecDriver)
+	out, _ := dockerCmd(c, "run", "--rm", "--sysctl", "net.core.somaxconn=1024", "--sysctl", "kernel.shmmni=8192",
+		"busybox", "cat", "/proc/sys/net/core/somaxconn", "/proc/sys/kernel/shmmni")
+	if fields := strings.Fields(out); len(fields) != 2 || fields[0] != "1024" || fields[1] != "8192" {
+		c.Fatalf("Expected the sysctls to be set to 1024 and 8192, got %q", out)
+	}
+
+	for _, args := range [][]string{
+		{"--sysctl", "kernel.hostname=docker"},
+		{"--net=host", "--sysctl", "net.core.somaxconn=1024"},
+		{"--ipc=host", "--sysctl", "kernel.shmmni=8192"},
+	} {
+		args = append(append([]string{"run", "--rm"}, args...), "busybox", "true")
+		if out, _, err := runCommandWithOutput(exec.Command(dockerBinary, args...)); err == nil {
+			c.Fatalf("Expected an error for %v, got %s", args, out)
+		}
+	}
+}
+
+func (s *DockerSuite) TestRunShmSize(c *check.C) {
+	testRequires(c, NativeExecDriver)
+	out, _ := dockerCmd(c, "run", "--rm", "--shm-size=128m", "busybox", "grep", "/dev/shm", "/proc/self/mounts")
+	if !strings.Contains(out, "size=131072k") {
+		c.Fatalf("Expected /dev/shm to be mounted with a size of 128m, got %q", out)
+	}
+
+	out, _ = dockerCmd(c, "run", "--rm", "busybox", "grep", "/dev/shm", "/proc/self/mounts")
+	if !strings.Contains(out, "size=65536k") {
+		c.Fatalf("Expected /dev/shm to be mounted with the default size of 64m, got %q", out)
+	}
+}
+
+func (s *DockerSuite) TestRunGroupAdd(c *check.C) {
+	testRequires(c, NativeExecDriver)
+	out, _ := dockerCmd(c, "run", "--rm", "--group-add=audio", "--group-add=777", "busybox", "id", "-G")
+	groups := strings.Fields(out)
+	if len(groups) != 3 || groups[0] != "0" || groups[1] != "29" || groups[2] != "777" {
+		c.Fatalf("Expected the groups 0, 29 (audio) and 777, got %q", out)
+	}
+
+	if out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "run", "--rm", "--group-add=nosuchgroup", "busybox", "true")); err == nil {
+		c.Fatalf("Expected an error for an unknown group, got %s", out)
+	}
+}
+
+func (s *DockerSuite) TestRunSeccompProfile(c *check.C) {
+	testRequires(c, NativeExecDriver)
+
+	tmpFile, err := ioutil.TempFile("", "seccomp.json")
+	if err != nil {
+		c.Fatal(err)
+	}
+	defer os.Remove(tmpFile.Name())
+	profile := `{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"name": "chmod", "action": "SCMP_ACT_ERRNO"}]}`
+	if _, err := tmpFile.Write([]byte(profile)); err != nil {
+		c.Fatal(err)
+	}
+	tmpFile.Close()
+
+	out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "run", "--rm", "--security-opt", "seccomp="+tmpFile.Name(), "busybox", "chmod", "400", "/etc/hostname"))
+	if err == nil || !strings.Contains(out, "Operation not permitted") {
+		c.Fatalf("Expected chmod to be blocked by the seccomp profile, got %s (%v)", out, err)
+	}
+
+	dockerCmd(c, "run", "--rm", "--security-opt", "seccomp=unconfined", "busybox", "chmod", "400", "/etc/hostname")
+	dockerCmd(c, "run", "--rm", "--privileged", "--security-opt", "seccomp="+tmpFile.Name(), "busybox", "chmod", "400", "/etc/hostname")
+
+	if out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "run", "--rm", "--security-opt", "seccomp=/nonexistent.json", "busybox", "true")); err == nil {
+		c.Fatalf("Expected an error for a missing seccomp profile, got %s", out)
+	}
+}
+
+func (s *DockerSuite) TestRunOomScoreAdj(c *check.C) {
+	testRequires(c, NativeExecDriver)
+
+	out, _ := dockerCmd(c, "run", "--rm", "--oom-score-adj", "200", "busybox", "cat", "/proc/self/oom_score_adj")
+	if strings.TrimSpace(out) != "200" {
+		c.Fatalf("Expected an OOM score adjustment of 200, got %s", out)
+	}
+
+	if out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "run", "--rm", "--oom-score-adj", "1001", "busybox", "true")); err == nil {
+		c.Fatalf("Expected an error for an out of range OOM score adjustment, got %s", out)
+	}
+}
+
+func (s *DockerSuite) TestRunInvalidMemoryReservation(c *check.C) {
+	out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "run", "--rm", "-m", "16m", "--memory-reservation", "32m", "busybox", "true"))
+	if err == nil || !strings.Contains(out, "Minimum memory limit should be larger than memory reservation limit") {
+		c.Fatalf("Expected an error for a memory reservation above the memory limit, got %s (%v)", out, err)
+	}
+
+	out, _, err = runCommandWithOutput(exec.Command(dockerBinary, "run", "--rm", "--memory-swappiness", "101", "busybox", "true"))
+	if err == nil || !strings.Contains(out, "memory swappiness") {
+		c.Fatalf("Expected an error for an out of range memory swappiness, got %s (%v)", out, err)
+	}
+}
+
+func (s *DockerSuite) TestRunInvalidBlkioDevice(c *check.C) {
+	out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "run", "--rm", "--device-read-bps", "/dev/nonexistent:1mb", "busybox", "true"))
+	if err == nil || !strings.Contains(out, "Invalid block IO device /dev/nonexistent") {
+		c.Fatalf("Expected an error for a missing block device, got %s (%v)", out, err)
+	}
+
+	out, _, err = runCommandWithOutput(exec.Command(dockerBinary, "run", "--rm", "--blkio-weight-device", "/dev/null:500", "busybox", "true"))
+	if err == nil || !strings.Contains(out, "not a block device") {
+		c.Fatalf("Expected an error for a character device, got %s (%v)", out, err)
+	}
+}
+
+func (s *DockerSuite) TestRunPidsLimit(c *check.C) {
+	testRequires(c, NativeExecDriver)
+
+	// the shell and the first three sleeps fill the limit
+	out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "run", "--rm", "--pids-limit", "4", "busybox", "sh", "-c", "for i in 1 2 3 4; do sleep 5 & done; wait"))
+	if err != nil && strings.Contains(out, "Your kernel does not support pids limit capabilities") {
+		c.Skip("Your kernel does not support pids limit capabilities, skip this test")
+	}
+	if !strings.Contains(out, "can't fork") {
+		c.Fatalf("Expected the pids limit to prevent a fork, got %s (%v)", out, err)
+	}
+}
+
+func (s *DockerSuite) TestRunInit(c *check.C) {
+	testRequires(c, NativeExecDriver)
+
+	out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "run", "--rm", "--init", "busybox", "sh", "-c", "echo $$; cat /proc/1/cmdline"))
+	if err != nil {
+		c.Fatal(out, err)
+	}
+	lines := strings.SplitN(strings.TrimSpace(out), "\n", 2)
+	if lines[0] == "1" {
+		c.Fatalf("Expected the command not to run as pid 1 with --init, got %s", out)
+	}
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], "/dev/init") {
+		c.Fatalf("Expected the init to run as pid 1, got %s", out)
+	}
+
+	// the exit status of the command is the one of the container
+	out, exitCode, _ := runCommandWithOutput(exec.Command(dockerBinary, "run", "--rm", "--init", "busybox", "sh", "-c", "exit 3"))
+	if exitCode != 3 {
+		c.Fatalf("Expected an exit status of 3, got %d: %s", exitCode, out)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+
+	"github.com/go-check/check"
+)
+
+func (s *DockerSuite) TestUpdateRunningContainer(c *check.C) {
+	testRequires(c, NativeExecDriver)
+	name := "test-update-container"
+	dockerCmd(c, "run", "-d", "--name", name, "-m", "300M", "-c", "512", "busybox", "top")
+	defer dockerCmd(c, "rm", "-f", name)
+
+	dockerCmd(c, "update", "-m", "500M", "-c", "1024", "--restart=on-failure:5", name)
+
+	for field, expected := range map[string]string{
+		"HostConfig.Memory":                          "524288000",
+		"HostConfig.CpuShares":                       "1024",
+		"HostConfig.RestartPolicy.Name":              "on-failure",
+		"HostConfig.RestartPolicy.MaximumRetryCount": "5",
+	} {
+		res, err := inspectField(name, field)
+		if err != nil {
+			c.Fatal(err)
+		}
+		if res != expected {
+			c.Fatalf("Expected %s to be %s after the update, got %s", field, expected, res)
+		}
+	}
+
+	// the limits survive a restart of the container
+	dockerCmd(c, "restart", name)
+	if res, err := inspectField(name, "HostConfig.Memory"); err != nil || res != "524288000" {
+		c.Fatalf("Expected the updated memory limit after a restart, got %s (%v)", res, err)
+	}
+}
+
+func (s *DockerSuite) TestUpdateRemoveLimits(c *check.C) {
+	testRequires(c, NativeExecDriver)
+	name := "test-update-remove-limits"
+	dockerCmd(c, "run", "-d", "--name", name, "-m", "300M", "--cpu-quota", "50000", "busybox", "top")
+	defer dockerCmd(c, "rm", "-f", name)
+
+	dockerCmd(c, "update", "-m", "0", "--cpu-quota", "0", name)
+
+	for _, field := range []string{"HostConfig.Memory", "HostConfig.MemorySwap", "HostConfig.CpuQuota"} {
+		if res, err := inspectField(name, field); err != nil || res != "0" {
+			c.Fatalf("Expected %s to be removed, got %s (%v)", field, res, err)
+		}
+	}
+
+	// the limits stay removed when the container is restarted
+	dockerCmd(c, "restart", name)
+	if res, err := inspectField(name, "HostConfig.Memory"); err != nil || res != "0" {
+		c.Fatalf("Expected no memory limit after a restart, got %s (%v)", res, err)
+	}
+}
+
+func (s *DockerSuite) TestUpdateStoppedContainer(c *check.C) {
+	name := "test-update-stopped-container"
+	dockerCmd(c, "create", "--name", name, "busybox", "true")
+	defer dockerCmd(c, "rm", "-f", name)
+
+	dockerCmd(c, "update", "--cpuset-cpus=0", name)
+	if res, err := inspectField(name, "HostConfig.CpusetCpus"); err != nil || res != "0" {
+		c.Fatalf("Expected the cpuset to be updated, got %s (%v)", res, err)
+	}
+}
+
+func (s *DockerSuite) TestUpdateInvalidLimits(c *check.C) {
+	name := "test-update-invalid-limits"
+	dockerCmd(c, "create", "--name", name, "-m", "300M", "busybox", "true")
+	defer dockerCmd(c, "rm", "-f", name)
+	swap, err := inspectField(name, "HostConfig.MemorySwap")
+	if err != nil {
+		c.Fatal(err)
+	}
+
+	out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "update", "--memory-swap=200M", name))
+	if err == nil || !strings.Contains(out, "memoryswap limit should be larger than memory limit") {
+		c.Fatalf("Expected the update to fail, got %s (%v)", out, err)
+	}
+	if res, err := inspectField(name, "HostConfig.MemorySwap"); err != nil || res != swap {
+		c.Fatalf("A rejected update changed the container: memory swap %s (%v)", res, err)
+	}
+
+	if out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "update", name)); err == nil {
+		c.Fatalf("Expected an update without flags to fail, got %s", out)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"os/exec"
 	"strings"
 	"time"
@@ -113,3 +114,55 @@ func (s *DockerSuite) TestWaitBlockedExitRandom(c *check.C) {
 	}
 
 }
+
+// wait for the next exit of a container that is not started yet
+func (s *DockerSuite) TestWaitConditionNextExit(c *check.C) {
+	out, _ := dockerCmd(c, "create", "busybox", "sh", "-c", "exit 42")
+	containerID := strings.TrimSpace(out)
+
+	waitCmd := exec.Command(dockerBinary, "wait", "--condition=next-exit", containerID)
+	var waitOut bytes.Buffer
+	waitCmd.Stdout = &waitOut
+	if err := waitCmd.Start(); err != nil {
+		c.Fatal(err)
+	}
+	// give the wait the time to reach the daemon before the start
+	time.Sleep(time.Second)
+
+	dockerCmd(c, "start", containerID)
+
+	if err := waitCmd.Wait(); err != nil || strings.TrimSpace(waitOut.String()) != "42" {
+		c.Fatal("failed to wait for the next exit", waitOut.String(), err)
+	}
+}
+
+// wait for the removal of a container
+func (s *DockerSuite) TestWaitConditionRemoved(c *check.C) {
+	out, _ := dockerCmd(c, "run", "-d", "busybox", "sh", "-c", "exit 7")
+	containerID := strings.TrimSpace(out)
+	dockerCmd(c, "wait", containerID)
+
+	waitCmd := exec.Command(dockerBinary, "wait", "--condition=removed", containerID)
+	var waitOut bytes.Buffer
+	waitCmd.Stdout = &waitOut
+	if err := waitCmd.Start(); err != nil {
+		c.Fatal(err)
+	}
+	time.Sleep(time.Second)
+
+	dockerCmd(c, "rm", containerID)
+
+	if err := waitCmd.Wait(); err != nil || strings.TrimSpace(waitOut.String()) != "7" {
+		c.Fatal("failed to wait for the removal", waitOut.String(), err)
+	}
+}
+
+func (s *DockerSuite) TestWaitConditionInvalid(c *check.C) {
+	out, _ := dockerCmd(c, "create", "busybox", "true")
+	containerID := strings.TrimSpace(out)
+
+	runCmd := exec.Command(dockerBinary, "wait", "--condition=started", containerID)
+	if out, _, err := runCommandWithOutput(runCmd); err == nil {
+		c.Fatal("wait should fail for an unknown condition", out)
+	}
+}
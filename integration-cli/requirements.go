@@ -79,6 +79,13 @@ var (
 		"Test requires the native (libcontainer) exec driver.",
 	}
 
+	Criu = TestRequirement{
+		func() bool {
+			_, err := exec.LookPath("criu")
+			return err == nil
+		},
+		"Test requires criu to checkpoint and restore containers.",
+	}
 	NotOverlay = TestRequirement{
 		func() bool {
 			cmd := exec.Command("grep", "^overlay / overlay", "/proc/mounts")
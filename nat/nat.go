@@ -97,12 +97,24 @@ func ParsePortSpecs(ports []string) (map[Port]struct{}, map[Port][]PortBinding,
 
 	for _, rawPort := range ports {
 		proto := "tcp"
+		bracketedIp := ""
 
 		if i := strings.LastIndex(rawPort, "/"); i != -1 {
 			proto = rawPort[i+1:]
 			rawPort = rawPort[:i]
 		}
-		if !strings.Contains(rawPort, ":") {
+		if strings.HasPrefix(rawPort, "[") {
+			// IPv6 addresses are bracketed: [ip]:hostPort:containerPort
+			end := strings.Index(rawPort, "]:")
+			if end == -1 || len(strings.Split(rawPort[end+1:], ":")) != 3 {
+				return nil, nil, fmt.Errorf("Invalid port specification: %s", rawPort)
+			}
+			bracketedIp = rawPort[1:end]
+			if bracketedIp == "" {
+				return nil, nil, fmt.Errorf("Invalid ip address: %s", rawPort)
+			}
+			rawPort = rawPort[end+1:]
+		} else if !strings.Contains(rawPort, ":") {
 			rawPort = fmt.Sprintf("::%s", rawPort)
 		} else if len(strings.Split(rawPort, ":")) == 2 {
 			rawPort = fmt.Sprintf(":%s", rawPort)
@@ -118,6 +130,9 @@ func ParsePortSpecs(ports []string) (map[Port]struct{}, map[Port][]PortBinding,
 			rawIp         = parts["ip"]
 			hostPort      = parts["hostPort"]
 		)
+		if bracketedIp != "" {
+			rawIp = bracketedIp
+		}
 
 		if rawIp != "" && net.ParseIP(rawIp) == nil {
 			return nil, nil, fmt.Errorf("Invalid ip address: %s", rawIp)
@@ -200,6 +200,33 @@ func TestParsePortSpecs(t *testing.T) {
 	}
 }
 
+func TestParsePortSpecsIPv6(t *testing.T) {
+	portMap, bindingMap, err := ParsePortSpecs([]string{"[::]:1234:1234/tcp", "[2001:db8::1]::2345/udp"})
+	if err != nil {
+		t.Fatalf("Error while processing ParsePortSpecs: %s", err)
+	}
+
+	if _, ok := portMap[Port("1234/tcp")]; !ok {
+		t.Fatal("1234/tcp was not parsed properly")
+	}
+	if bindings := bindingMap[Port("1234/tcp")]; len(bindings) != 1 || bindings[0].HostIp != "::" || bindings[0].HostPort != "1234" {
+		t.Fatalf("Unexpected bindings for 1234/tcp: %v", bindings)
+	}
+
+	if _, ok := portMap[Port("2345/udp")]; !ok {
+		t.Fatal("2345/udp was not parsed properly")
+	}
+	if bindings := bindingMap[Port("2345/udp")]; len(bindings) != 1 || bindings[0].HostIp != "2001:db8::1" || bindings[0].HostPort != "" {
+		t.Fatalf("Unexpected bindings for 2345/udp: %v", bindings)
+	}
+
+	for _, spec := range []string{"[::]:1234", "[::1234:1234", "[]:1234:1234", "[::1]1234:1234", "[fe80::zz]:1234:1234"} {
+		if _, _, err := ParsePortSpecs([]string{spec}); err == nil {
+			t.Fatalf("Received no error while trying to parse %s", spec)
+		}
+	}
+}
+
 func TestParsePortSpecsWithRange(t *testing.T) {
 	var (
 		portMap    map[Port]struct{}
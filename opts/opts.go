@@ -235,6 +235,61 @@ func ValidateLabel(val string) (string, error) {
 	return val, nil
 }
 
+// ipcSysctls are the sysctls of the IPC namespace, besides fs.mqueue.*
+var ipcSysctls = map[string]bool{
+	"kernel.msgmax":          true,
+	"kernel.msgmnb":          true,
+	"kernel.msgmni":          true,
+	"kernel.sem":             true,
+	"kernel.shmall":          true,
+	"kernel.shmmax":          true,
+	"kernel.shmmni":          true,
+	"kernel.shm_rmid_forced": true,
+}
+
+// IsIpcSysctl returns whether the sysctl belongs to the IPC namespace.
+func IsIpcSysctl(key string) bool {
+	return ipcSysctls[key] || strings.HasPrefix(key, "fs.mqueue.")
+}
+
+// IsNetSysctl returns whether the sysctl belongs to the network namespace.
+func IsNetSysctl(key string) bool {
+	return strings.HasPrefix(key, "net.")
+}
+
+// isInterfaceSysctl returns whether the sysctl is set for a network
+// interface, like net.ipv4.conf.eth0.forwarding, other than lo and the
+// all and default entries. The sysctls of a container are set before its
+// interfaces are created.
+func isInterfaceSysctl(key string) bool {
+	parts := strings.Split(key, ".")
+	if len(parts) < 5 || parts[0] != "net" || (parts[1] != "ipv4" && parts[1] != "ipv6") || (parts[2] != "conf" && parts[2] != "neigh") {
+		return false
+	}
+	switch parts[3] {
+	case "all", "default", "lo":
+		return false
+	}
+	return true
+}
+
+// ValidateSysctl validates a sysctl given as key=value. Only the sysctls of
+// the namespaces of a container are allowed, the others would change the
+// settings of the host.
+func ValidateSysctl(val string) (string, error) {
+	arr := strings.SplitN(val, "=", 2)
+	if len(arr) != 2 || arr[0] == "" {
+		return "", fmt.Errorf("bad sysctl format: %s", val)
+	}
+	if !IsIpcSysctl(arr[0]) && !IsNetSysctl(arr[0]) {
+		return "", fmt.Errorf("sysctl %s is not namespaced, it can't be set for a container", arr[0])
+	}
+	if isInterfaceSysctl(arr[0]) {
+		return "", fmt.Errorf("sysctl %s is set for a network interface, only the all, default and lo interfaces exist when it is set", arr[0])
+	}
+	return val, nil
+}
+
 func ValidateHost(val string) (string, error) {
 	host, err := parsers.ParseHost(DefaultHTTPHost, DefaultUnixSocket, val)
 	if err != nil {
@@ -152,3 +152,36 @@ func TestValidateExtraHosts(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateSysctl(t *testing.T) {
+	valid := []string{
+		"net.core.somaxconn=1024",
+		"net.ipv4.tcp_syncookies=0",
+		"kernel.shmmax=68719476736",
+		"kernel.sem=250 32000 100 128",
+		"fs.mqueue.msg_max=100",
+		"net.ipv4.conf.all.forwarding=1",
+		"net.ipv6.conf.default.disable_ipv6=1",
+		"net.ipv4.conf.lo.route_localnet=1",
+	}
+	invalid := map[string]string{
+		"net.core.somaxconn":                   "bad sysctl format",
+		"=1024":                                "bad sysctl format",
+		"kernel.hostname=docker":               "not namespaced",
+		"vm.swappiness=10":                     "not namespaced",
+		"fs.file-max=100":                      "not namespaced",
+		"net.ipv4.conf.eth0.forwarding=1":      "network interface",
+		"net.ipv6.neigh.eth0.gc_stale_time=60": "network interface",
+	}
+
+	for _, sysctl := range valid {
+		if _, err := ValidateSysctl(sysctl); err != nil {
+			t.Fatalf("ValidateSysctl(`%s`) should succeed: error %v", sysctl, err)
+		}
+	}
+	for sysctl, expectedError := range invalid {
+		if _, err := ValidateSysctl(sysctl); err == nil || !strings.Contains(err.Error(), expectedError) {
+			t.Fatalf("ValidateSysctl(`%s`) should have failed with %q, got %v", sysctl, expectedError, err)
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/pkg/fileutils"
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/pools"
 	"github.com/docker/docker/pkg/promise"
 	"github.com/docker/docker/pkg/system"
@@ -35,6 +36,10 @@ type (
 		Compression     Compression
 		NoLchown        bool
 		Name            string
+		// UIDMaps and GIDMaps translate the owners of the files between
+		// the ids used on the host and the ids used in the archive.
+		UIDMaps []idtools.IDMap
+		GIDMaps []idtools.IDMap
 	}
 
 	// Archiver allows the reuse of most utility functions of this package
@@ -170,6 +175,10 @@ type tarAppender struct {
 
 	// for hardlink mapping
 	SeenFiles map[uint64]string
+
+	// for translating host ids into the ids written to the archive
+	UIDMaps []idtools.IDMap
+	GIDMaps []idtools.IDMap
 }
 
 // canonicalTarName provides a platform-independent and consistent posix-style
@@ -231,6 +240,17 @@ func (ta *tarAppender) addTarFile(path, name string) error {
 		}
 	}
 
+	// the archive carries the ids the files have inside the container,
+	// not the ones they were remapped to on the host
+	if ta.UIDMaps != nil || ta.GIDMaps != nil {
+		if hdr.Uid, err = idtools.ToContainer(hdr.Uid, ta.UIDMaps); err != nil {
+			return err
+		}
+		if hdr.Gid, err = idtools.ToContainer(hdr.Gid, ta.GIDMaps); err != nil {
+			return err
+		}
+	}
+
 	capability, _ := system.Lgetxattr(path, "security.capability")
 	if capability != nil {
 		hdr.Xattrs = make(map[string]string)
@@ -403,6 +423,8 @@ func TarWithOptions(srcPath string, options *TarOptions) (io.ReadCloser, error)
 			TarWriter: tar.NewWriter(compressWriter),
 			Buffer:    pools.BufioWriter32KPool.Get(nil),
 			SeenFiles: make(map[uint64]string),
+			UIDMaps:   options.UIDMaps,
+			GIDMaps:   options.GIDMaps,
 		}
 		// this buffer is needed for the duration of this piped stream
 		defer pools.BufioWriter32KPool.Put(ta.Buffer)
@@ -556,6 +578,11 @@ loop:
 			}
 		}
 		trBuf.Reset(tr)
+
+		if err := remapIDs(hdr, options.UIDMaps, options.GIDMaps); err != nil {
+			return err
+		}
+
 		if err := createTarFile(path, dest, hdr, trBuf, !options.NoLchown); err != nil {
 			return err
 		}
@@ -577,6 +604,24 @@ loop:
 	return nil
 }
 
+// remapIDs translates the owner of an archive entry into the ids it has on
+// the host.
+func remapIDs(hdr *tar.Header, uidMaps, gidMaps []idtools.IDMap) error {
+	if uidMaps == nil && gidMaps == nil {
+		return nil
+	}
+	uid, err := idtools.ToHost(hdr.Uid, uidMaps)
+	if err != nil {
+		return err
+	}
+	gid, err := idtools.ToHost(hdr.Gid, gidMaps)
+	if err != nil {
+		return err
+	}
+	hdr.Uid, hdr.Gid = uid, gid
+	return nil
+}
+
 // Untar reads a stream of bytes from `archive`, parses it as a tar archive,
 // and unpacks it into the directory at `dest`.
 // The archive may be compressed with one of the following algorithms:
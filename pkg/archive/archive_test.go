@@ -14,6 +14,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/system"
 	"github.com/docker/docker/vendor/src/code.google.com/p/go/src/pkg/archive/tar"
 )
@@ -709,6 +710,62 @@ func TestTarWithOptions(t *testing.T) {
 	}
 }
 
+func TestTarUntarWithIDMaps(t *testing.T) {
+	origin, err := ioutil.TempDir("", "docker-test-untar-origin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(origin)
+	if err := ioutil.WriteFile(path.Join(origin, "1"), []byte("hello world"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chown(path.Join(origin, "1"), 100000, 100000); err != nil {
+		t.Fatal(err)
+	}
+
+	idMaps := []idtools.IDMap{{ContainerID: 0, HostID: 100000, Size: 65536}}
+	options := &TarOptions{
+		IncludeFiles: []string{"1"},
+		UIDMaps:      idMaps,
+		GIDMaps:      idMaps,
+	}
+
+	archive, err := TarWithOptions(origin, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := ioutil.ReadAll(archive)
+	archive.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hdr, err := tar.NewReader(bytes.NewReader(buf)).Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Uid != 0 || hdr.Gid != 0 {
+		t.Fatalf("Expected the archive to be owned by 0:0, got %d:%d", hdr.Uid, hdr.Gid)
+	}
+
+	dest, err := ioutil.TempDir("", "docker-test-untar-dest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+	if err := Untar(bytes.NewReader(buf), dest, &TarOptions{UIDMaps: idMaps, GIDMaps: idMaps}); err != nil {
+		t.Fatal(err)
+	}
+
+	var stat syscall.Stat_t
+	if err := syscall.Lstat(path.Join(dest, "1"), &stat); err != nil {
+		t.Fatal(err)
+	}
+	if stat.Uid != 100000 || stat.Gid != 100000 {
+		t.Fatalf("Expected the unpacked file to be owned by 100000:100000, got %d:%d", stat.Uid, stat.Gid)
+	}
+}
+
 // Some tar archives such as http://haproxy.1wt.eu/download/1.5/src/devel/haproxy-1.5-dev21.tar.gz
 // use PAX Global Extended Headers.
 // Failing prevents the archives from being uncompressed during ADD
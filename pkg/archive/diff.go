@@ -15,7 +15,14 @@ import (
 	"github.com/docker/docker/pkg/system"
 )
 
-func UnpackLayer(dest string, layer ArchiveReader) (size int64, err error) {
+// UnpackLayer unpacks the uncompressed layer `layer` into the directory
+// `dest`, translating file owners with the id maps in `options`, if any.
+// Returns the size in bytes of the contents of the layer.
+func UnpackLayer(dest string, layer ArchiveReader, options *TarOptions) (size int64, err error) {
+	if options == nil {
+		options = &TarOptions{}
+	}
+
 	tr := tar.NewReader(layer)
 	trBuf := pools.BufioReader32KPool.Get(tr)
 	defer pools.BufioReader32KPool.Put(trBuf)
@@ -69,6 +76,9 @@ func UnpackLayer(dest string, layer ArchiveReader) (size int64, err error) {
 					}
 					defer os.RemoveAll(aufsTempdir)
 				}
+				if err := remapIDs(hdr, options.UIDMaps, options.GIDMaps); err != nil {
+					return 0, err
+				}
 				if err := createTarFile(filepath.Join(aufsTempdir, basename), dest, hdr, tr, true); err != nil {
 					return 0, err
 				}
@@ -123,6 +133,8 @@ func UnpackLayer(dest string, layer ArchiveReader) (size int64, err error) {
 				}
 				defer tmpFile.Close()
 				srcData = tmpFile
+			} else if err := remapIDs(srcHdr, options.UIDMaps, options.GIDMaps); err != nil {
+				return 0, err
 			}
 
 			if err := createTarFile(path, dest, srcHdr, srcData, true); err != nil {
@@ -152,6 +164,12 @@ func UnpackLayer(dest string, layer ArchiveReader) (size int64, err error) {
 // applies it to the directory `dest`. Returns the size in bytes of the
 // contents of the layer.
 func ApplyLayer(dest string, layer ArchiveReader) (int64, error) {
+	return ApplyLayerWithOptions(dest, layer, nil)
+}
+
+// ApplyLayerWithOptions is like ApplyLayer, but translates the owners of
+// the files in the layer with the id maps in `options`.
+func ApplyLayerWithOptions(dest string, layer ArchiveReader, options *TarOptions) (int64, error) {
 	dest = filepath.Clean(dest)
 
 	// We need to be able to set any perms
@@ -165,5 +183,5 @@ func ApplyLayer(dest string, layer ArchiveReader) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	return UnpackLayer(dest, layer)
+	return UnpackLayer(dest, layer, options)
 }
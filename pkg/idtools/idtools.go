@@ -0,0 +1,172 @@
+package idtools
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// IDMap maps a range of container ids onto a range of host ids, the
+// same way the uid_map and gid_map files of a user namespace do.
+type IDMap struct {
+	ContainerID int `json:"container_id"`
+	HostID      int `json:"host_id"`
+	Size        int `json:"size"`
+}
+
+type subIDRange struct {
+	Start  int
+	Length int
+}
+
+const (
+	subuidFileName = "/etc/subuid"
+	subgidFileName = "/etc/subgid"
+)
+
+// MkdirAs creates a directory with the given mode and makes it owned by
+// ownerUID:ownerGID. Like os.Mkdir, it fails if the directory exists.
+func MkdirAs(path string, mode os.FileMode, ownerUID, ownerGID int) error {
+	if err := os.Mkdir(path, mode); err != nil {
+		return err
+	}
+	return os.Chown(path, ownerUID, ownerGID)
+}
+
+// MkdirAllAs creates a directory (and any missing parents) with the given
+// mode and makes the final directory owned by ownerUID:ownerGID. The owner
+// of a directory that already exists is left alone.
+func MkdirAllAs(path string, mode os.FileMode, ownerUID, ownerGID int) error {
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(path, mode); err != nil {
+		return err
+	}
+	return os.Chown(path, ownerUID, ownerGID)
+}
+
+// GetRootUIDGID returns the host uid and gid that root inside a
+// container is mapped to. Without mappings this is 0 and 0.
+func GetRootUIDGID(uidMap, gidMap []IDMap) (int, int, error) {
+	uid, err := ToHost(0, uidMap)
+	if err != nil {
+		return -1, -1, err
+	}
+	gid, err := ToHost(0, gidMap)
+	if err != nil {
+		return -1, -1, err
+	}
+	return uid, gid, nil
+}
+
+// ToContainer translates a host id into the id it has inside a container
+// using the given mapping. Ids are returned unchanged if there is no mapping.
+func ToContainer(hostID int, idMap []IDMap) (int, error) {
+	if idMap == nil {
+		return hostID, nil
+	}
+	for _, m := range idMap {
+		if hostID >= m.HostID && hostID < m.HostID+m.Size {
+			return m.ContainerID + (hostID - m.HostID), nil
+		}
+	}
+	return -1, fmt.Errorf("Host ID %d cannot be mapped to a container ID", hostID)
+}
+
+// ToHost translates a container id into the id it has on the host using
+// the given mapping. Ids are returned unchanged if there is no mapping.
+func ToHost(contID int, idMap []IDMap) (int, error) {
+	if idMap == nil {
+		return contID, nil
+	}
+	for _, m := range idMap {
+		if contID >= m.ContainerID && contID < m.ContainerID+m.Size {
+			return m.HostID + (contID - m.ContainerID), nil
+		}
+	}
+	return -1, fmt.Errorf("Container ID %d cannot be mapped to a host ID", contID)
+}
+
+// CreateIDMappings builds the uid and gid mappings for the subordinate id
+// ranges that /etc/subuid and /etc/subgid assign to username and groupname.
+func CreateIDMappings(username, groupname string) ([]IDMap, []IDMap, error) {
+	subuidRanges, err := parseSubuid(username)
+	if err != nil {
+		return nil, nil, err
+	}
+	subgidRanges, err := parseSubgid(groupname)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(subuidRanges) == 0 {
+		return nil, nil, fmt.Errorf("No subuid ranges found for user %q in %s", username, subuidFileName)
+	}
+	if len(subgidRanges) == 0 {
+		return nil, nil, fmt.Errorf("No subgid ranges found for group %q in %s", groupname, subgidFileName)
+	}
+	return createIDMap(subuidRanges), createIDMap(subgidRanges), nil
+}
+
+func createIDMap(subidRanges []subIDRange) []IDMap {
+	idMap := []IDMap{}
+
+	// the ranges are mapped one after the other, starting at container id 0
+	containerID := 0
+	for _, idrange := range subidRanges {
+		idMap = append(idMap, IDMap{
+			ContainerID: containerID,
+			HostID:      idrange.Start,
+			Size:        idrange.Length,
+		})
+		containerID = containerID + idrange.Length
+	}
+	return idMap
+}
+
+func parseSubuid(username string) ([]subIDRange, error) {
+	return parseSubidFile(subuidFileName, username)
+}
+
+func parseSubgid(username string) ([]subIDRange, error) {
+	return parseSubidFile(subgidFileName, username)
+}
+
+// parseSubidFile returns the ranges assigned to username in a file in the
+// subuid(5) format, one "name:start:count" entry per line.
+func parseSubidFile(path, username string) ([]subIDRange, error) {
+	var rangeList []subIDRange
+
+	subidFile, err := os.Open(path)
+	if err != nil {
+		return rangeList, err
+	}
+	defer subidFile.Close()
+
+	s := bufio.NewScanner(subidFile)
+	for s.Scan() {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		parts := strings.Split(text, ":")
+		if len(parts) != 3 {
+			return rangeList, fmt.Errorf("Cannot parse %s: invalid entry %q", path, text)
+		}
+		if parts[0] != username {
+			continue
+		}
+		startid, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return rangeList, fmt.Errorf("String to int conversion failed during subid file parsing %s: %v", path, err)
+		}
+		length, err := strconv.Atoi(parts[2])
+		if err != nil {
+			return rangeList, fmt.Errorf("String to int conversion failed during subid file parsing %s: %v", path, err)
+		}
+		rangeList = append(rangeList, subIDRange{startid, length})
+	}
+	return rangeList, s.Err()
+}
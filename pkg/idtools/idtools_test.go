@@ -0,0 +1,112 @@
+package idtools
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSubidFile(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "docker-idtools-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "subuid")
+	content := "# comment\nother:100000:65536\ndockremap:165536:65536\n\ndockremap:300000:1000\n"
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ranges, err := parseSubidFile(path, "dockremap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 2 {
+		t.Fatalf("Expected 2 ranges, got %d", len(ranges))
+	}
+
+	idMap := createIDMap(ranges)
+	expected := []IDMap{
+		{ContainerID: 0, HostID: 165536, Size: 65536},
+		{ContainerID: 65536, HostID: 300000, Size: 1000},
+	}
+	for i, m := range expected {
+		if idMap[i] != m {
+			t.Fatalf("Expected mapping %v, got %v", m, idMap[i])
+		}
+	}
+
+	ranges, err = parseSubidFile(path, "nobody")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 0 {
+		t.Fatalf("Expected no ranges, got %v", ranges)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("dockremap:165536\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseSubidFile(path, "dockremap"); err == nil {
+		t.Fatal("Expected an error for an invalid entry")
+	}
+}
+
+func TestToHostToContainer(t *testing.T) {
+	idMap := []IDMap{
+		{ContainerID: 0, HostID: 165536, Size: 65536},
+		{ContainerID: 65536, HostID: 300000, Size: 1000},
+	}
+
+	for contID, hostID := range map[int]int{0: 165536, 1000: 166536, 65536: 300000, 66535: 300999} {
+		id, err := ToHost(contID, idMap)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != hostID {
+			t.Fatalf("Expected container ID %d to map to host ID %d, got %d", contID, hostID, id)
+		}
+		id, err = ToContainer(hostID, idMap)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != contID {
+			t.Fatalf("Expected host ID %d to map to container ID %d, got %d", hostID, contID, id)
+		}
+	}
+
+	if _, err := ToHost(66536, idMap); err == nil {
+		t.Fatal("Expected an error for an unmapped container ID")
+	}
+	if _, err := ToContainer(0, idMap); err == nil {
+		t.Fatal("Expected an error for an unmapped host ID")
+	}
+
+	if id, err := ToHost(42, nil); err != nil || id != 42 {
+		t.Fatalf("Expected IDs to be unchanged without a mapping, got %d (%v)", id, err)
+	}
+}
+
+func TestGetRootUIDGID(t *testing.T) {
+	uidMap := []IDMap{{ContainerID: 0, HostID: 165536, Size: 65536}}
+	gidMap := []IDMap{{ContainerID: 0, HostID: 175536, Size: 65536}}
+
+	uid, gid, err := GetRootUIDGID(uidMap, gidMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uid != 165536 || gid != 175536 {
+		t.Fatalf("Expected root to map to 165536:175536, got %d:%d", uid, gid)
+	}
+
+	uid, gid, err = GetRootUIDGID(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uid != 0 || gid != 0 {
+		t.Fatalf("Expected root to map to 0:0 without mappings, got %d:%d", uid, gid)
+	}
+}
@@ -25,15 +25,21 @@ const (
 )
 
 var (
-	iptablesPath        string
-	supportsXlock       = false
-	ErrIptablesNotFound = errors.New("Iptables not found")
+	iptablesPath         string
+	ip6tablesPath        string
+	supportsXlock        = false
+	supportsXlock6       = false
+	ErrIptablesNotFound  = errors.New("Iptables not found")
+	ErrIp6tablesNotFound = errors.New("Ip6tables not found")
 )
 
 type Chain struct {
-	Name   string
-	Bridge string
-	Table  Table
+	Name        string
+	Bridge      string
+	Table       Table
+	HairpinMode bool
+	// IPVersion selects between iptables and ip6tables, IPv4 if empty.
+	IPVersion IPV
 }
 
 type ChainError struct {
@@ -58,11 +64,39 @@ func initCheck() error {
 	return nil
 }
 
-func NewChain(name, bridge string, table Table) (*Chain, error) {
+func initCheck6() error {
+	if ip6tablesPath == "" {
+		path, err := exec.LookPath("ip6tables")
+		if err != nil {
+			return ErrIp6tablesNotFound
+		}
+		ip6tablesPath = path
+		supportsXlock6 = exec.Command(ip6tablesPath, "--wait", "-L", "-n").Run() == nil
+	}
+	return nil
+}
+
+// NewChain creates the chain in the given table and links it from the
+// builtin chains. With hairpinMode set, traffic originating from the bridge
+// and from the host's loopback addresses is NATed as well, so that published
+// ports work without the userland proxy.
+func NewChain(name, bridge string, table Table, hairpinMode bool) (*Chain, error) {
+	return newChain(Iptables, name, bridge, table, hairpinMode)
+}
+
+// NewChain6 is the ip6tables counterpart of NewChain. Loopback traffic is
+// never NATed, as IPv6 can't route ::1 out of the host.
+func NewChain6(name, bridge string, table Table, hairpinMode bool) (*Chain, error) {
+	return newChain(Ip6tables, name, bridge, table, hairpinMode)
+}
+
+func newChain(ipv IPV, name, bridge string, table Table, hairpinMode bool) (*Chain, error) {
 	c := &Chain{
-		Name:   name,
-		Bridge: bridge,
-		Table:  table,
+		Name:        name,
+		Bridge:      bridge,
+		Table:       table,
+		HairpinMode: hairpinMode,
+		IPVersion:   ipv,
 	}
 
 	if string(c.Table) == "" {
@@ -70,8 +104,8 @@ func NewChain(name, bridge string, table Table) (*Chain, error) {
 	}
 
 	// Add chain if it doesn't exist
-	if _, err := Raw("-t", string(c.Table), "-n", "-L", c.Name); err != nil {
-		if output, err := Raw("-t", string(c.Table), "-N", c.Name); err != nil {
+	if _, err := c.raw("-t", string(c.Table), "-n", "-L", c.Name); err != nil {
+		if output, err := c.raw("-t", string(c.Table), "-N", c.Name); err != nil {
 			return nil, err
 		} else if len(output) != 0 {
 			return nil, fmt.Errorf("Could not create %s/%s chain: %s", c.Table, c.Name, output)
@@ -83,16 +117,18 @@ func NewChain(name, bridge string, table Table) (*Chain, error) {
 		preroute := []string{
 			"-m", "addrtype",
 			"--dst-type", "LOCAL"}
-		if !Exists(Nat, "PREROUTING", preroute...) {
+		if !exists(ipv, Nat, "PREROUTING", preroute...) {
 			if err := c.Prerouting(Append, preroute...); err != nil {
 				return nil, fmt.Errorf("Failed to inject docker in PREROUTING chain: %s", err)
 			}
 		}
 		output := []string{
 			"-m", "addrtype",
-			"--dst-type", "LOCAL",
-			"!", "--dst", "127.0.0.0/8"}
-		if !Exists(Nat, "OUTPUT", output...) {
+			"--dst-type", "LOCAL"}
+		if !hairpinMode || ipv == Ip6tables {
+			output = append(output, "!", "--dst", c.loopback())
+		}
+		if !exists(ipv, Nat, "OUTPUT", output...) {
 			if err := c.Output(Append, output...); err != nil {
 				return nil, fmt.Errorf("Failed to inject docker in OUTPUT chain: %s", err)
 			}
@@ -101,9 +137,9 @@ func NewChain(name, bridge string, table Table) (*Chain, error) {
 		link := []string{
 			"-o", c.Bridge,
 			"-j", c.Name}
-		if !Exists(Filter, "FORWARD", link...) {
+		if !exists(ipv, Filter, "FORWARD", link...) {
 			insert := append([]string{string(Insert), "FORWARD"}, link...)
-			if output, err := Raw(insert...); err != nil {
+			if output, err := c.raw(insert...); err != nil {
 				return nil, err
 			} else if len(output) != 0 {
 				return nil, fmt.Errorf("Could not create linking rule to %s/%s: %s", c.Table, c.Name, output)
@@ -124,6 +160,38 @@ func RemoveExistingChain(name string, table Table) error {
 	return c.Remove()
 }
 
+func RemoveExistingChain6(name string, table Table) error {
+	c := &Chain{
+		Name:      name,
+		Table:     table,
+		IPVersion: Ip6tables,
+	}
+	if string(c.Table) == "" {
+		c.Table = Filter
+	}
+	return c.Remove()
+}
+
+func (c *Chain) ipVersion() IPV {
+	if c.IPVersion == "" {
+		return Iptables
+	}
+	return c.IPVersion
+}
+
+// loopback returns the loopback network of the chain's IP version
+func (c *Chain) loopback() string {
+	if c.ipVersion() == Ip6tables {
+		return "::1/128"
+	}
+	return "127.0.0.0/8"
+}
+
+// raw runs iptables or ip6tables depending on the chain's IP version
+func (c *Chain) raw(args ...string) ([]byte, error) {
+	return raw(c.ipVersion(), args...)
+}
+
 // Add forwarding rule to 'filter' table and corresponding nat rule to 'nat' table
 func (c *Chain) Forward(action Action, ip net.IP, port int, proto, destAddr string, destPort int) error {
 	daddr := ip.String()
@@ -133,19 +201,22 @@ func (c *Chain) Forward(action Action, ip net.IP, port int, proto, destAddr stri
 		// value" by both iptables and ip6tables.
 		daddr = "0/0"
 	}
-	if output, err := Raw("-t", string(Nat), string(action), c.Name,
+	args := []string{"-t", string(Nat), string(action), c.Name,
 		"-p", proto,
 		"-d", daddr,
 		"--dport", strconv.Itoa(port),
-		"!", "-i", c.Bridge,
 		"-j", "DNAT",
-		"--to-destination", net.JoinHostPort(destAddr, strconv.Itoa(destPort))); err != nil {
+		"--to-destination", net.JoinHostPort(destAddr, strconv.Itoa(destPort))}
+	if !c.HairpinMode {
+		args = append(args, "!", "-i", c.Bridge)
+	}
+	if output, err := c.raw(args...); err != nil {
 		return err
 	} else if len(output) != 0 {
 		return ChainError{Chain: "FORWARD", Output: output}
 	}
 
-	if output, err := Raw("-t", string(Filter), string(action), c.Name,
+	if output, err := c.raw("-t", string(Filter), string(action), c.Name,
 		"!", "-i", c.Bridge,
 		"-o", c.Bridge,
 		"-p", proto,
@@ -157,7 +228,7 @@ func (c *Chain) Forward(action Action, ip net.IP, port int, proto, destAddr stri
 		return ChainError{Chain: "FORWARD", Output: output}
 	}
 
-	if output, err := Raw("-t", string(Nat), string(action), "POSTROUTING",
+	if output, err := c.raw("-t", string(Nat), string(action), "POSTROUTING",
 		"-p", proto,
 		"-s", destAddr,
 		"-d", destAddr,
@@ -174,7 +245,7 @@ func (c *Chain) Forward(action Action, ip net.IP, port int, proto, destAddr stri
 // Add reciprocal ACCEPT rule for two supplied IP addresses.
 // Traffic is allowed from ip1 to ip2 and vice-versa
 func (c *Chain) Link(action Action, ip1, ip2 net.IP, port int, proto string) error {
-	if output, err := Raw("-t", string(Filter), string(action), c.Name,
+	if output, err := c.raw("-t", string(Filter), string(action), c.Name,
 		"-i", c.Bridge, "-o", c.Bridge,
 		"-p", proto,
 		"-s", ip1.String(),
@@ -185,7 +256,7 @@ func (c *Chain) Link(action Action, ip1, ip2 net.IP, port int, proto string) err
 	} else if len(output) != 0 {
 		return fmt.Errorf("Error iptables forward: %s", output)
 	}
-	if output, err := Raw("-t", string(Filter), string(action), c.Name,
+	if output, err := c.raw("-t", string(Filter), string(action), c.Name,
 		"-i", c.Bridge, "-o", c.Bridge,
 		"-p", proto,
 		"-s", ip2.String(),
@@ -205,7 +276,7 @@ func (c *Chain) Prerouting(action Action, args ...string) error {
 	if len(args) > 0 {
 		a = append(a, args...)
 	}
-	if output, err := Raw(append(a, "-j", c.Name)...); err != nil {
+	if output, err := c.raw(append(a, "-j", c.Name)...); err != nil {
 		return err
 	} else if len(output) != 0 {
 		return ChainError{Chain: "PREROUTING", Output: output}
@@ -219,7 +290,7 @@ func (c *Chain) Output(action Action, args ...string) error {
 	if len(args) > 0 {
 		a = append(a, args...)
 	}
-	if output, err := Raw(append(a, "-j", c.Name)...); err != nil {
+	if output, err := c.raw(append(a, "-j", c.Name)...); err != nil {
 		return err
 	} else if len(output) != 0 {
 		return ChainError{Chain: "OUTPUT", Output: output}
@@ -231,19 +302,28 @@ func (c *Chain) Remove() error {
 	// Ignore errors - This could mean the chains were never set up
 	if c.Table == Nat {
 		c.Prerouting(Delete, "-m", "addrtype", "--dst-type", "LOCAL")
-		c.Output(Delete, "-m", "addrtype", "--dst-type", "LOCAL", "!", "--dst", "127.0.0.0/8")
-		c.Output(Delete, "-m", "addrtype", "--dst-type", "LOCAL") // Created in versions <= 0.1.6
+		c.Output(Delete, "-m", "addrtype", "--dst-type", "LOCAL", "!", "--dst", c.loopback())
+		c.Output(Delete, "-m", "addrtype", "--dst-type", "LOCAL") // Created in versions <= 0.1.6 and in hairpin mode
 
 		c.Prerouting(Delete)
 		c.Output(Delete)
 	}
-	Raw("-t", string(c.Table), "-F", c.Name)
-	Raw("-t", string(c.Table), "-X", c.Name)
+	c.raw("-t", string(c.Table), "-F", c.Name)
+	c.raw("-t", string(c.Table), "-X", c.Name)
 	return nil
 }
 
 // Check if a rule exists
 func Exists(table Table, chain string, rule ...string) bool {
+	return exists(Iptables, table, chain, rule...)
+}
+
+// Check if an ip6tables rule exists
+func Exists6(table Table, chain string, rule ...string) bool {
+	return exists(Ip6tables, table, chain, rule...)
+}
+
+func exists(ipv IPV, table Table, chain string, rule ...string) bool {
 	if string(table) == "" {
 		table = Filter
 	}
@@ -253,7 +333,7 @@ func Exists(table Table, chain string, rule ...string) bool {
 
 	// try -C
 	// if exit status is 0 then return true, the rule exists
-	if _, err := Raw(append([]string{
+	if _, err := raw(ipv, append([]string{
 		"-t", string(table), "-C", chain}, rule...)...); err == nil {
 		return true
 	}
@@ -261,7 +341,7 @@ func Exists(table Table, chain string, rule ...string) bool {
 	// parse "iptables -S" for the rule (this checks rules in a specific chain
 	// in a specific table)
 	ruleString := strings.Join(rule, " ")
-	existingRules, _ := exec.Command("iptables", "-t", string(table), "-S", chain).Output()
+	existingRules, _ := exec.Command(command(ipv), "-t", string(table), "-S", chain).Output()
 
 	// regex to replace ips in rule
 	// because MASQUERADE rule will not be exactly what was passed
@@ -273,28 +353,50 @@ func Exists(table Table, chain string, rule ...string) bool {
 	)
 }
 
+func command(ipv IPV) string {
+	if ipv == Ip6tables {
+		return "ip6tables"
+	}
+	return "iptables"
+}
+
 // Call 'iptables' system command, passing supplied arguments
 func Raw(args ...string) ([]byte, error) {
+	return raw(Iptables, args...)
+}
+
+// Call 'ip6tables' system command, passing supplied arguments
+func Raw6(args ...string) ([]byte, error) {
+	return raw(Ip6tables, args...)
+}
+
+func raw(ipv IPV, args ...string) ([]byte, error) {
 	if firewalldRunning {
-		output, err := Passthrough(Iptables, args...)
+		output, err := Passthrough(ipv, args...)
 		if err == nil || !strings.Contains(err.Error(), "was not provided by any .service files") {
 			return output, err
 		}
 
 	}
 
-	if err := initCheck(); err != nil {
+	path, xlock := iptablesPath, supportsXlock
+	if ipv == Ip6tables {
+		if err := initCheck6(); err != nil {
+			return nil, err
+		}
+		path, xlock = ip6tablesPath, supportsXlock6
+	} else if err := initCheck(); err != nil {
 		return nil, err
 	}
-	if supportsXlock {
+	if xlock {
 		args = append([]string{"--wait"}, args...)
 	}
 
-	logrus.Debugf("%s, %v", iptablesPath, args)
+	logrus.Debugf("%s, %v", path, args)
 
-	output, err := exec.Command(iptablesPath, args...).CombinedOutput()
+	output, err := exec.Command(path, args...).CombinedOutput()
 	if err != nil {
-		return nil, fmt.Errorf("iptables failed: iptables %v: %s (%s)", strings.Join(args, " "), output, err)
+		return nil, fmt.Errorf("%s failed: %s %v: %s (%s)", command(ipv), command(ipv), strings.Join(args, " "), output, err)
 	}
 
 	// ignore iptables' message about xtables lock
@@ -16,12 +16,12 @@ var filterChain *Chain
 func TestNewChain(t *testing.T) {
 	var err error
 
-	natChain, err = NewChain(chainName, "lo", Nat)
+	natChain, err = NewChain(chainName, "lo", Nat, false)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	filterChain, err = NewChain(chainName, "lo", Filter)
+	filterChain, err = NewChain(chainName, "lo", Filter, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -78,6 +78,35 @@ func TestForward(t *testing.T) {
 	}
 }
 
+func TestForwardHairpin(t *testing.T) {
+	ip := net.ParseIP("192.168.1.2")
+	port := 1235
+	dstAddr := "172.17.0.2"
+	dstPort := 4322
+	proto := "tcp"
+
+	hairpinChain := &Chain{Name: natChain.Name, Bridge: natChain.Bridge, Table: Nat, HairpinMode: true}
+	if err := hairpinChain.Forward(Insert, ip, port, proto, dstAddr, dstPort); err != nil {
+		t.Fatal(err)
+	}
+
+	dnatRule := []string{
+		"-d", ip.String(),
+		"-p", proto,
+		"--dport", strconv.Itoa(port),
+		"-j", "DNAT",
+		"--to-destination", dstAddr + ":" + strconv.Itoa(dstPort),
+	}
+
+	if !Exists(natChain.Table, natChain.Name, dnatRule...) {
+		t.Fatalf("DNAT rule does not exist")
+	}
+
+	if err := hairpinChain.Forward(Delete, ip, port, proto, dstAddr, dstPort); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestLink(t *testing.T) {
 	var err error
 
@@ -169,6 +198,60 @@ func TestOutput(t *testing.T) {
 	}
 }
 
+func TestChainLoopback(t *testing.T) {
+	if l := (&Chain{}).loopback(); l != "127.0.0.0/8" {
+		t.Fatalf("Unexpected IPv4 loopback %s", l)
+	}
+	if l := (&Chain{IPVersion: Ip6tables}).loopback(); l != "::1/128" {
+		t.Fatalf("Unexpected IPv6 loopback %s", l)
+	}
+}
+
+func TestForward6(t *testing.T) {
+	if _, err := exec.LookPath("ip6tables"); err != nil {
+		t.Skip("ip6tables is not available")
+	}
+	natChain6, err := NewChain6(chainName, "lo", Nat, false)
+	if err != nil {
+		// the ip6tables nat table needs Linux 3.7
+		t.Skipf("Unable to create an ip6tables nat chain: %v", err)
+	}
+	defer RemoveExistingChain6(chainName, Nat)
+	filterChain6, err := NewChain6(chainName, "lo", Filter, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		Raw6("-t", string(Filter), string(Delete), "FORWARD", "-o", "lo", "-j", chainName)
+		filterChain6.Remove()
+	}()
+
+	ip := net.ParseIP("2001:db8::1")
+	dstAddr := "2001:db8:1::2"
+	if err := natChain6.Forward(Insert, ip, 1234, "tcp", dstAddr, 4321); err != nil {
+		t.Fatal(err)
+	}
+
+	dnatRule := []string{
+		"!", "-i", "lo",
+		"-d", ip.String() + "/128",
+		"-p", "tcp",
+		"--dport", "1234",
+		"-j", "DNAT",
+		"--to-destination", "[" + dstAddr + "]:4321",
+	}
+	if !Exists6(Nat, chainName, dnatRule...) {
+		t.Fatalf("DNAT rule does not exist")
+	}
+	if Exists(Nat, chainName, dnatRule...) {
+		t.Fatalf("DNAT rule added to iptables")
+	}
+
+	if err := natChain6.Forward(Delete, ip, 1234, "tcp", dstAddr, 4321); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestCleanup(t *testing.T) {
 	var err error
 	var rules []byte
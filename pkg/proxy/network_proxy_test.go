@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -138,6 +139,49 @@ func TestTCP6Proxy(t *testing.T) {
 	testProxy(t, "tcp", proxy)
 }
 
+func TestTCP6ProxyToIPv4Backend(t *testing.T) {
+	backend := NewEchoServer(t, "tcp", "127.0.0.1:0")
+	defer backend.Close()
+	backend.Run()
+	frontendAddr := &net.TCPAddr{IP: net.IPv6loopback, Port: 0}
+	proxy, err := NewProxy(frontendAddr, backend.LocalAddr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testProxy(t, "tcp", proxy)
+}
+
+func TestTCPProxiesPerAddressFamily(t *testing.T) {
+	backend := NewEchoServer(t, "tcp", "127.0.0.1:0")
+	defer backend.Close()
+	backend.Run()
+	proxy4, err := NewProxy(&net.TCPAddr{IP: net.IPv4zero, Port: 0}, backend.LocalAddr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy4.Close()
+	// the IPv4 wildcard must leave the same IPv6 port free
+	port := proxy4.FrontendAddr().(*net.TCPAddr).Port
+	proxy6, err := NewProxy(&net.TCPAddr{IP: net.IPv6unspecified, Port: port}, backend.LocalAddr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testProxyAt(t, "tcp", proxy6, net.JoinHostPort("::1", strconv.Itoa(port)))
+}
+
+func TestListenNetwork(t *testing.T) {
+	for ip, network := range map[string]string{
+		"0.0.0.0":     "tcp4",
+		"127.0.0.1":   "tcp4",
+		"::":          "tcp6",
+		"2001:db8::1": "tcp6",
+	} {
+		if n := ListenNetwork("tcp", net.ParseIP(ip)); n != network {
+			t.Fatalf("Expected %s for %s, got %s", network, ip, n)
+		}
+	}
+}
+
 func TestTCPDualStackProxy(t *testing.T) {
 	// If I understand `godoc -src net favoriteAddrFamily` (used by the
 	// net.Listen* functions) correctly this should work, but it doesn't.
@@ -17,6 +17,16 @@ type Proxy interface {
 	BackendAddr() net.Addr
 }
 
+// ListenNetwork returns the network to listen on ip with, for proto "tcp" or
+// "udp". Listening on an unspecified address only covers the address family
+// of ip, so that IPv4 and IPv6 ports can be bound separately.
+func ListenNetwork(proto string, ip net.IP) string {
+	if ip.To4() != nil {
+		return proto + "4"
+	}
+	return proto + "6"
+}
+
 func NewProxy(frontendAddr, backendAddr net.Addr) (Proxy, error) {
 	switch frontendAddr.(type) {
 	case *net.UDPAddr:
@@ -50,7 +50,7 @@ type UDPProxy struct {
 }
 
 func NewUDPProxy(frontendAddr, backendAddr *net.UDPAddr) (*UDPProxy, error) {
-	listener, err := net.ListenUDP("udp", frontendAddr)
+	listener, err := net.ListenUDP(ListenNetwork("udp", frontendAddr.IP), frontendAddr)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,121 @@
+// +build linux
+
+// Package reaper implements a minimal init process for containers. It runs
+// a command as its only child, forwards the signals it receives to the
+// command, reaps the orphaned processes that get reparented to it and exits
+// with the status of the command.
+package reaper
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/docker/docker/pkg/reexec"
+	"github.com/docker/docker/pkg/term"
+)
+
+// Path is where the init binary is mounted in containers. The command to
+// run follows it on the command line, after a "--" argument.
+const Path = "/dev/init"
+
+func init() {
+	reexec.Register(Path, initializer)
+}
+
+func initializer() {
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "init: no command to run")
+		os.Exit(1)
+	}
+	os.Exit(Run(args))
+}
+
+// forwardedSignals are the signals passed on to the command. The signals
+// the Go runtime uses itself, like SIGURG, and the ones about the terminal
+// or the init process itself, like SIGCHLD and SIGPIPE, are left out.
+var forwardedSignals = []os.Signal{
+	syscall.SIGHUP,
+	syscall.SIGINT,
+	syscall.SIGQUIT,
+	syscall.SIGABRT,
+	syscall.SIGUSR1,
+	syscall.SIGUSR2,
+	syscall.SIGALRM,
+	syscall.SIGTERM,
+	syscall.SIGCONT,
+	syscall.SIGTSTP,
+	syscall.SIGPWR,
+}
+
+// Run starts the command args and waits for it to exit, forwarding signals
+// to it and reaping any other child process in the meantime. It returns the
+// exit status of the command, or 128 plus the signal number if the command
+// was killed by a signal.
+func Run(args []string) int {
+	signals := make(chan os.Signal, 64)
+	signal.Notify(signals, append(forwardedSignals, syscall.SIGCHLD)...)
+	defer signal.Stop(signals)
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// The command gets a process group of its own so that signals reach
+	// the processes it starts too, unless it must stay in the foreground
+	// process group of the terminal to read from it.
+	ownGroup := !term.IsTerminal(os.Stdin.Fd())
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: ownGroup}
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "init: %v\n", err)
+		return 127
+	}
+
+	pid := cmd.Process.Pid
+	target := pid
+	if ownGroup {
+		target = -pid
+	}
+	for sig := range signals {
+		s, ok := sig.(syscall.Signal)
+		if !ok {
+			continue
+		}
+		if s != syscall.SIGCHLD {
+			syscall.Kill(target, s)
+			continue
+		}
+		if status, exited := reap(pid); exited {
+			return status
+		}
+	}
+	return 1
+}
+
+// reap waits for all the children that exited and reports the exit status
+// of pid if it is one of them.
+func reap(pid int) (int, bool) {
+	for {
+		var ws syscall.WaitStatus
+		child, err := syscall.Wait4(-1, &ws, syscall.WNOHANG, nil)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil || child <= 0 {
+			return 0, false
+		}
+		if child != pid {
+			continue
+		}
+		if ws.Signaled() {
+			return 128 + int(ws.Signal()), true
+		}
+		return ws.ExitStatus(), true
+	}
+}
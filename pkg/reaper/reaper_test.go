@@ -0,0 +1,42 @@
+// +build linux
+
+package reaper
+
+import "testing"
+
+func TestRunExitStatus(t *testing.T) {
+	if status := Run([]string{"sh", "-c", "exit 3"}); status != 3 {
+		t.Fatalf("Expected exit status 3, got %d", status)
+	}
+}
+
+func TestRunKilledBySignal(t *testing.T) {
+	if status := Run([]string{"sh", "-c", "kill -TERM $$"}); status != 143 {
+		t.Fatalf("Expected exit status 143, got %d", status)
+	}
+}
+
+func TestRunReturnsWhenCommandExits(t *testing.T) {
+	// the background sleep outlives the shell
+	if status := Run([]string{"sh", "-c", "sleep 5 >/dev/null 2>&1 & exit 0"}); status != 0 {
+		t.Fatalf("Expected exit status 0, got %d", status)
+	}
+}
+
+func TestRunForwardsSignals(t *testing.T) {
+	if status := Run([]string{"sh", "-c", "trap 'exit 7' USR1; kill -USR1 $PPID; while true; do sleep 0.1; done"}); status != 7 {
+		t.Fatalf("Expected exit status 7, got %d", status)
+	}
+}
+
+func TestRunMissingCommand(t *testing.T) {
+	if status := Run([]string{"/nonexistent"}); status != 127 {
+		t.Fatalf("Expected exit status 127, got %d", status)
+	}
+}
+
+func TestRunDoesNotForwardTerminalSignals(t *testing.T) {
+	if status := Run([]string{"sh", "-c", "trap 'exit 7' WINCH; kill -WINCH $PPID; sleep 0.5; exit 0"}); status != 0 {
+		t.Fatalf("Expected exit status 0, got %d", status)
+	}
+}
@@ -0,0 +1,31 @@
+// +build linux
+
+package signal
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestParseSignal(t *testing.T) {
+	for raw, expected := range map[string]syscall.Signal{
+		"15":      syscall.SIGTERM,
+		"QUIT":    syscall.SIGQUIT,
+		"SIGINT":  syscall.SIGINT,
+		"sigusr1": syscall.SIGUSR1,
+	} {
+		sig, err := ParseSignal(raw)
+		if err != nil {
+			t.Fatalf("Unable to parse %q: %v", raw, err)
+		}
+		if sig != expected {
+			t.Fatalf("Expected %q to be %v, got %v", raw, expected, sig)
+		}
+	}
+
+	for _, raw := range []string{"", "0", "-1", "65", "99999", "SIGNOPE", "SIG"} {
+		if _, err := ParseSignal(raw); err == nil {
+			t.Fatalf("Expected an error parsing %q", raw)
+		}
+	}
+}
@@ -13,7 +13,17 @@ import (
 type SysInfo struct {
 	MemoryLimit            bool
 	SwapLimit              bool
+	KernelMemory           bool
+	MemorySwappiness       bool
+	OomKillDisable         bool
 	CpuCfsQuota            bool
+	BlkioWeight            bool
+	BlkioWeightDevice      bool
+	BlkioReadBpsDevice     bool
+	BlkioWriteBpsDevice    bool
+	BlkioReadIOpsDevice    bool
+	BlkioWriteIOpsDevice   bool
+	PidsLimit              bool
 	IPv4ForwardingDisabled bool
 	AppArmor               bool
 }
@@ -38,6 +48,24 @@ func New(quiet bool) *SysInfo {
 		if !sysInfo.SwapLimit && !quiet {
 			logrus.Warn("Your kernel does not support cgroup swap limit.")
 		}
+
+		_, err = ioutil.ReadFile(path.Join(cgroupMemoryMountpoint, "memory.kmem.limit_in_bytes"))
+		sysInfo.KernelMemory = err == nil
+		if !sysInfo.KernelMemory && !quiet {
+			logrus.Warn("Your kernel does not support cgroup kernel memory limit.")
+		}
+
+		_, err = ioutil.ReadFile(path.Join(cgroupMemoryMountpoint, "memory.swappiness"))
+		sysInfo.MemorySwappiness = err == nil
+		if !sysInfo.MemorySwappiness && !quiet {
+			logrus.Warn("Your kernel does not support cgroup memory swappiness.")
+		}
+
+		_, err = ioutil.ReadFile(path.Join(cgroupMemoryMountpoint, "memory.oom_control"))
+		sysInfo.OomKillDisable = err == nil
+		if !sysInfo.OomKillDisable && !quiet {
+			logrus.Warn("Your kernel does not support disabling the OOM killer of a cgroup.")
+		}
 	}
 
 	if cgroupCpuMountpoint, err := cgroups.FindCgroupMountpoint("cpu"); err != nil {
@@ -52,6 +80,54 @@ func New(quiet bool) *SysInfo {
 		}
 	}
 
+	if cgroupBlkioMountpoint, err := cgroups.FindCgroupMountpoint("blkio"); err != nil {
+		if !quiet {
+			logrus.Warnf("%v", err)
+		}
+	} else {
+		_, err = ioutil.ReadFile(path.Join(cgroupBlkioMountpoint, "blkio.weight"))
+		sysInfo.BlkioWeight = err == nil
+		if !sysInfo.BlkioWeight && !quiet {
+			logrus.Warn("Your kernel does not support cgroup blkio weight")
+		}
+
+		_, err = ioutil.ReadFile(path.Join(cgroupBlkioMountpoint, "blkio.weight_device"))
+		sysInfo.BlkioWeightDevice = err == nil
+		if !sysInfo.BlkioWeightDevice && !quiet {
+			logrus.Warn("Your kernel does not support cgroup blkio weight_device")
+		}
+
+		_, err = ioutil.ReadFile(path.Join(cgroupBlkioMountpoint, "blkio.throttle.read_bps_device"))
+		sysInfo.BlkioReadBpsDevice = err == nil
+		if !sysInfo.BlkioReadBpsDevice && !quiet {
+			logrus.Warn("Your kernel does not support cgroup blkio throttle.read_bps_device")
+		}
+
+		_, err = ioutil.ReadFile(path.Join(cgroupBlkioMountpoint, "blkio.throttle.write_bps_device"))
+		sysInfo.BlkioWriteBpsDevice = err == nil
+		if !sysInfo.BlkioWriteBpsDevice && !quiet {
+			logrus.Warn("Your kernel does not support cgroup blkio throttle.write_bps_device")
+		}
+
+		_, err = ioutil.ReadFile(path.Join(cgroupBlkioMountpoint, "blkio.throttle.read_iops_device"))
+		sysInfo.BlkioReadIOpsDevice = err == nil
+		if !sysInfo.BlkioReadIOpsDevice && !quiet {
+			logrus.Warn("Your kernel does not support cgroup blkio throttle.read_iops_device")
+		}
+
+		_, err = ioutil.ReadFile(path.Join(cgroupBlkioMountpoint, "blkio.throttle.write_iops_device"))
+		sysInfo.BlkioWriteIOpsDevice = err == nil
+		if !sysInfo.BlkioWriteIOpsDevice && !quiet {
+			logrus.Warn("Your kernel does not support cgroup blkio throttle.write_iops_device")
+		}
+	}
+
+	_, err := cgroups.FindCgroupMountpoint("pids")
+	sysInfo.PidsLimit = err == nil
+	if !sysInfo.PidsLimit && !quiet {
+		logrus.Warn("Your kernel does not support pids limit capabilities or the cgroup is not mounted.")
+	}
+
 	// Check if AppArmor is supported.
 	if _, err := os.Stat("/sys/kernel/security/apparmor"); os.IsNotExist(err) {
 		sysInfo.AppArmor = false
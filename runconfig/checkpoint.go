@@ -0,0 +1,46 @@
+package runconfig
+
+import (
+	flag "github.com/docker/docker/pkg/mflag"
+)
+
+// CheckpointConfig holds the options to checkpoint a container with CRIU and
+// to restore it. A container has to be restored with the options it was
+// checkpointed with.
+type CheckpointConfig struct {
+	LeaveRunning            bool // Keep the container running after the checkpoint
+	TcpEstablished          bool // Checkpoint and restore established TCP connections
+	ExternalUnixConnections bool // Allow unix sockets connected outside of the container
+	ShellJob                bool // Allow processes that are part of a shell job
+}
+
+func ParseCheckpoint(cmd *flag.FlagSet, args []string) (*CheckpointConfig, error) {
+	flLeaveRunning := cmd.Bool([]string{"-leave-running"}, false, "Leave the container running after the checkpoint")
+	return parseCheckpointConfig(cmd, args, flLeaveRunning)
+}
+
+func ParseRestore(cmd *flag.FlagSet, args []string) (*CheckpointConfig, error) {
+	return parseCheckpointConfig(cmd, args, nil)
+}
+
+func parseCheckpointConfig(cmd *flag.FlagSet, args []string, flLeaveRunning *bool) (*CheckpointConfig, error) {
+	var (
+		flTcpEstablished = cmd.Bool([]string{"-allow-tcp"}, false, "Allow established TCP connections")
+		flExtUnix        = cmd.Bool([]string{"-allow-ext-unix"}, false, "Allow unix sockets connected outside of the container")
+		flShellJob       = cmd.Bool([]string{"-allow-shell"}, false, "Allow processes that are part of a shell job")
+	)
+	cmd.Require(flag.Min, 1)
+	if err := cmd.ParseFlags(args, true); err != nil {
+		return nil, err
+	}
+
+	config := &CheckpointConfig{
+		TcpEstablished:          *flTcpEstablished,
+		ExternalUnixConnections: *flExtUnix,
+		ShellJob:                *flShellJob,
+	}
+	if flLeaveRunning != nil {
+		config.LeaveRunning = *flLeaveRunning
+	}
+	return config, nil
+}
@@ -0,0 +1,41 @@
+package runconfig
+
+import (
+	"io/ioutil"
+	"testing"
+
+	flag "github.com/docker/docker/pkg/mflag"
+)
+
+func newFlagSet(name string) *flag.FlagSet {
+	cmd := flag.NewFlagSet(name, flag.ContinueOnError)
+	cmd.SetOutput(ioutil.Discard)
+	cmd.Usage = nil
+	return cmd
+}
+
+func TestParseCheckpoint(t *testing.T) {
+	config, err := ParseCheckpoint(newFlagSet("checkpoint"), []string{"--leave-running", "--allow-tcp", "container"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := CheckpointConfig{LeaveRunning: true, TcpEstablished: true}
+	if *config != expected {
+		t.Fatalf("Expected %+v, got %+v", expected, config)
+	}
+}
+
+func TestParseRestore(t *testing.T) {
+	config, err := ParseRestore(newFlagSet("restore"), []string{"--allow-ext-unix", "--allow-shell", "container"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := CheckpointConfig{ExternalUnixConnections: true, ShellJob: true}
+	if *config != expected {
+		t.Fatalf("Expected %+v, got %+v", expected, config)
+	}
+
+	if _, err := ParseRestore(newFlagSet("restore"), []string{"--leave-running", "container"}); err == nil {
+		t.Fatal("Expected --leave-running to be rejected by restore")
+	}
+}
@@ -3,6 +3,7 @@ package runconfig
 import (
 	"encoding/json"
 	"io"
+	"time"
 
 	"github.com/docker/docker/nat"
 )
@@ -98,6 +99,20 @@ func NewCommand(parts ...string) *Command {
 	return &Command{parts}
 }
 
+// HealthConfig holds the configuration of the probe run to check that the
+// container is healthy.
+type HealthConfig struct {
+	// Test is the probe to run. It is either {} to inherit the probe of
+	// the image, {"NONE"} to disable it, {"CMD", args...} to exec args
+	// directly or {"CMD-SHELL", command} to run command with /bin/sh -c.
+	Test []string `json:",omitempty"`
+
+	// Zero means to inherit the value of the image, or use the default.
+	Interval time.Duration `json:",omitempty"` // Time to wait between probes
+	Timeout  time.Duration `json:",omitempty"` // Time after which a probe is considered hung
+	Retries  int           `json:",omitempty"` // Consecutive failures needed to be unhealthy
+}
+
 // Note: the Config structure should hold only portable information about the container.
 // Here, "portable" means "independent from the host we are running on".
 // Non-portable information *should* appear in HostConfig.
@@ -123,6 +138,9 @@ type Config struct {
 	MacAddress      string
 	OnBuild         []string
 	Labels          map[string]string
+	Healthcheck     *HealthConfig `json:",omitempty"`
+	StopSignal      string        `json:",omitempty"` // Signal sent to stop the container, SIGTERM if empty
+	StopTimeout     *int          `json:",omitempty"` // Seconds to wait for the container to stop before killing it, nil for the default
 }
 
 type ContainerConfigWrapper struct {
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/docker/docker/nat"
 )
@@ -192,6 +193,71 @@ func TestCompare(t *testing.T) {
 	if !Compare(&config1, &config1) {
 		t.Fatalf("Compare should return true")
 	}
+	config6 := config1
+	config6.Healthcheck = &HealthConfig{Test: []string{"CMD-SHELL", "true"}}
+	if Compare(&config1, &config6) {
+		t.Fatalf("Compare should return false, Healthchecks are different")
+	}
+	config7 := config6
+	config7.Healthcheck = &HealthConfig{Test: []string{"CMD-SHELL", "true"}, Retries: 1}
+	if Compare(&config6, &config7) {
+		t.Fatalf("Compare should return false, Healthcheck retries are different")
+	}
+}
+
+func TestMergeHealthcheck(t *testing.T) {
+	configImage := &Config{
+		Healthcheck: &HealthConfig{
+			Test:     []string{"CMD", "check"},
+			Interval: time.Minute,
+			Retries:  5,
+		},
+	}
+
+	configUser := &Config{}
+	if err := Merge(configUser, configImage); err != nil {
+		t.Fatal(err)
+	}
+	if configUser.Healthcheck == nil || configUser.Healthcheck.Test[1] != "check" {
+		t.Fatalf("Expected the health check of the image, got %+v", configUser.Healthcheck)
+	}
+
+	configUser = &Config{Healthcheck: &HealthConfig{Interval: time.Second}}
+	if err := Merge(configUser, configImage); err != nil {
+		t.Fatal(err)
+	}
+	health := configUser.Healthcheck
+	if len(health.Test) != 2 || health.Interval != time.Second || health.Retries != 5 {
+		t.Fatalf("Expected the interval to override the health check of the image, got %+v", health)
+	}
+
+	configUser = &Config{Healthcheck: &HealthConfig{Test: []string{"NONE"}}}
+	if err := Merge(configUser, configImage); err != nil {
+		t.Fatal(err)
+	}
+	if health := configUser.Healthcheck; len(health.Test) != 1 || health.Test[0] != "NONE" {
+		t.Fatalf("Expected the health check to stay disabled, got %+v", health)
+	}
+}
+
+func TestMergeStopSignal(t *testing.T) {
+	configImage := &Config{StopSignal: "SIGQUIT"}
+
+	configUser := &Config{}
+	if err := Merge(configUser, configImage); err != nil {
+		t.Fatal(err)
+	}
+	if configUser.StopSignal != "SIGQUIT" {
+		t.Fatalf("Expected the stop signal of the image, got %q", configUser.StopSignal)
+	}
+
+	configUser = &Config{StopSignal: "SIGINT"}
+	if err := Merge(configUser, configImage); err != nil {
+		t.Fatal(err)
+	}
+	if configUser.StopSignal != "SIGINT" {
+		t.Fatalf("Expected the stop signal of the user, got %q", configUser.StopSignal)
+	}
 }
 
 func TestMerge(t *testing.T) {
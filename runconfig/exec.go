@@ -1,6 +1,9 @@
 package runconfig
 
 import (
+	"path"
+
+	"github.com/docker/docker/opts"
 	flag "github.com/docker/docker/pkg/mflag"
 )
 
@@ -14,6 +17,8 @@ type ExecConfig struct {
 	AttachStdout bool
 	Detach       bool
 	Cmd          []string
+	Env          []string // Variables added to the environment of the container
+	WorkingDir   string   // Working directory, the container's if empty
 }
 
 func ParseExec(cmd *flag.FlagSet, args []string) (*ExecConfig, error) {
@@ -23,13 +28,19 @@ func ParseExec(cmd *flag.FlagSet, args []string) (*ExecConfig, error) {
 		flDetach     = cmd.Bool([]string{"d", "-detach"}, false, "Detached mode: run command in the background")
 		flUser       = cmd.String([]string{"u", "-user"}, "", "Username or UID (format: <name|uid>[:<group|gid>])")
 		flPrivileged = cmd.Bool([]string{"-privileged"}, false, "Give extended privileges to the command")
+		flWorkingDir = cmd.String([]string{"w", "-workdir"}, "", "Working directory inside the container")
+		flEnv        = opts.NewListOpts(opts.ValidateEnv)
 		execCmd      []string
 		container    string
 	)
+	cmd.Var(&flEnv, []string{"e", "-env"}, "Set environment variables")
 	cmd.Require(flag.Min, 2)
 	if err := cmd.ParseFlags(args, true); err != nil {
 		return nil, err
 	}
+	if *flWorkingDir != "" && !path.IsAbs(*flWorkingDir) {
+		return nil, ErrInvalidWorkingDirectory
+	}
 	container = cmd.Arg(0)
 	parsedArgs := cmd.Args()
 	execCmd = parsedArgs[1:]
@@ -41,6 +52,8 @@ func ParseExec(cmd *flag.FlagSet, args []string) (*ExecConfig, error) {
 		Cmd:        execCmd,
 		Container:  container,
 		Detach:     *flDetach,
+		Env:        flEnv.GetAll(),
+		WorkingDir: *flWorkingDir,
 	}
 
 	// If -d is not set, attach to everything by default
@@ -0,0 +1,40 @@
+package runconfig
+
+import (
+	"io/ioutil"
+	"testing"
+
+	flag "github.com/docker/docker/pkg/mflag"
+)
+
+func parseExec(args []string) (*ExecConfig, error) {
+	cmd := flag.NewFlagSet("exec", flag.ContinueOnError)
+	cmd.SetOutput(ioutil.Discard)
+	cmd.Usage = nil
+	return ParseExec(cmd, args)
+}
+
+func TestParseExec(t *testing.T) {
+	config, err := parseExec([]string{"-e", "FOO=bar", "--env=BAZ=qux", "-w", "/tmp", "container", "ls", "-l"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Container != "container" || len(config.Cmd) != 2 || config.Cmd[0] != "ls" {
+		t.Fatalf("Unexpected container and command %+v", config)
+	}
+	if len(config.Env) != 2 || config.Env[0] != "FOO=bar" || config.Env[1] != "BAZ=qux" {
+		t.Fatalf("Unexpected environment %v", config.Env)
+	}
+	if config.WorkingDir != "/tmp" {
+		t.Fatalf("Unexpected working directory %q", config.WorkingDir)
+	}
+	if !config.AttachStdout || !config.AttachStderr || config.AttachStdin {
+		t.Fatalf("Unexpected attach settings %+v", config)
+	}
+}
+
+func TestParseExecRelativeWorkingDir(t *testing.T) {
+	if _, err := parseExec([]string{"-w", "tmp", "container", "ls"}); err != ErrInvalidWorkingDirectory {
+		t.Fatalf("Expected %v, got %v", ErrInvalidWorkingDirectory, err)
+	}
+}
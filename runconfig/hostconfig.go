@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/docker/docker/nat"
 	"github.com/docker/docker/pkg/ulimit"
@@ -34,6 +35,30 @@ func (n NetworkMode) IsNone() bool {
 	return n == "none"
 }
 
+func (n NetworkMode) IsMacvlan() bool {
+	return n == "macvlan"
+}
+
+func (n NetworkMode) IsOverlay() bool {
+	parts := strings.SplitN(string(n), ":", 2)
+	return len(parts) > 1 && parts[0] == "overlay"
+}
+
+// OverlayNetwork returns the name of the overlay network of an overlay mode
+func (n NetworkMode) OverlayNetwork() string {
+	if !n.IsOverlay() {
+		return ""
+	}
+	return strings.SplitN(string(n), ":", 2)[1]
+}
+
+// NetworkRate is the bandwidth allowed in each direction of the container's
+// network interface, in bytes per second. 0 means unlimited.
+type NetworkRate struct {
+	Ingress int64 // Traffic received by the container
+	Egress  int64 // Traffic sent by the container
+}
+
 type IpcMode string
 
 // IsPrivate indicates whether container use it's private ipc stack
@@ -99,9 +124,25 @@ type DeviceMapping struct {
 	CgroupPermissions string
 }
 
+// WeightDevice is the block IO weight of a device on the host.
+type WeightDevice struct {
+	Path   string
+	Weight uint16
+}
+
+// ThrottleDevice is a block IO limit of a device on the host, in bytes or
+// IO operations per second.
+type ThrottleDevice struct {
+	Path string
+	Rate uint64
+}
+
 type RestartPolicy struct {
 	Name              string
 	MaximumRetryCount int
+	Delay             time.Duration `json:",omitempty"` // Delay before a restart, doubled after each quick exit
+	MaxDelay          time.Duration `json:",omitempty"` // Maximum delay between restarts
+	ResetWindow       time.Duration `json:",omitempty"` // Running time after which the delay is reset
 }
 
 type LogConfig struct {
@@ -159,35 +200,53 @@ func NewLxcConfig(values []KeyValuePair) *LxcConfig {
 }
 
 type HostConfig struct {
-	Binds           []string
-	ContainerIDFile string
-	LxcConf         *LxcConfig
-	Memory          int64  // Memory limit (in bytes)
-	MemorySwap      int64  // Total memory usage (memory + swap); set `-1` to disable swap
-	CpuShares       int64  // CPU shares (relative weight vs. other containers)
-	CpusetCpus      string // CpusetCpus 0-2, 0,1
-	CpusetMems      string // CpusetMems 0-2, 0,1
-	CpuQuota        int64
-	Privileged      bool
-	PortBindings    nat.PortMap
-	Links           []string
-	PublishAllPorts bool
-	Dns             []string
-	DnsSearch       []string
-	ExtraHosts      []string
-	VolumesFrom     []string
-	Devices         []DeviceMapping
-	NetworkMode     NetworkMode
-	IpcMode         IpcMode
-	PidMode         PidMode
-	CapAdd          []string
-	CapDrop         []string
-	RestartPolicy   RestartPolicy
-	SecurityOpt     []string
-	ReadonlyRootfs  bool
-	Ulimits         []*ulimit.Ulimit
-	LogConfig       LogConfig
-	CgroupParent    string // Parent cgroup.
+	Binds                []string
+	ContainerIDFile      string
+	LxcConf              *LxcConfig
+	Memory               int64  // Memory limit (in bytes)
+	MemorySwap           int64  // Total memory usage (memory + swap); set `-1` to disable swap
+	MemoryReservation    int64  // Memory soft limit (in bytes)
+	KernelMemory         int64  // Kernel memory limit (in bytes)
+	MemorySwappiness     *int64 // Tuning container memory swappiness behaviour, nil for the kernel's default
+	OomKillDisable       bool   // Whether to disable OOM Killer or not
+	OomScoreAdj          int    // Container preference for OOM-killing
+	CpuShares            int64  // CPU shares (relative weight vs. other containers)
+	CpusetCpus           string // CpusetCpus 0-2, 0,1
+	CpusetMems           string // CpusetMems 0-2, 0,1
+	CpuQuota             int64
+	PidsLimit            int64             // Process limit (set -1 for unlimited)
+	BlkioWeight          uint16            // Block IO weight (relative weight vs. other containers)
+	BlkioWeightDevice    []*WeightDevice   // Block IO weight (relative device weight)
+	BlkioDeviceReadBps   []*ThrottleDevice // Limit read rate (bytes per second) from a device
+	BlkioDeviceWriteBps  []*ThrottleDevice // Limit write rate (bytes per second) to a device
+	BlkioDeviceReadIOps  []*ThrottleDevice // Limit read rate (IO per second) from a device
+	BlkioDeviceWriteIOps []*ThrottleDevice // Limit write rate (IO per second) to a device
+	Privileged           bool
+	PortBindings         nat.PortMap
+	Links                []string
+	PublishAllPorts      bool
+	Dns                  []string
+	DnsSearch            []string
+	ExtraHosts           []string
+	VolumesFrom          []string
+	Devices              []DeviceMapping
+	NetworkMode          NetworkMode
+	NetworkRate          NetworkRate
+	IpcMode              IpcMode
+	PidMode              PidMode
+	CapAdd               []string
+	CapDrop              []string
+	RestartPolicy        RestartPolicy
+	AutoRemove           bool // Remove the container when it exits
+	SecurityOpt          []string
+	ReadonlyRootfs       bool
+	Ulimits              []*ulimit.Ulimit
+	Sysctls              map[string]string // Namespaced kernel parameters to set in the container
+	ShmSize              int64             // Size of /dev/shm in bytes, 64MB if 0
+	GroupAdd             []string          // Additional groups, by name or gid, of the container's user
+	LogConfig            LogConfig
+	CgroupParent         string // Parent cgroup.
+	Init                 *bool  // Run an init inside the container, nil for the daemon's default
 }
 
 func MergeConfigs(config *Config, hostConfig *HostConfig) *ContainerConfigWrapper {
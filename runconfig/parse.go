@@ -2,6 +2,7 @@ package runconfig
 
 import (
 	"fmt"
+	"io/ioutil"
 	"path"
 	"strconv"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"github.com/docker/docker/opts"
 	flag "github.com/docker/docker/pkg/mflag"
 	"github.com/docker/docker/pkg/parsers"
+	"github.com/docker/docker/pkg/signal"
 	"github.com/docker/docker/pkg/ulimit"
 	"github.com/docker/docker/pkg/units"
 )
@@ -21,6 +23,8 @@ var (
 	ErrConflictNetworkHostname          = fmt.Errorf("Conflicting options: -h and the network mode (--net)")
 	ErrConflictHostNetworkAndDns        = fmt.Errorf("Conflicting options: --net=host can't be used with --dns. This configuration is invalid.")
 	ErrConflictHostNetworkAndLinks      = fmt.Errorf("Conflicting options: --net=host can't be used with links. This would result in undefined behavior.")
+	ErrConflictNetworkRate              = fmt.Errorf("Conflicting options: --net-rate-ingress and --net-rate-egress can only be used with --net=bridge")
+	ErrConflictNoHealthcheck            = fmt.Errorf("Conflicting options: --no-healthcheck can't be used with the other --health-* options")
 )
 
 func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSet, error) {
@@ -33,6 +37,12 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 		flLabels  = opts.NewListOpts(opts.ValidateEnv)
 		flDevices = opts.NewListOpts(opts.ValidatePath)
 
+		flBlkioWeightDevice = opts.NewListOpts(nil)
+		flDeviceReadBps     = opts.NewListOpts(nil)
+		flDeviceWriteBps    = opts.NewListOpts(nil)
+		flDeviceReadIOps    = opts.NewListOpts(nil)
+		flDeviceWriteIOps   = opts.NewListOpts(nil)
+
 		ulimits   = make(map[string]*ulimit.Ulimit)
 		flUlimits = opts.NewUlimitOpt(ulimits)
 
@@ -48,6 +58,8 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 		flCapDrop     = opts.NewListOpts(nil)
 		flSecurityOpt = opts.NewListOpts(nil)
 		flLabelsFile  = opts.NewListOpts(nil)
+		flSysctls     = opts.NewListOpts(opts.ValidateSysctl)
+		flGroupAdd    = opts.NewListOpts(nil)
 
 		flNetwork         = cmd.Bool([]string{"#n", "#-networking"}, true, "Enable networking for this container")
 		flPrivileged      = cmd.Bool([]string{"#privileged", "-privileged"}, false, "Give extended privileges to this container")
@@ -60,25 +72,51 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 		flHostname        = cmd.String([]string{"h", "-hostname"}, "", "Container host name")
 		flMemoryString    = cmd.String([]string{"m", "-memory"}, "", "Memory limit")
 		flMemorySwap      = cmd.String([]string{"-memory-swap"}, "", "Total memory (memory + swap), '-1' to disable swap")
+		flMemoryReserve   = cmd.String([]string{"-memory-reservation"}, "", "Memory soft limit")
+		flKernelMemory    = cmd.String([]string{"-kernel-memory"}, "", "Kernel memory limit")
+		flShmSize         = cmd.String([]string{"-shm-size"}, "", "Size of /dev/shm, 64m by default")
+		flSwappiness      = cmd.Int64([]string{"-memory-swappiness"}, -1, "Tuning container memory swappiness (0 to 100)")
+		flOomKillDisable  = cmd.Bool([]string{"-oom-kill-disable"}, false, "Disable OOM Killer")
+		flOomScoreAdj     = cmd.Int([]string{"-oom-score-adj"}, 0, "Tune host's OOM preferences (-1000 to 1000)")
 		flUser            = cmd.String([]string{"u", "-user"}, "", "Username or UID (format: <name|uid>[:<group|gid>])")
 		flWorkingDir      = cmd.String([]string{"w", "-workdir"}, "", "Working directory inside the container")
 		flCpuShares       = cmd.Int64([]string{"c", "-cpu-shares"}, 0, "CPU shares (relative weight)")
 		flCpusetCpus      = cmd.String([]string{"#-cpuset", "-cpuset-cpus"}, "", "CPUs in which to allow execution (0-3, 0,1)")
 		flCpusetMems      = cmd.String([]string{"-cpuset-mems"}, "", "MEMs in which to allow execution (0-3, 0,1)")
 		flCpuQuota        = cmd.Int64([]string{"-cpu-quota"}, 0, "Limit the CPU CFS (Completely Fair Scheduler) quota")
+		flBlkioWeight     = cmd.Uint([]string{"-blkio-weight"}, 0, "Block IO (relative weight), between 10 and 1000")
+		flPidsLimit       = cmd.Int64([]string{"-pids-limit"}, 0, "Tune container pids limit (set -1 for unlimited)")
 		flNetMode         = cmd.String([]string{"-net"}, "bridge", "Set the Network mode for the container")
 		flMacAddress      = cmd.String([]string{"-mac-address"}, "", "Container MAC address (e.g. 92:d0:c6:0a:29:33)")
+		flNetRateIngress  = cmd.String([]string{"-net-rate-ingress"}, "", "Bandwidth limit for traffic into the container (bytes per second)")
+		flNetRateEgress   = cmd.String([]string{"-net-rate-egress"}, "", "Bandwidth limit for traffic out of the container (bytes per second)")
 		flIpcMode         = cmd.String([]string{"-ipc"}, "", "IPC namespace to use")
 		flRestartPolicy   = cmd.String([]string{"-restart"}, "no", "Restart policy to apply when a container exits")
+		flRestartDelay    = cmd.Duration([]string{"-restart-delay"}, 0, "Delay before restarting the container, doubled after each quick exit")
+		flRestartMaxDelay = cmd.Duration([]string{"-restart-max-delay"}, 0, "Maximum delay between restarts of the container")
+		flRestartReset    = cmd.Duration([]string{"-restart-reset-window"}, 0, "Running time after which the restart delay is reset")
 		flReadonlyRootfs  = cmd.Bool([]string{"-read-only"}, false, "Mount the container's root filesystem as read only")
+		flInit            = cmd.Bool([]string{"-init"}, false, "Run an init inside the container that forwards signals and reaps processes")
 		flLoggingDriver   = cmd.String([]string{"-log-driver"}, "", "Logging driver for container")
 		flCgroupParent    = cmd.String([]string{"-cgroup-parent"}, "", "Optional parent cgroup for the container")
+		flHealthCmd       = cmd.String([]string{"-health-cmd"}, "", "Command to run to check health")
+		flHealthInterval  = cmd.Duration([]string{"-health-interval"}, 0, "Time between running the check")
+		flHealthTimeout   = cmd.Duration([]string{"-health-timeout"}, 0, "Maximum time to allow one check to run")
+		flHealthRetries   = cmd.Int([]string{"-health-retries"}, 0, "Consecutive failures needed to report unhealthy")
+		flNoHealthcheck   = cmd.Bool([]string{"-no-healthcheck"}, false, "Disable any container-specified HEALTHCHECK")
+		flStopSignal      = cmd.String([]string{"-stop-signal"}, "", "Signal to stop a container, SIGTERM by default")
+		flStopTimeout     = cmd.Int([]string{"-stop-timeout"}, 10, "Seconds to wait for the container to stop before killing it")
 	)
 
 	cmd.Var(&flAttach, []string{"a", "-attach"}, "Attach to STDIN, STDOUT or STDERR")
 	cmd.Var(&flVolumes, []string{"v", "-volume"}, "Bind mount a volume")
 	cmd.Var(&flLinks, []string{"#link", "-link"}, "Add link to another container")
 	cmd.Var(&flDevices, []string{"-device"}, "Add a host device to the container")
+	cmd.Var(&flBlkioWeightDevice, []string{"-blkio-weight-device"}, "Block IO weight (relative device weight)")
+	cmd.Var(&flDeviceReadBps, []string{"-device-read-bps"}, "Limit read rate (bytes per second) from a device")
+	cmd.Var(&flDeviceWriteBps, []string{"-device-write-bps"}, "Limit write rate (bytes per second) to a device")
+	cmd.Var(&flDeviceReadIOps, []string{"-device-read-iops"}, "Limit read rate (IO per second) from a device")
+	cmd.Var(&flDeviceWriteIOps, []string{"-device-write-iops"}, "Limit write rate (IO per second) to a device")
 	cmd.Var(&flLabels, []string{"l", "-label"}, "Set meta data on a container")
 	cmd.Var(&flLabelsFile, []string{"-label-file"}, "Read in a line delimited file of labels")
 	cmd.Var(&flEnv, []string{"e", "-env"}, "Set environment variables")
@@ -94,6 +132,8 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 	cmd.Var(&flCapDrop, []string{"-cap-drop"}, "Drop Linux capabilities")
 	cmd.Var(&flSecurityOpt, []string{"-security-opt"}, "Security Options")
 	cmd.Var(flUlimits, []string{"-ulimit"}, "Ulimit options")
+	cmd.Var(&flSysctls, []string{"-sysctl"}, "Set a namespaced kernel parameter (e.g. net.core.somaxconn=1024)")
+	cmd.Var(&flGroupAdd, []string{"-group-add"}, "Add additional groups to join")
 
 	cmd.Require(flag.Min, 1)
 
@@ -118,7 +158,7 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 		attachStderr = flAttach.Get("stderr")
 	)
 
-	if *flNetMode != "bridge" && *flNetMode != "none" && *flHostname != "" {
+	if *flNetMode != "bridge" && *flNetMode != "none" && *flNetMode != "macvlan" && !NetworkMode(*flNetMode).IsOverlay() && *flHostname != "" {
 		return nil, nil, cmd, ErrConflictNetworkHostname
 	}
 
@@ -169,6 +209,93 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 		}
 	}
 
+	var MemoryReservation int64
+	if *flMemoryReserve != "" {
+		parsedMemoryReserve, err := units.RAMInBytes(*flMemoryReserve)
+		if err != nil {
+			return nil, nil, cmd, err
+		}
+		MemoryReservation = parsedMemoryReserve
+	}
+
+	var KernelMemory int64
+	if *flKernelMemory != "" {
+		parsedKernelMemory, err := units.RAMInBytes(*flKernelMemory)
+		if err != nil {
+			return nil, nil, cmd, err
+		}
+		KernelMemory = parsedKernelMemory
+	}
+
+	var ShmSize int64
+	if *flShmSize != "" {
+		parsedShmSize, err := units.RAMInBytes(*flShmSize)
+		if err != nil {
+			return nil, nil, cmd, err
+		}
+		if parsedShmSize <= 0 {
+			return nil, nil, cmd, fmt.Errorf("Invalid size of /dev/shm: %s, it has to be greater than 0", *flShmSize)
+		}
+		ShmSize = parsedShmSize
+	}
+
+	var MemorySwappiness *int64
+	if *flSwappiness != -1 {
+		MemorySwappiness = flSwappiness
+	}
+
+	var netRateIngress, netRateEgress int64
+	if *flNetRateIngress != "" {
+		parsedRate, err := units.FromHumanSize(*flNetRateIngress)
+		if err != nil {
+			return nil, nil, cmd, err
+		}
+		netRateIngress = parsedRate
+	}
+	if *flNetRateEgress != "" {
+		parsedRate, err := units.FromHumanSize(*flNetRateEgress)
+		if err != nil {
+			return nil, nil, cmd, err
+		}
+		netRateEgress = parsedRate
+	}
+	if (netRateIngress != 0 || netRateEgress != 0) && *flNetMode != "bridge" {
+		return nil, nil, cmd, ErrConflictNetworkRate
+	}
+
+	var healthConfig *HealthConfig
+	haveHealthSettings := *flHealthCmd != "" || *flHealthInterval != 0 || *flHealthTimeout != 0 || *flHealthRetries != 0
+	if *flNoHealthcheck {
+		if haveHealthSettings {
+			return nil, nil, cmd, ErrConflictNoHealthcheck
+		}
+		healthConfig = &HealthConfig{Test: []string{"NONE"}}
+	} else if haveHealthSettings {
+		if *flHealthInterval < 0 || *flHealthTimeout < 0 || *flHealthRetries < 0 {
+			return nil, nil, cmd, fmt.Errorf("--health-interval, --health-timeout and --health-retries can't be negative")
+		}
+		var probe []string
+		if *flHealthCmd != "" {
+			probe = []string{"CMD-SHELL", *flHealthCmd}
+		}
+		healthConfig = &HealthConfig{
+			Test:     probe,
+			Interval: *flHealthInterval,
+			Timeout:  *flHealthTimeout,
+			Retries:  *flHealthRetries,
+		}
+	}
+
+	if *flStopSignal != "" {
+		if _, err := signal.ParseSignal(*flStopSignal); err != nil {
+			return nil, nil, cmd, err
+		}
+	}
+
+	if *flStopTimeout < 0 {
+		return nil, nil, cmd, fmt.Errorf("Invalid stop timeout: %d, it can't be negative", *flStopTimeout)
+	}
+
 	var binds []string
 	// add any bind targets to the list of container volumes
 	for bind := range flVolumes.GetMap() {
@@ -250,6 +377,36 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 		deviceMappings = append(deviceMappings, deviceMapping)
 	}
 
+	if *flBlkioWeight > 1000 {
+		return nil, nil, cmd, fmt.Errorf("Invalid --blkio-weight: %d, the valid range is 10-1000", *flBlkioWeight)
+	}
+
+	// parse block IO weights and throttles
+	blkioWeightDevices := []*WeightDevice{}
+	for _, val := range flBlkioWeightDevice.GetAll() {
+		weightDevice, err := ParseWeightDevice(val)
+		if err != nil {
+			return nil, nil, cmd, err
+		}
+		blkioWeightDevices = append(blkioWeightDevices, weightDevice)
+	}
+	deviceReadBps, err := parseThrottleDevices(flDeviceReadBps.GetAll(), ParseThrottleBpsDevice)
+	if err != nil {
+		return nil, nil, cmd, err
+	}
+	deviceWriteBps, err := parseThrottleDevices(flDeviceWriteBps.GetAll(), ParseThrottleBpsDevice)
+	if err != nil {
+		return nil, nil, cmd, err
+	}
+	deviceReadIOps, err := parseThrottleDevices(flDeviceReadIOps.GetAll(), ParseThrottleIOpsDevice)
+	if err != nil {
+		return nil, nil, cmd, err
+	}
+	deviceWriteIOps, err := parseThrottleDevices(flDeviceWriteIOps.GetAll(), ParseThrottleIOpsDevice)
+	if err != nil {
+		return nil, nil, cmd, err
+	}
+
 	// collect all the environment variables for the container
 	envVariables, err := readKVStrings(flEnvFile.GetAll(), flEnv.GetAll())
 	if err != nil {
@@ -281,6 +438,17 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 	if err != nil {
 		return nil, nil, cmd, err
 	}
+	if *flRestartDelay < 0 || *flRestartMaxDelay < 0 || *flRestartReset < 0 {
+		return nil, nil, cmd, fmt.Errorf("Restart delays can't be negative")
+	}
+	restartPolicy.Delay = *flRestartDelay
+	restartPolicy.MaxDelay = *flRestartMaxDelay
+	restartPolicy.ResetWindow = *flRestartReset
+
+	securityOpts, err := parseSecurityOpts(flSecurityOpt.GetAll())
+	if err != nil {
+		return nil, nil, cmd, err
+	}
 
 	config := &Config{
 		Hostname:        hostname,
@@ -302,38 +470,64 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 		Entrypoint:      entrypoint,
 		WorkingDir:      *flWorkingDir,
 		Labels:          convertKVStringsToMap(labels),
+		Healthcheck:     healthConfig,
+		StopSignal:      *flStopSignal,
 	}
 
 	hostConfig := &HostConfig{
-		Binds:           binds,
-		ContainerIDFile: *flContainerIDFile,
-		LxcConf:         lxcConf,
-		Memory:          flMemory,
-		MemorySwap:      MemorySwap,
-		CpuShares:       *flCpuShares,
-		CpusetCpus:      *flCpusetCpus,
-		CpusetMems:      *flCpusetMems,
-		CpuQuota:        *flCpuQuota,
-		Privileged:      *flPrivileged,
-		PortBindings:    portBindings,
-		Links:           flLinks.GetAll(),
-		PublishAllPorts: *flPublishAll,
-		Dns:             flDns.GetAll(),
-		DnsSearch:       flDnsSearch.GetAll(),
-		ExtraHosts:      flExtraHosts.GetAll(),
-		VolumesFrom:     flVolumesFrom.GetAll(),
-		NetworkMode:     netMode,
-		IpcMode:         ipcMode,
-		PidMode:         pidMode,
-		Devices:         deviceMappings,
-		CapAdd:          flCapAdd.GetAll(),
-		CapDrop:         flCapDrop.GetAll(),
-		RestartPolicy:   restartPolicy,
-		SecurityOpt:     flSecurityOpt.GetAll(),
-		ReadonlyRootfs:  *flReadonlyRootfs,
-		Ulimits:         flUlimits.GetList(),
-		LogConfig:       LogConfig{Type: *flLoggingDriver},
-		CgroupParent:    *flCgroupParent,
+		Binds:                binds,
+		ContainerIDFile:      *flContainerIDFile,
+		LxcConf:              lxcConf,
+		Memory:               flMemory,
+		MemorySwap:           MemorySwap,
+		MemoryReservation:    MemoryReservation,
+		KernelMemory:         KernelMemory,
+		MemorySwappiness:     MemorySwappiness,
+		OomKillDisable:       *flOomKillDisable,
+		OomScoreAdj:          *flOomScoreAdj,
+		CpuShares:            *flCpuShares,
+		CpusetCpus:           *flCpusetCpus,
+		CpusetMems:           *flCpusetMems,
+		CpuQuota:             *flCpuQuota,
+		PidsLimit:            *flPidsLimit,
+		BlkioWeight:          uint16(*flBlkioWeight),
+		BlkioWeightDevice:    blkioWeightDevices,
+		BlkioDeviceReadBps:   deviceReadBps,
+		BlkioDeviceWriteBps:  deviceWriteBps,
+		BlkioDeviceReadIOps:  deviceReadIOps,
+		BlkioDeviceWriteIOps: deviceWriteIOps,
+		Privileged:           *flPrivileged,
+		PortBindings:         portBindings,
+		Links:                flLinks.GetAll(),
+		PublishAllPorts:      *flPublishAll,
+		Dns:                  flDns.GetAll(),
+		DnsSearch:            flDnsSearch.GetAll(),
+		ExtraHosts:           flExtraHosts.GetAll(),
+		VolumesFrom:          flVolumesFrom.GetAll(),
+		NetworkMode:          netMode,
+		NetworkRate:          NetworkRate{Ingress: netRateIngress, Egress: netRateEgress},
+		IpcMode:              ipcMode,
+		PidMode:              pidMode,
+		Devices:              deviceMappings,
+		CapAdd:               flCapAdd.GetAll(),
+		CapDrop:              flCapDrop.GetAll(),
+		RestartPolicy:        restartPolicy,
+		SecurityOpt:          securityOpts,
+		ReadonlyRootfs:       *flReadonlyRootfs,
+		Ulimits:              flUlimits.GetList(),
+		Sysctls:              convertKVStringsToMap(flSysctls.GetAll()),
+		ShmSize:              ShmSize,
+		GroupAdd:             flGroupAdd.GetAll(),
+		LogConfig:            LogConfig{Type: *flLoggingDriver},
+		CgroupParent:         *flCgroupParent,
+	}
+
+	if cmd.IsSet("-init") {
+		hostConfig.Init = flInit
+	}
+
+	if cmd.IsSet("-stop-timeout") {
+		config.StopTimeout = flStopTimeout
 	}
 
 	// When allocating stdin in attached mode, close stdin at client disconnect
@@ -389,9 +583,9 @@ func ParseRestartPolicy(policy string) (RestartPolicy, error) {
 
 	p.Name = name
 	switch name {
-	case "always":
+	case "always", "unless-stopped":
 		if len(parts) == 2 {
-			return p, fmt.Errorf("maximum restart count not valid with restart policy of \"always\"")
+			return p, fmt.Errorf("maximum restart count not valid with restart policy of %q", name)
 		}
 	case "no":
 		// do nothing
@@ -412,6 +606,27 @@ func ParseRestartPolicy(policy string) (RestartPolicy, error) {
 }
 
 // options will come in the format of name.key=value or name.option
+// parseSecurityOpts replaces the path of a seccomp profile given with
+// seccomp=<profile.json> by the contents of the file, the daemon cannot
+// read files on the client's host.
+func parseSecurityOpts(securityOpts []string) ([]string, error) {
+	for i, opt := range securityOpts {
+		if !strings.HasPrefix(opt, "seccomp=") {
+			continue
+		}
+		profile := strings.TrimPrefix(opt, "seccomp=")
+		if profile == "unconfined" {
+			continue
+		}
+		f, err := ioutil.ReadFile(profile)
+		if err != nil {
+			return securityOpts, fmt.Errorf("Opening seccomp profile (%s) failed: %v", profile, err)
+		}
+		securityOpts[i] = "seccomp=" + string(f)
+	}
+	return securityOpts, nil
+}
+
 func parseDriverOpts(opts opts.ListOpts) (map[string][]string, error) {
 	out := make(map[string][]string, len(opts.GetAll()))
 	for _, o := range opts.GetAll() {
@@ -445,11 +660,15 @@ func parseKeyValueOpts(opts opts.ListOpts) ([]KeyValuePair, error) {
 func parseNetMode(netMode string) (NetworkMode, error) {
 	parts := strings.Split(netMode, ":")
 	switch mode := parts[0]; mode {
-	case "bridge", "none", "host":
+	case "bridge", "none", "host", "macvlan":
 	case "container":
 		if len(parts) < 2 || parts[1] == "" {
 			return "", fmt.Errorf("invalid container format container:<name|id>")
 		}
+	case "overlay":
+		if len(parts) != 2 || parts[1] == "" {
+			return "", fmt.Errorf("invalid overlay format overlay:<network>")
+		}
 	default:
 		return "", fmt.Errorf("invalid --net: %s", netMode)
 	}
@@ -485,3 +704,71 @@ func ParseDevice(device string) (DeviceMapping, error) {
 	}
 	return deviceMapping, nil
 }
+
+// ParseWeightDevice parses a block IO weight of a device in the form
+// <device-path>:<weight>.
+func ParseWeightDevice(val string) (*WeightDevice, error) {
+	arr := strings.Split(val, ":")
+	if len(arr) != 2 {
+		return nil, fmt.Errorf("Invalid weight device specification: %s", val)
+	}
+	if !strings.HasPrefix(arr[0], "/dev/") {
+		return nil, fmt.Errorf("Invalid weight device specification: %s, the device must be a path in /dev", val)
+	}
+	weight, err := strconv.ParseUint(arr[1], 10, 0)
+	if err != nil || (weight != 0 && (weight < 10 || weight > 1000)) {
+		return nil, fmt.Errorf("Invalid weight for device: %s, the valid range is 10-1000", val)
+	}
+	return &WeightDevice{Path: arr[0], Weight: uint16(weight)}, nil
+}
+
+// ParseThrottleBpsDevice parses a bandwidth limit of a device in the form
+// <device-path>:<number>[<unit>], where unit is one of b, k, m or g.
+func ParseThrottleBpsDevice(val string) (*ThrottleDevice, error) {
+	return parseThrottleDevice(val, func(rate string) (uint64, error) {
+		bytes, err := units.RAMInBytes(rate)
+		if err != nil || bytes < 0 {
+			return 0, fmt.Errorf("Invalid rate for device: %s, the rate must be a positive integer with an optional unit (b, k, m or g)", val)
+		}
+		return uint64(bytes), nil
+	})
+}
+
+// ParseThrottleIOpsDevice parses a limit of the IO operations per second
+// of a device in the form <device-path>:<number>.
+func ParseThrottleIOpsDevice(val string) (*ThrottleDevice, error) {
+	return parseThrottleDevice(val, func(rate string) (uint64, error) {
+		iops, err := strconv.ParseUint(rate, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid rate for device: %s, the rate must be a positive integer", val)
+		}
+		return iops, nil
+	})
+}
+
+func parseThrottleDevice(val string, parseRate func(string) (uint64, error)) (*ThrottleDevice, error) {
+	arr := strings.Split(val, ":")
+	if len(arr) != 2 {
+		return nil, fmt.Errorf("Invalid throttle device specification: %s", val)
+	}
+	if !strings.HasPrefix(arr[0], "/dev/") {
+		return nil, fmt.Errorf("Invalid throttle device specification: %s, the device must be a path in /dev", val)
+	}
+	rate, err := parseRate(arr[1])
+	if err != nil {
+		return nil, err
+	}
+	return &ThrottleDevice{Path: arr[0], Rate: rate}, nil
+}
+
+func parseThrottleDevices(vals []string, parse func(string) (*ThrottleDevice, error)) ([]*ThrottleDevice, error) {
+	devices := []*ThrottleDevice{}
+	for _, val := range vals {
+		device, err := parse(val)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
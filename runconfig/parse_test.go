@@ -3,6 +3,7 @@ package runconfig
 import (
 	"io/ioutil"
 	"testing"
+	"time"
 
 	flag "github.com/docker/docker/pkg/mflag"
 	"github.com/docker/docker/pkg/parsers"
@@ -49,6 +50,18 @@ func TestNetHostname(t *testing.T) {
 		t.Fatalf("Unexpected error: %s", err)
 	}
 
+	if _, _, _, err := parseRun([]string{"-h=name", "--net=macvlan", "img", "cmd"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if _, _, _, err := parseRun([]string{"-h=name", "--net=overlay:blue", "img", "cmd"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if _, _, _, err := parseRun([]string{"--net=overlay:", "img", "cmd"}); err == nil {
+		t.Fatal("Expected an error for an overlay without network name")
+	}
+
 	if _, _, _, err := parseRun([]string{"-h=name", "--net=host", "img", "cmd"}); err != ErrConflictNetworkHostname {
 		t.Fatalf("Expected error ErrConflictNetworkHostname, got: %s", err)
 	}
@@ -63,3 +76,232 @@ func TestConflictContainerNetworkAndLinks(t *testing.T) {
 		t.Fatalf("Expected error ErrConflictContainerNetworkAndLinks, got: %s", err)
 	}
 }
+
+func TestParseHealth(t *testing.T) {
+	config, _, _, err := parseRun([]string{"img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Healthcheck != nil {
+		t.Fatalf("Unexpected health check %+v", config.Healthcheck)
+	}
+
+	config, _, _, err = parseRun([]string{"--no-healthcheck", "img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Healthcheck == nil || len(config.Healthcheck.Test) != 1 || config.Healthcheck.Test[0] != "NONE" {
+		t.Fatalf("Expected the health check to be disabled, got %+v", config.Healthcheck)
+	}
+
+	config, _, _, err = parseRun([]string{"--health-cmd=curl -f http://localhost/", "--health-interval=5s", "--health-retries=2", "img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	health := config.Healthcheck
+	if health == nil || len(health.Test) != 2 || health.Test[0] != "CMD-SHELL" || health.Test[1] != "curl -f http://localhost/" {
+		t.Fatalf("Unexpected health check %+v", health)
+	}
+	if health.Interval != 5*time.Second || health.Timeout != 0 || health.Retries != 2 {
+		t.Fatalf("Unexpected health check settings %+v", health)
+	}
+
+	// settings without a command override those of the image's probe
+	config, _, _, err = parseRun([]string{"--health-timeout=2s", "img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if health := config.Healthcheck; health == nil || len(health.Test) != 0 || health.Timeout != 2*time.Second {
+		t.Fatalf("Unexpected health check %+v", health)
+	}
+
+	if _, _, _, err := parseRun([]string{"--no-healthcheck", "--health-cmd=true", "img", "cmd"}); err != ErrConflictNoHealthcheck {
+		t.Fatalf("Expected error ErrConflictNoHealthcheck, got: %v", err)
+	}
+	if _, _, _, err := parseRun([]string{"--health-retries=-1", "img", "cmd"}); err == nil {
+		t.Fatal("Expected an error for negative retries")
+	}
+}
+
+func TestParseStopSignal(t *testing.T) {
+	config, _, _, err := parseRun([]string{"img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.StopSignal != "" {
+		t.Fatalf("Expected no stop signal, got %q", config.StopSignal)
+	}
+
+	config, _, _, err = parseRun([]string{"--stop-signal=SIGQUIT", "img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.StopSignal != "SIGQUIT" {
+		t.Fatalf("Expected stop signal SIGQUIT, got %q", config.StopSignal)
+	}
+
+	if _, _, _, err := parseRun([]string{"--stop-signal=SIGNOPE", "img", "cmd"}); err == nil {
+		t.Fatal("Expected an error for an invalid stop signal")
+	}
+}
+
+func TestParseStopTimeout(t *testing.T) {
+	config, _, _, err := parseRun([]string{"img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.StopTimeout != nil {
+		t.Fatalf("Expected no stop timeout, got %d", *config.StopTimeout)
+	}
+
+	config, _, _, err = parseRun([]string{"--stop-timeout=30", "img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.StopTimeout == nil || *config.StopTimeout != 30 {
+		t.Fatalf("Expected stop timeout 30, got %v", config.StopTimeout)
+	}
+
+	if _, _, _, err := parseRun([]string{"--stop-timeout=-1", "img", "cmd"}); err == nil {
+		t.Fatal("Expected an error for a negative stop timeout")
+	}
+}
+
+func TestParseSysctls(t *testing.T) {
+	_, hostConfig, _, err := parseRun([]string{"--sysctl=net.core.somaxconn=1024", "--sysctl=kernel.sem=250 32000 100 128", "img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hostConfig.Sysctls) != 2 || hostConfig.Sysctls["net.core.somaxconn"] != "1024" || hostConfig.Sysctls["kernel.sem"] != "250 32000 100 128" {
+		t.Fatalf("Unexpected sysctls %v", hostConfig.Sysctls)
+	}
+
+	if _, _, _, err := parseRun([]string{"--sysctl=kernel.hostname=docker", "img", "cmd"}); err == nil {
+		t.Fatal("Expected an error for a sysctl that isn't namespaced")
+	}
+}
+
+func TestParseShmSize(t *testing.T) {
+	_, hostConfig, _, err := parseRun([]string{"--shm-size=128m", "img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hostConfig.ShmSize != 128*1024*1024 {
+		t.Fatalf("Expected a /dev/shm size of 128m, got %d", hostConfig.ShmSize)
+	}
+
+	for _, size := range []string{"0", "-1", "big"} {
+		if _, _, _, err := parseRun([]string{"--shm-size=" + size, "img", "cmd"}); err == nil {
+			t.Fatalf("Expected an error for a /dev/shm size of %q", size)
+		}
+	}
+}
+
+func TestParseGroupAdd(t *testing.T) {
+	_, hostConfig, _, err := parseRun([]string{"--group-add=audio", "--group-add=777", "img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hostConfig.GroupAdd) != 2 || hostConfig.GroupAdd[0] != "audio" || hostConfig.GroupAdd[1] != "777" {
+		t.Fatalf("Unexpected groups %v", hostConfig.GroupAdd)
+	}
+}
+
+func TestParseNetworkRate(t *testing.T) {
+	_, hostConfig, _, err := parseRun([]string{"--net-rate-ingress=10m", "--net-rate-egress=500k", "img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hostConfig.NetworkRate.Ingress != 10000000 || hostConfig.NetworkRate.Egress != 500000 {
+		t.Fatalf("Unexpected network rate %+v", hostConfig.NetworkRate)
+	}
+
+	if _, _, _, err := parseRun([]string{"--net-rate-ingress=fast", "img", "cmd"}); err == nil {
+		t.Fatal("Expected an error for an invalid rate")
+	}
+
+	if _, _, _, err := parseRun([]string{"--net=host", "--net-rate-egress=1m", "img", "cmd"}); err != ErrConflictNetworkRate {
+		t.Fatalf("Expected error ErrConflictNetworkRate, got: %v", err)
+	}
+}
+
+func TestParsePidsLimit(t *testing.T) {
+	_, hostConfig, _, err := parseRun([]string{"--pids-limit=100", "img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hostConfig.PidsLimit != 100 {
+		t.Fatalf("Expected a pids limit of 100, got %d", hostConfig.PidsLimit)
+	}
+}
+
+func TestParseInit(t *testing.T) {
+	_, hostConfig, _, err := parseRun([]string{"img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hostConfig.Init != nil {
+		t.Fatalf("Expected no init setting, got %v", *hostConfig.Init)
+	}
+	for _, flag := range []string{"--init", "--init=false"} {
+		_, hostConfig, _, err := parseRun([]string{flag, "img", "cmd"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := flag == "--init"
+		if hostConfig.Init == nil || *hostConfig.Init != expected {
+			t.Fatalf("Expected init to be %v for %s, got %v", expected, flag, hostConfig.Init)
+		}
+	}
+}
+
+func TestParseBlkio(t *testing.T) {
+	_, hostConfig, _, err := parseRun([]string{"--blkio-weight=300", "--blkio-weight-device=/dev/sda:500", "--device-read-bps=/dev/sda:10mb", "--device-write-iops=/dev/sdb:1000", "img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hostConfig.BlkioWeight != 300 {
+		t.Fatalf("Expected a block IO weight of 300, got %d", hostConfig.BlkioWeight)
+	}
+	if len(hostConfig.BlkioWeightDevice) != 1 || *hostConfig.BlkioWeightDevice[0] != (WeightDevice{"/dev/sda", 500}) {
+		t.Fatalf("Unexpected weight devices %v", hostConfig.BlkioWeightDevice)
+	}
+	if len(hostConfig.BlkioDeviceReadBps) != 1 || *hostConfig.BlkioDeviceReadBps[0] != (ThrottleDevice{"/dev/sda", 10485760}) {
+		t.Fatalf("Unexpected read bps devices %v", hostConfig.BlkioDeviceReadBps)
+	}
+	if len(hostConfig.BlkioDeviceWriteIOps) != 1 || *hostConfig.BlkioDeviceWriteIOps[0] != (ThrottleDevice{"/dev/sdb", 1000}) {
+		t.Fatalf("Unexpected write iops devices %v", hostConfig.BlkioDeviceWriteIOps)
+	}
+
+	invalid := [][]string{
+		{"--blkio-weight=2000"},
+		{"--blkio-weight-device=/dev/sda:5"},
+		{"--blkio-weight-device=sda:500"},
+		{"--device-read-bps=/dev/sda"},
+		{"--device-write-bps=/dev/sda:fast"},
+		{"--device-read-iops=/dev/sda:10k"},
+	}
+	for _, args := range invalid {
+		if _, _, _, err := parseRun(append(args, "img", "cmd")); err == nil {
+			t.Fatalf("Expected an error for %v", args)
+		}
+	}
+}
+
+func TestParseRestartPolicy(t *testing.T) {
+	_, hostConfig, _, err := parseRun([]string{"--restart=unless-stopped", "--restart-delay=1s", "--restart-max-delay=1m", "--restart-reset-window=30s", "img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := RestartPolicy{Name: "unless-stopped", Delay: time.Second, MaxDelay: time.Minute, ResetWindow: 30 * time.Second}
+	if hostConfig.RestartPolicy != expected {
+		t.Fatalf("Expected restart policy %+v, got %+v", expected, hostConfig.RestartPolicy)
+	}
+
+	if _, _, _, err := parseRun([]string{"--restart=unless-stopped:3", "img", "cmd"}); err == nil {
+		t.Fatal("Expected an error for a maximum restart count with unless-stopped")
+	}
+	if _, _, _, err := parseRun([]string{"--restart-delay=-1s", "img", "cmd"}); err == nil {
+		t.Fatal("Expected an error for a negative restart delay")
+	}
+}
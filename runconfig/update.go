@@ -0,0 +1,74 @@
+package runconfig
+
+import (
+	flag "github.com/docker/docker/pkg/mflag"
+	"github.com/docker/docker/pkg/units"
+)
+
+// UpdateConfig holds the resource limits and restart policy that can be
+// changed on an existing container. Nil limits and empty strings leave the
+// current setting of the container unchanged, while a limit of 0 removes it.
+type UpdateConfig struct {
+	CpuShares     *int64
+	CpuQuota      *int64
+	CpusetCpus    string
+	CpusetMems    string
+	Memory        *int64
+	MemorySwap    *int64
+	RestartPolicy RestartPolicy
+}
+
+func ParseUpdate(cmd *flag.FlagSet, args []string) (*UpdateConfig, error) {
+	var (
+		flCpuShares     = cmd.Int64([]string{"c", "-cpu-shares"}, 0, "CPU shares (relative weight), 0 to reset to the default")
+		flCpuQuota      = cmd.Int64([]string{"-cpu-quota"}, 0, "Limit the CPU CFS (Completely Fair Scheduler) quota, 0 to remove the limit")
+		flCpusetCpus    = cmd.String([]string{"-cpuset-cpus"}, "", "CPUs in which to allow execution (0-3, 0,1)")
+		flCpusetMems    = cmd.String([]string{"-cpuset-mems"}, "", "MEMs in which to allow execution (0-3, 0,1)")
+		flMemoryString  = cmd.String([]string{"m", "-memory"}, "", "Memory limit, 0 to remove the limit")
+		flMemorySwap    = cmd.String([]string{"-memory-swap"}, "", "Total memory (memory + swap), '-1' to disable swap")
+		flRestartPolicy = cmd.String([]string{"-restart"}, "", "Restart policy to apply when a container exits")
+	)
+	cmd.Require(flag.Min, 1)
+	if err := cmd.ParseFlags(args, true); err != nil {
+		return nil, err
+	}
+
+	updateConfig := &UpdateConfig{
+		CpusetCpus: *flCpusetCpus,
+		CpusetMems: *flCpusetMems,
+	}
+	if cmd.IsSet("c") || cmd.IsSet("-cpu-shares") {
+		updateConfig.CpuShares = flCpuShares
+	}
+	if cmd.IsSet("-cpu-quota") {
+		updateConfig.CpuQuota = flCpuQuota
+	}
+
+	if *flMemoryString != "" {
+		memory, err := units.RAMInBytes(*flMemoryString)
+		if err != nil {
+			return nil, err
+		}
+		updateConfig.Memory = &memory
+	}
+
+	if *flMemorySwap != "" {
+		var memorySwap int64 = -1
+		if *flMemorySwap != "-1" {
+			parsedMemorySwap, err := units.RAMInBytes(*flMemorySwap)
+			if err != nil {
+				return nil, err
+			}
+			memorySwap = parsedMemorySwap
+		}
+		updateConfig.MemorySwap = &memorySwap
+	}
+
+	restartPolicy, err := ParseRestartPolicy(*flRestartPolicy)
+	if err != nil {
+		return nil, err
+	}
+	updateConfig.RestartPolicy = restartPolicy
+
+	return updateConfig, nil
+}
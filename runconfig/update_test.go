@@ -0,0 +1,59 @@
+package runconfig
+
+import (
+	"io/ioutil"
+	"testing"
+
+	flag "github.com/docker/docker/pkg/mflag"
+)
+
+func parseUpdate(args []string) (*UpdateConfig, error) {
+	cmd := flag.NewFlagSet("update", flag.ContinueOnError)
+	cmd.SetOutput(ioutil.Discard)
+	cmd.Usage = nil
+	return ParseUpdate(cmd, args)
+}
+
+func TestParseUpdate(t *testing.T) {
+	config, err := parseUpdate([]string{"-m", "64m", "--memory-swap=-1", "-c", "512", "--cpuset-cpus=0-1", "--restart=on-failure:3", "container"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Memory == nil || *config.Memory != 64*1024*1024 || config.MemorySwap == nil || *config.MemorySwap != -1 {
+		t.Fatalf("Unexpected memory limits %v/%v", config.Memory, config.MemorySwap)
+	}
+	if config.CpuShares == nil || *config.CpuShares != 512 || config.CpusetCpus != "0-1" || config.CpusetMems != "" || config.CpuQuota != nil {
+		t.Fatalf("Unexpected cpu settings %+v", config)
+	}
+	if config.RestartPolicy.Name != "on-failure" || config.RestartPolicy.MaximumRetryCount != 3 {
+		t.Fatalf("Unexpected restart policy %+v", config.RestartPolicy)
+	}
+
+	// nothing changes unless asked for
+	config, err = parseUpdate([]string{"container"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *config != (UpdateConfig{}) {
+		t.Fatalf("Expected an empty update, got %+v", config)
+	}
+
+	// limits set to 0 are removed
+	config, err = parseUpdate([]string{"-m", "0", "--cpu-quota", "0", "-c", "0", "container"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Memory == nil || *config.Memory != 0 || config.CpuQuota == nil || *config.CpuQuota != 0 || config.CpuShares == nil || *config.CpuShares != 0 {
+		t.Fatalf("Expected the limits to be removed, got %+v", config)
+	}
+	if config.MemorySwap != nil {
+		t.Fatalf("Expected the memory+swap limit to be left alone, got %d", *config.MemorySwap)
+	}
+
+	if _, err := parseUpdate([]string{"-m", "lots", "container"}); err == nil {
+		t.Fatal("Expected an error for an invalid memory limit")
+	}
+	if _, err := parseUpdate([]string{"--restart=sometimes", "container"}); err == nil {
+		t.Fatal("Expected an error for an invalid restart policy")
+	}
+}
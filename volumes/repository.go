@@ -9,6 +9,7 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/daemon/graphdriver"
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/stringid"
 )
 
@@ -17,9 +18,15 @@ type Repository struct {
 	driver     graphdriver.Driver
 	volumes    map[string]*Volume
 	lock       sync.Mutex
+	uidMaps    []idtools.IDMap
+	gidMaps    []idtools.IDMap
 }
 
-func NewRepository(configPath string, driver graphdriver.Driver) (*Repository, error) {
+// NewRepository creates a volume repository storing its metadata in
+// configPath and creating volumes with driver. Host directories created for
+// bind mounts are owned by the host ids root is mapped to by uidMaps and
+// gidMaps.
+func NewRepository(configPath string, driver graphdriver.Driver, uidMaps, gidMaps []idtools.IDMap) (*Repository, error) {
 	abspath, err := filepath.Abs(configPath)
 	if err != nil {
 		return nil, err
@@ -34,6 +41,8 @@ func NewRepository(configPath string, driver graphdriver.Driver) (*Repository, e
 		driver:     driver,
 		configPath: abspath,
 		volumes:    make(map[string]*Volume),
+		uidMaps:    uidMaps,
+		gidMaps:    gidMaps,
 	}
 
 	return repo, repo.restore()
@@ -91,6 +100,7 @@ func (r *Repository) restore() error {
 		id := v.Name()
 		vol := &Volume{
 			ID:         id,
+			repository: r,
 			configPath: r.configPath + "/" + id,
 			containers: make(map[string]struct{}),
 		}
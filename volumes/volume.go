@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"sync"
 
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/symlink"
 )
 
@@ -62,7 +63,13 @@ func (v *Volume) initialize() error {
 		if !os.IsNotExist(err) {
 			return err
 		}
-		if err := os.MkdirAll(v.Path, 0755); err != nil {
+		rootUID, rootGID := 0, 0
+		if v.repository != nil {
+			if rootUID, rootGID, err = idtools.GetRootUIDGID(v.repository.uidMaps, v.repository.gidMaps); err != nil {
+				return err
+			}
+		}
+		if err := idtools.MkdirAllAs(v.Path, 0755, rootUID, rootGID); err != nil {
 			return err
 		}
 	}